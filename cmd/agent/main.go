@@ -35,6 +35,10 @@ func main() {
 			AgentName:      hostname,
 			ReportInterval: 5,
 			TLSSkipVerify:  true,
+			Processes: models.ProcessConfig{
+				TopN:     5,
+				Interval: 60,
+			},
 		}
 
 		if err := config.SaveConfig(*configPath, cfg); err != nil {
@@ -6,6 +6,11 @@ import {
     Card,
     CardContent,
     CircularProgress,
+    Table,
+    TableBody,
+    TableCell,
+    TableHead,
+    TableRow,
 } from '@mui/material';
 import { FormControl, InputLabel, Select, MenuItem } from '@mui/material';
 import { LineChart } from '@mui/x-charts/LineChart';
@@ -22,11 +27,43 @@ if (typeof Date !== 'undefined' && Date.prototype && Date.prototype.toString) {
 }
 /* eslint-enable no-extend-native */
 
+// Formats the start of a range as the server's since parameter, in local time
+function rangeSince(range) {
+    const now = Date.now();
+    let sinceTs = now - 5 * 60 * 1000; // default 5m
+    switch (range) {
+        case '5m': sinceTs = now - 5 * 60 * 1000; break;
+        case '30m': sinceTs = now - 30 * 60 * 1000; break;
+        case '15m': sinceTs = now - 15 * 60 * 1000; break;
+        case '1h': sinceTs = now - 60 * 60 * 1000; break;
+        case '6h': sinceTs = now - 6 * 60 * 60 * 1000; break;
+        case '24h': sinceTs = now - 24 * 60 * 60 * 1000; break;
+        case '7d': sinceTs = now - 7 * 24 * 60 * 60 * 1000; break;
+        default: sinceTs = now - 5 * 60 * 1000; break;
+    }
+    const pad = (n) => String(n).padStart(2, '0');
+    const d = new Date(sinceTs);
+    return `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())} ${pad(d.getHours())}:${pad(d.getMinutes())}:${pad(d.getSeconds())}`;
+}
+
 function AgentDetails({ token }) {
     const { agentId } = useParams();
     const [metrics, setMetrics] = useState([]);
     const [loading, setLoading] = useState(true);
     const [range, setRange] = useState('5m');
+    const [forecasts, setForecasts] = useState([]);
+    const [inventory, setInventory] = useState(null);
+    const [filesystems, setFilesystems] = useState([]);
+    const [disks, setDisks] = useState([]);
+    const [interfaces, setInterfaces] = useState([]);
+    const [processes, setProcesses] = useState([]);
+    const [watches, setWatches] = useState([]);
+    const [sensors, setSensors] = useState([]);
+    const [custom, setCustom] = useState([]);
+    const [customSeries, setCustomSeries] = useState('');
+    const [customHistory, setCustomHistory] = useState([]);
+    const [containers, setContainers] = useState([]);
+    const [containerEvents, setContainerEvents] = useState([]);
 
     const handleRangeChange = (e) => {
         setRange(e.target.value);
@@ -35,21 +72,7 @@ function AgentDetails({ token }) {
     const fetchMetrics = useCallback(async () => {
         try {
             // compute since based on selected range
-            const now = Date.now();
-            let sinceTs = now - 5 * 60 * 1000; // default 5m
-            switch (range) {
-                case '5m': sinceTs = now - 5 * 60 * 1000; break;
-                case '30m': sinceTs = now - 30 * 60 * 1000; break;
-                case '15m': sinceTs = now - 15 * 60 * 1000; break;
-                case '1h': sinceTs = now - 60 * 60 * 1000; break;
-                case '6h': sinceTs = now - 6 * 60 * 60 * 1000; break;
-                case '24h': sinceTs = now - 24 * 60 * 60 * 1000; break;
-                case '7d': sinceTs = now - 7 * 24 * 60 * 60 * 1000; break;
-                default: sinceTs = now - 5 * 60 * 1000; break;
-            }
-            const pad = (n) => String(n).padStart(2, '0');
-            const d = new Date(sinceTs);
-            const since = `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())} ${pad(d.getHours())}:${pad(d.getMinutes())}:${pad(d.getSeconds())}`;
+            const since = rangeSince(range);
             // Only send time-based since parameter (RFC3339). Server computes
             // aggregation buckets based on the time window; clients should not
             // request a specific points count anymore.
@@ -72,6 +95,116 @@ function AgentDetails({ token }) {
         return () => clearInterval(interval);
     }, [fetchMetrics, range]);
 
+    const fetchForecasts = useCallback(async () => {
+        try {
+            const response = await axios.get(`/api/agents/${agentId}/forecast`, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            setForecasts(response.data || []);
+        } catch (error) {
+            console.error('Failed to fetch forecasts:', error);
+        }
+    }, [agentId, token]);
+
+    useEffect(() => {
+        fetchForecasts();
+        const interval = setInterval(fetchForecasts, 60000);
+        return () => clearInterval(interval);
+    }, [fetchForecasts]);
+
+    // Graph the first custom series until another one is picked
+    useEffect(() => {
+        if (!customSeries && custom.length > 0) {
+            setCustomSeries(custom[0].name);
+        }
+    }, [custom, customSeries]);
+
+    const fetchCustomHistory = useCallback(async () => {
+        if (!customSeries) return;
+        try {
+            const params = new URLSearchParams({ name: customSeries, since: rangeSince(range) });
+            const response = await axios.get(`/api/agents/${agentId}/custom-history?${params}`, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            setCustomHistory(response.data || []);
+        } catch (error) {
+            console.error('Failed to fetch custom series:', error);
+        }
+    }, [agentId, token, range, customSeries]);
+
+    useEffect(() => {
+        fetchCustomHistory();
+        const interval = setInterval(fetchCustomHistory, 15000);
+        return () => clearInterval(interval);
+    }, [fetchCustomHistory]);
+
+    const fetchInventory = useCallback(async () => {
+        try {
+            const response = await axios.get(`/api/agents/${agentId}`, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            setInventory(response.data.inventory || null);
+        } catch (error) {
+            console.error('Failed to fetch inventory:', error);
+        }
+    }, [agentId, token]);
+
+    useEffect(() => {
+        fetchInventory();
+        const interval = setInterval(fetchInventory, 60000);
+        return () => clearInterval(interval);
+    }, [fetchInventory]);
+
+    const formatUptime = (seconds) => {
+        const days = Math.floor(seconds / 86400);
+        const hours = Math.floor((seconds % 86400) / 3600);
+        const minutes = Math.floor((seconds % 3600) / 60);
+        return days > 0 ? `${days}d ${hours}h` : `${hours}h ${minutes}m`;
+    };
+
+    const fetchFilesystems = useCallback(async () => {
+        try {
+            const headers = { Authorization: `Bearer ${token}` };
+            const [fsResponse, diskResponse, ifaceResponse, procResponse, watchResponse, sensorResponse, customResponse, containerResponse, eventResponse] = await Promise.all([
+                axios.get(`/api/agents/${agentId}/filesystems`, { headers }),
+                axios.get(`/api/agents/${agentId}/disks`, { headers }),
+                axios.get(`/api/agents/${agentId}/interfaces`, { headers }),
+                axios.get(`/api/agents/${agentId}/processes`, { headers }),
+                axios.get(`/api/agents/${agentId}/watches`, { headers }),
+                axios.get(`/api/agents/${agentId}/sensors`, { headers }),
+                axios.get(`/api/agents/${agentId}/custom`, { headers }),
+                axios.get(`/api/agents/${agentId}/containers`, { headers }),
+                axios.get(`/api/agents/${agentId}/container-events`, { headers }),
+            ]);
+            setFilesystems(fsResponse.data || []);
+            setDisks(diskResponse.data || []);
+            setInterfaces(ifaceResponse.data || []);
+            setProcesses(procResponse.data || []);
+            setWatches(watchResponse.data || []);
+            setSensors(sensorResponse.data || []);
+            setCustom(customResponse.data || []);
+            setContainers(containerResponse.data || []);
+            setContainerEvents(eventResponse.data || []);
+        } catch (error) {
+            console.error('Failed to fetch filesystems:', error);
+        }
+    }, [agentId, token]);
+
+    useEffect(() => {
+        fetchFilesystems();
+        const interval = setInterval(fetchFilesystems, 15000);
+        return () => clearInterval(interval);
+    }, [fetchFilesystems]);
+
+    // Describes when a resource is predicted to be full, if it is growing.
+    // Disks are forecast per mountpoint.
+    const fullIn = (resource, filesystem = '') => {
+        const forecast = forecasts.find((f) => f.resource === resource && f.filesystem === filesystem);
+        if (!forecast || forecast.hours_until_full === null) return null;
+        const hours = forecast.hours_until_full;
+        return hours < 48 ? `Full in ~${hours.toFixed(1)}h` : `Full in ~${(hours / 24).toFixed(1)}d`;
+    };
+
     if (loading) {
         return (
             <Box display="flex" justifyContent="center" alignItems="center" minHeight="400px">
@@ -118,8 +251,30 @@ function AgentDetails({ token }) {
 
     const cpuData = buildSeriesWithGaps(cpuRaw);
     const memoryData = buildSeriesWithGaps(memoryRaw);
+    const swapData = buildSeriesWithGaps(metrics.map((m) => (m.swap_total ? (m.swap_used / m.swap_total) * 100 : 0)));
+    const memPressureData = buildSeriesWithGaps(metrics.map((m) => m.memory_pressure_some || 0));
     const diskData = buildSeriesWithGaps(diskRaw);
+    const cpuBreakdown = [
+        ['cpu_user', 'User'],
+        ['cpu_system', 'System'],
+        ['cpu_iowait', 'I/O wait'],
+        ['cpu_steal', 'Steal'],
+        ['cpu_irq', 'IRQ'],
+    ].map(([field, label]) => ({ data: buildSeriesWithGaps(metrics.map((m) => m[field] || 0)), label, curve: 'linear' }));
+    const mb = 1024 * 1024;
+    const diskReadData = buildSeriesWithGaps(metrics.map((m) => (m.disk_read_bps || 0) / mb));
+    const diskWriteData = buildSeriesWithGaps(metrics.map((m) => (m.disk_write_bps || 0) / mb));
+    const diskReadIOPSData = buildSeriesWithGaps(metrics.map((m) => m.disk_read_iops || 0));
+    const diskWriteIOPSData = buildSeriesWithGaps(metrics.map((m) => m.disk_write_iops || 0));
+    const diskUtilData = buildSeriesWithGaps(metrics.map((m) => m.disk_util_percent || 0));
+    const diskAwaitData = buildSeriesWithGaps(metrics.map((m) => m.disk_await_ms || 0));
+    const netRxData = buildSeriesWithGaps(metrics.map((m) => m.network_rx / mb));
+    const netTxData = buildSeriesWithGaps(metrics.map((m) => m.network_tx / mb));
+    const netRxPacketsData = buildSeriesWithGaps(metrics.map((m) => m.network_rx_packets || 0));
+    const netTxPacketsData = buildSeriesWithGaps(metrics.map((m) => m.network_tx_packets || 0));
     const loadData = buildSeriesWithGaps(loadRaw);
+    const temperatureData = buildSeriesWithGaps(metrics.map((m) => m.temperature_max || 0));
+    const hasTemperature = metrics.some((m) => m.temperature_max > 0);
 
     // Compute Y axis max based on visible (non-null) values
     const computeMax = (arr) => {
@@ -130,7 +285,7 @@ function AgentDetails({ token }) {
         return mx <= 0 ? 1 : Math.ceil(mx * 1.05);
     };
     const cpuMax = computeMax(cpuData);
-    const memMax = computeMax(memoryData);
+    const memMax = computeMax([...memoryData, ...swapData, ...memPressureData]);
     const diskMax = computeMax(diskData);
     const loadMax = computeMax(loadData);
 
@@ -176,6 +331,37 @@ function AgentDetails({ token }) {
                     </FormControl>
                 </Box>
 
+                {inventory && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Host
+                            </Typography>
+                            <Table size="small">
+                                <TableBody>
+                                    {[
+                                        ['Hostname', inventory.hostname],
+                                        ['OS', `${inventory.platform || inventory.os} ${inventory.platform_version}`],
+                                        ['Kernel', `${inventory.kernel_version} (${inventory.arch})`],
+                                        ['CPU', `${inventory.cpu_model} x ${inventory.cpu_count}`],
+                                        ['Memory', formatBytes(inventory.memory_total)],
+                                        ['IP addresses', (inventory.ip_addresses || []).join(', ')],
+                                        ['Virtualization', inventory.virtualization || 'none'],
+                                        ['Boot time', new Date(inventory.boot_time).toString()],
+                                        ['Uptime', formatUptime(inventory.uptime)],
+                                        ['Agent version', inventory.agent_version],
+                                    ].map(([label, value]) => (
+                                        <TableRow key={label}>
+                                            <TableCell sx={{ width: '12rem' }}>{label}</TableCell>
+                                            <TableCell>{value}</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
                 {latest && (
                     <Box sx={{ display: 'flex', direction: 'row', gap: 2, mb: 2, flexWrap: 'nowrap', justifyContent: 'center' }}>
                         <Card sx={{ width: '100%' }}>
@@ -188,6 +374,10 @@ function AgentDetails({ token }) {
                                 </Typography>
                                 <Typography variant="body2" color="text.secondary">
                                     Cores: {latest.cpu_cores}
+                                    {latest.cpu_per_core && ` | Busiest: ${Math.max(...latest.cpu_per_core).toFixed(1)}%`}
+                                </Typography>
+                                <Typography variant="body2" color="text.secondary">
+                                    usr {latest.cpu_user.toFixed(1)} | sys {latest.cpu_system.toFixed(1)} | wa {latest.cpu_iowait.toFixed(1)} | st {latest.cpu_steal.toFixed(1)}
                                 </Typography>
                             </CardContent>
                         </Card>
@@ -202,6 +392,19 @@ function AgentDetails({ token }) {
                                 <Typography variant="body2" color="text.secondary">
                                     {formatBytes(latest.memory_used)} / {formatBytes(latest.memory_total)}
                                 </Typography>
+                                <Typography variant="body2" color="text.secondary">
+                                    avail {formatBytes(latest.memory_available)} | cache {formatBytes(latest.memory_cached + latest.memory_buffers)}
+                                </Typography>
+                                {latest.swap_total > 0 && (
+                                    <Typography variant="body2" color="text.secondary">
+                                        swap {formatBytes(latest.swap_used)} / {formatBytes(latest.swap_total)}
+                                    </Typography>
+                                )}
+                                {fullIn('memory') && (
+                                    <Typography variant="body2" color="warning.main">
+                                        {fullIn('memory')}
+                                    </Typography>
+                                )}
                             </CardContent>
                         </Card>
                         <Card sx={{ width: '100%' }}>
@@ -215,6 +418,11 @@ function AgentDetails({ token }) {
                                 <Typography variant="body2" color="text.secondary">
                                     {formatBytes(latest.disk_used)} / {formatBytes(latest.disk_total)}
                                 </Typography>
+                                {fullIn('disk', '/') && (
+                                    <Typography variant="body2" color="warning.main">
+                                        {fullIn('disk', '/')}
+                                    </Typography>
+                                )}
                             </CardContent>
                         </Card>
                         <Card sx={{ width: '100%' }}>
@@ -256,6 +464,22 @@ function AgentDetails({ token }) {
                                 </Box>
                             </CardContent>
                         </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    CPU Time Breakdown (%)
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={cpuBreakdown}
+                                        yAxis={[{ min: 0 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
                         <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
                             <CardContent>
                                 <Typography variant="h6" gutterBottom>
@@ -264,7 +488,11 @@ function AgentDetails({ token }) {
                                 <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
                                     <LineChart
                                         xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
-                                        series={[{ data: memoryData, label: 'Memory %', curve: 'linear' }]}
+                                        series={[
+                                            { data: memoryData, label: 'Memory %', curve: 'linear' },
+                                            { data: swapData, label: 'Swap %', curve: 'linear' },
+                                            { data: memPressureData, label: 'Pressure %', curve: 'linear' },
+                                        ]}
                                         yAxis={[{ min: 0, ...(memMax ? { max: memMax } : {}) }]}
                                         tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
                                         height={240}
@@ -304,8 +532,445 @@ function AgentDetails({ token }) {
                                 </Box>
                             </CardContent>
                         </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    Disk Throughput (MB/s)
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: diskReadData, label: 'Read', curve: 'linear' }, { data: diskWriteData, label: 'Write', curve: 'linear' }]}
+                                        yAxis={[{ min: 0 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    Disk IOPS
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: diskReadIOPSData, label: 'Read', curve: 'linear' }, { data: diskWriteIOPSData, label: 'Write', curve: 'linear' }]}
+                                        yAxis={[{ min: 0 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    Disk Busy (%)
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: diskUtilData, label: 'Busiest device %', curve: 'linear' }]}
+                                        yAxis={[{ min: 0, max: 100 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    Disk Await (ms)
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: diskAwaitData, label: 'Await', curve: 'linear' }]}
+                                        yAxis={[{ min: 0 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    Network (MB/s)
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: netRxData, label: 'Received', curve: 'linear' }, { data: netTxData, label: 'Sent', curve: 'linear' }]}
+                                        yAxis={[{ min: 0 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
+                        <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                            <CardContent>
+                                <Typography variant="h6" gutterBottom>
+                                    Network Packets (/s)
+                                </Typography>
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: netRxPacketsData, label: 'Received', curve: 'linear' }, { data: netTxPacketsData, label: 'Sent', curve: 'linear' }]}
+                                        yAxis={[{ min: 0 }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            </CardContent>
+                        </Card>
+                        {hasTemperature && (
+                            <Card sx={{ flex: "1 1 calc(50% - 1rem)", minWidth: 320 }}>
+                                <CardContent>
+                                    <Typography variant="h6" gutterBottom>
+                                        Hottest Sensor (°C)
+                                    </Typography>
+                                    <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                        <LineChart
+                                            xAxis={[{ data: timestamps, scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                            series={[{ data: temperatureData, label: 'Temperature', curve: 'linear' }]}
+                                            yAxis={[{ min: 0 }]}
+                                            tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                            height={240}
+                                        />
+                                    </Box>
+                                </CardContent>
+                            </Card>
+                        )}
                     </Box>
                 )}
+
+                {watches.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Watched Processes
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Name</TableCell>
+                                        <TableCell>Status</TableCell>
+                                        <TableCell>Processes</TableCell>
+                                        <TableCell>CPU</TableCell>
+                                        <TableCell>Memory</TableCell>
+                                        <TableCell>Restarts</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {watches.map((watch) => (
+                                        <TableRow key={watch.name}>
+                                            <TableCell>{watch.name}</TableCell>
+                                            <TableCell>
+                                                <Typography variant="body2" color={watch.up ? 'success.main' : 'error.main'}>
+                                                    {watch.up ? 'Up' : 'Down'}{watch.error && ` (${watch.error})`}
+                                                </Typography>
+                                            </TableCell>
+                                            <TableCell>{watch.processes}</TableCell>
+                                            <TableCell>{watch.cpu_percent.toFixed(1)}%</TableCell>
+                                            <TableCell>{formatBytes(watch.rss)}</TableCell>
+                                            <TableCell>{watch.total_restarts}</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
+                {sensors.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Sensors
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Sensor</TableCell>
+                                        <TableCell>Reading</TableCell>
+                                        <TableCell>High</TableCell>
+                                        <TableCell>Critical</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {sensors.map((sensor) => {
+                                        const unit = sensor.kind === 'fan' ? ' RPM' : ' °C';
+                                        const hot = sensor.critical > 0 && sensor.value >= sensor.critical;
+                                        const warm = sensor.high > 0 && sensor.value >= sensor.high;
+                                        return (
+                                            <TableRow key={sensor.name}>
+                                                <TableCell>{sensor.name}</TableCell>
+                                                <TableCell>
+                                                    <Typography variant="body2" color={hot ? 'error.main' : warm ? 'warning.main' : 'text.primary'}>
+                                                        {sensor.value}{unit}
+                                                    </Typography>
+                                                </TableCell>
+                                                <TableCell>{sensor.high ? `${sensor.high}${unit}` : '-'}</TableCell>
+                                                <TableCell>{sensor.critical ? `${sensor.critical}${unit}` : '-'}</TableCell>
+                                            </TableRow>
+                                        );
+                                    })}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
+                {custom.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
+                                <Typography variant="h6" gutterBottom>
+                                    Custom Metrics
+                                </Typography>
+                                <FormControl size="small" sx={{ minWidth: '16rem' }}>
+                                    <InputLabel id="custom-series-label">Series</InputLabel>
+                                    <Select
+                                        labelId="custom-series-label"
+                                        value={customSeries}
+                                        label="Series"
+                                        onChange={(e) => setCustomSeries(e.target.value)}
+                                    >
+                                        {custom.map((c) => (
+                                            <MenuItem key={c.name} value={c.name}>{c.name}</MenuItem>
+                                        ))}
+                                    </Select>
+                                </FormControl>
+                            </Box>
+                            {customHistory.length > 0 && (
+                                <Box sx={{ width: '100%', '& svg circle': { r: 0, display: 'none' }, '& svg path': { strokeWidth: 1.2 }, '& svg text': { fontSize: '0.85rem' } }}>
+                                    <LineChart
+                                        xAxis={[{ data: customHistory.map((p) => new Date(p.timestamp)), scaleType: 'time', tickFormat: (d) => formatTick(new Date(d)) }]}
+                                        series={[{ data: customHistory.map((p) => p.value), label: customSeries, curve: 'linear' }]}
+                                        tooltip={{ xFormatter: (d) => formatTick(new Date(d)) }}
+                                        height={240}
+                                    />
+                                </Box>
+                            )}
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Series</TableCell>
+                                        <TableCell>Value</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {custom.map((c) => (
+                                        <TableRow key={c.name} hover onClick={() => setCustomSeries(c.name)} sx={{ cursor: 'pointer' }}>
+                                            <TableCell>{c.name}</TableCell>
+                                            <TableCell>{c.value}{c.unit && ` ${c.unit}`}</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
+                {(containers.length > 0 || containerEvents.length > 0) && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Containers
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Name</TableCell>
+                                        <TableCell>Image</TableCell>
+                                        <TableCell>CPU</TableCell>
+                                        <TableCell>Memory</TableCell>
+                                        <TableCell>Network (rx/tx)</TableCell>
+                                        <TableCell>Disk (read/write)</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {containers.map((container) => (
+                                        <TableRow key={container.id}>
+                                            <TableCell title={container.id}>{container.name}</TableCell>
+                                            <TableCell>{container.image || '-'}</TableCell>
+                                            <TableCell>{container.cpu_percent.toFixed(1)}%</TableCell>
+                                            <TableCell>
+                                                {formatBytes(container.memory_used)}
+                                                {container.memory_limit > 0 && ` / ${formatBytes(container.memory_limit)}`}
+                                            </TableCell>
+                                            <TableCell>{formatBytes(Math.round(container.network_rx))}/s / {formatBytes(Math.round(container.network_tx))}/s</TableCell>
+                                            <TableCell>{formatBytes(Math.round(container.disk_read_bps))}/s / {formatBytes(Math.round(container.disk_write_bps))}/s</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                            {containerEvents.length > 0 && (
+                                <>
+                                    <Typography variant="subtitle2" sx={{ mt: 2 }} gutterBottom>
+                                        Recent Events
+                                    </Typography>
+                                    {containerEvents.slice(0, 10).map((event, i) => (
+                                        <Typography key={i} variant="body2" color={event.type === 'stop' ? 'warning.main' : 'text.secondary'}>
+                                            {formatTick(new Date(event.timestamp))} {event.name} {event.type === 'stop' ? 'stopped' : 'started'}
+                                        </Typography>
+                                    ))}
+                                </>
+                            )}
+                        </CardContent>
+                    </Card>
+                )}
+
+                {processes.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Top Processes
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>PID</TableCell>
+                                        <TableCell>Name</TableCell>
+                                        <TableCell>User</TableCell>
+                                        <TableCell>CPU</TableCell>
+                                        <TableCell>Memory</TableCell>
+                                        <TableCell>Open Files</TableCell>
+                                        <TableCell>Threads</TableCell>
+                                        <TableCell>Command</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {processes.map((proc) => (
+                                        <TableRow key={proc.pid}>
+                                            <TableCell>{proc.pid}</TableCell>
+                                            <TableCell>{proc.name}</TableCell>
+                                            <TableCell>{proc.user}</TableCell>
+                                            <TableCell>{proc.cpu_percent.toFixed(1)}%</TableCell>
+                                            <TableCell>{formatBytes(proc.rss)}</TableCell>
+                                            <TableCell>{proc.fds || '-'}</TableCell>
+                                            <TableCell>{proc.threads}</TableCell>
+                                            <TableCell sx={{ maxWidth: 400, overflow: 'hidden', textOverflow: 'ellipsis', whiteSpace: 'nowrap' }} title={proc.cmdline}>
+                                                {proc.cmdline}
+                                            </TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
+                {interfaces.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Network Interfaces
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Interface</TableCell>
+                                        <TableCell>Received</TableCell>
+                                        <TableCell>Sent</TableCell>
+                                        <TableCell>Packets (rx/tx)</TableCell>
+                                        <TableCell>Errors (rx/tx)</TableCell>
+                                        <TableCell>Drops (rx/tx)</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {interfaces.map((iface) => (
+                                        <TableRow key={iface.name}>
+                                            <TableCell>{iface.name}</TableCell>
+                                            <TableCell>{formatBytes(Math.round(iface.rx_bytes))}/s</TableCell>
+                                            <TableCell>{formatBytes(Math.round(iface.tx_bytes))}/s</TableCell>
+                                            <TableCell>{iface.rx_packets.toFixed(1)} / {iface.tx_packets.toFixed(1)}</TableCell>
+                                            <TableCell>{iface.rx_errors.toFixed(2)} / {iface.tx_errors.toFixed(2)}</TableCell>
+                                            <TableCell>{iface.rx_drops.toFixed(2)} / {iface.tx_drops.toFixed(2)}</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
+                {disks.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Disk I/O
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Device</TableCell>
+                                        <TableCell>Read</TableCell>
+                                        <TableCell>Write</TableCell>
+                                        <TableCell>IOPS (r/w)</TableCell>
+                                        <TableCell>Busy</TableCell>
+                                        <TableCell>Await</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {disks.map((d) => (
+                                        <TableRow key={d.device}>
+                                            <TableCell>{d.device}</TableCell>
+                                            <TableCell>{formatBytes(Math.round(d.read_bps))}/s</TableCell>
+                                            <TableCell>{formatBytes(Math.round(d.write_bps))}/s</TableCell>
+                                            <TableCell>{d.read_iops.toFixed(1)} / {d.write_iops.toFixed(1)}</TableCell>
+                                            <TableCell>{d.util_percent.toFixed(1)}%</TableCell>
+                                            <TableCell>{d.await_ms.toFixed(2)} ms</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
+
+                {filesystems.length > 0 && (
+                    <Card sx={{ mb: 2 }}>
+                        <CardContent>
+                            <Typography variant="h6" gutterBottom>
+                                Filesystems
+                            </Typography>
+                            <Table size="small">
+                                <TableHead>
+                                    <TableRow>
+                                        <TableCell>Mountpoint</TableCell>
+                                        <TableCell>Device</TableCell>
+                                        <TableCell>Type</TableCell>
+                                        <TableCell>Used</TableCell>
+                                        <TableCell>Inodes</TableCell>
+                                        <TableCell>Forecast</TableCell>
+                                    </TableRow>
+                                </TableHead>
+                                <TableBody>
+                                    {filesystems.map((fs) => (
+                                        <TableRow key={fs.mountpoint}>
+                                            <TableCell>{fs.mountpoint}</TableCell>
+                                            <TableCell>{fs.device}</TableCell>
+                                            <TableCell>{fs.fstype}</TableCell>
+                                            <TableCell>
+                                                {((fs.used / fs.total) * 100).toFixed(1)}% ({formatBytes(fs.used)} / {formatBytes(fs.total)})
+                                            </TableCell>
+                                            <TableCell>
+                                                {fs.inodes_total > 0 ? `${((fs.inodes_used / fs.inodes_total) * 100).toFixed(1)}%` : '-'}
+                                            </TableCell>
+                                            <TableCell>{fullIn('disk', fs.mountpoint) || '-'}</TableCell>
+                                        </TableRow>
+                                    ))}
+                                </TableBody>
+                            </Table>
+                        </CardContent>
+                    </Card>
+                )}
             </Box>
         </Box>
     );
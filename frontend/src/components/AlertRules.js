@@ -22,10 +22,33 @@ import {
 } from '@mui/material';
 import axios from 'axios';
 
+// Labels of the target field of metrics that can be limited to a single
+// mountpoint or device
+const targetLabels = {
+    disk: 'Mountpoint (leave empty for /)',
+    disk_inodes_percent: 'Mountpoint (leave empty for /)',
+    disk_util_percent: 'Device (leave empty for all)',
+    disk_await_ms: 'Device (leave empty for all)',
+    disk_read_bps: 'Device (leave empty for all)',
+    disk_write_bps: 'Device (leave empty for all)',
+    disk_iops: 'Device (leave empty for all)',
+    network_rx: 'Interface (leave empty for all)',
+    network_tx: 'Interface (leave empty for all)',
+    network_errors: 'Interface (leave empty for all)',
+    network_drops: 'Interface (leave empty for all)',
+    watches_down: 'Watch name (leave empty for all)',
+    watch_restarts: 'Watch name (leave empty for all)',
+    temperature: 'Sensor (leave empty for the hottest)',
+    fan_rpm: 'Sensor (leave empty for the first fan)',
+    custom: 'Series name, such as queue.depth',
+};
+
 function AlertRules({ token }) {
     const [rules, setRules] = useState([]);
     const [loading, setLoading] = useState(true);
     const [openDialog, setOpenDialog] = useState(false);
+    const [formError, setFormError] = useState('');
+    const [backtest, setBacktest] = useState(null);
     const [newRule, setNewRule] = useState({
         agent_id: '',
         metric_type: 'cpu',
@@ -34,6 +57,7 @@ function AlertRules({ token }) {
         duration: 60,
         enabled: true,
         description: '',
+        severity: 'warning',
     });
 
     const fetchRules = useCallback(async () => {
@@ -59,6 +83,8 @@ function AlertRules({ token }) {
                 headers: { Authorization: `Bearer ${token}` },
             });
             setOpenDialog(false);
+            setBacktest(null);
+            setFormError('');
             fetchRules();
             setNewRule({
                 agent_id: '',
@@ -68,9 +94,47 @@ function AlertRules({ token }) {
                 duration: 60,
                 enabled: true,
                 description: '',
+                severity: 'warning',
             });
         } catch (error) {
             console.error('Failed to add rule:', error);
+            setFormError(error.response?.data?.error || 'Failed to add rule');
+        }
+    };
+
+    const handleBacktest = async () => {
+        try {
+            const response = await axios.post('/api/alert-rules/backtest', { rule: newRule }, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            setFormError('');
+            setBacktest(response.data);
+        } catch (error) {
+            console.error('Failed to backtest rule:', error);
+            setBacktest(null);
+            setFormError(error.response?.data?.error || 'Failed to backtest rule');
+        }
+    };
+
+    const handleToggleRule = async (rule) => {
+        try {
+            await axios.patch(`/api/alert-rules/${rule.id}`, { enabled: !rule.enabled }, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            fetchRules();
+        } catch (error) {
+            console.error('Failed to update rule:', error);
+        }
+    };
+
+    const handleDeleteRule = async (id) => {
+        try {
+            await axios.delete(`/api/alert-rules/${id}`, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            fetchRules();
+        } catch (error) {
+            console.error('Failed to delete rule:', error);
         }
     };
 
@@ -100,15 +164,17 @@ function AlertRules({ token }) {
                             <TableCell>Agent</TableCell>
                             <TableCell>Metric</TableCell>
                             <TableCell>Condition</TableCell>
+                            <TableCell>Severity</TableCell>
                             <TableCell>Duration</TableCell>
                             <TableCell>Description</TableCell>
                             <TableCell>Enabled</TableCell>
+                            <TableCell>Actions</TableCell>
                         </TableRow>
                     </TableHead>
                     <TableBody>
                         {rules.length === 0 ? (
                             <TableRow>
-                                <TableCell colSpan={6} align="center">
+                                <TableCell colSpan={8} align="center">
                                     <Typography color="text.secondary">No alert rules</Typography>
                                 </TableCell>
                             </TableRow>
@@ -116,13 +182,32 @@ function AlertRules({ token }) {
                             rules.map((rule) => (
                                 <TableRow key={rule.id}>
                                     <TableCell>{rule.agent_id || 'All'}</TableCell>
-                                    <TableCell>{rule.metric_type}</TableCell>
                                     <TableCell>
-                                        {rule.operator} {rule.threshold}
+                                        {rule.metric_type}
+                                        {rule.target && ` ${rule.target}`}
                                     </TableCell>
+                                    <TableCell>
+                                        {rule.expression || (rule.levels && rule.levels.length > 0
+                                            ? rule.levels.map((l) => `${l.severity} ${rule.operator} ${l.threshold}`).join(', ')
+                                            : `${rule.operator} ${rule.threshold}`)}
+                                        {rule.type === 'anomaly' && ` σ ${rule.direction} baseline (${rule.seasonality})`}
+                                        {rule.type === 'forecast' && 'h until full'}
+                                    </TableCell>
+                                    <TableCell>{rule.severity}</TableCell>
                                     <TableCell>{rule.duration}s</TableCell>
                                     <TableCell>{rule.description}</TableCell>
-                                    <TableCell>{rule.enabled ? 'Yes' : 'No'}</TableCell>
+                                    <TableCell>
+                                        <Switch
+                                            checked={rule.enabled}
+                                            onChange={() => handleToggleRule(rule)}
+                                            size="small"
+                                        />
+                                    </TableCell>
+                                    <TableCell>
+                                        <Button size="small" color="error" onClick={() => handleDeleteRule(rule.id)}>
+                                            Delete
+                                        </Button>
+                                    </TableCell>
                                 </TableRow>
                             ))
                         )}
@@ -133,6 +218,17 @@ function AlertRules({ token }) {
             <Dialog open={openDialog} onClose={() => setOpenDialog(false)}>
                 <DialogTitle>Add Alert Rule</DialogTitle>
                 <DialogContent>
+                    {formError && (
+                        <Typography color="error" variant="body2" sx={{ mb: 1 }}>
+                            {formError}
+                        </Typography>
+                    )}
+                    {backtest && (
+                        <Typography variant="body2" color="textSecondary" sx={{ mb: 1 }}>
+                            In the last 24 hours this rule would have raised {backtest.count} alert(s)
+                            and fired {backtest.notifications} time(s) across {backtest.agents} agent(s).
+                        </Typography>
+                    )}
                     <TextField
                         margin="dense"
                         label="Agent ID (leave empty for all)"
@@ -141,19 +237,85 @@ function AlertRules({ token }) {
                         value={newRule.agent_id}
                         onChange={(e) => setNewRule({ ...newRule, agent_id: e.target.value })}
                     />
+                    <TextField
+                        margin="dense"
+                        label="Rule Type"
+                        select
+                        fullWidth
+                        value={newRule.type || 'threshold'}
+                        onChange={(e) => {
+                            const { direction, seasonality, ...rule } = newRule;
+                            if (e.target.value === 'anomaly') {
+                                setNewRule({ ...rule, type: 'anomaly', operator: 'gt', threshold: 3, direction: 'above', seasonality: 'hour_of_week' });
+                            } else if (e.target.value === 'forecast') {
+                                setNewRule({
+                                    ...rule,
+                                    type: 'forecast',
+                                    metric_type: 'disk',
+                                    operator: 'lt',
+                                    threshold: 48,
+                                    target: targetLabels[rule.metric_type] === targetLabels.disk ? rule.target : '',
+                                });
+                            } else {
+                                setNewRule({ ...rule, type: 'threshold' });
+                            }
+                        }}
+                    >
+                        <MenuItem value="threshold">Static threshold</MenuItem>
+                        <MenuItem value="anomaly">Anomaly (deviation from learned baseline)</MenuItem>
+                        <MenuItem value="forecast">Forecast (disk or memory full within)</MenuItem>
+                    </TextField>
                     <TextField
                         margin="dense"
                         label="Metric Type"
                         select
                         fullWidth
                         value={newRule.metric_type}
-                        onChange={(e) => setNewRule({ ...newRule, metric_type: e.target.value })}
+                        onChange={(e) => {
+                            const { target, ...rule } = newRule;
+                            setNewRule(targetLabels[e.target.value] === targetLabels[newRule.metric_type]
+                                ? { ...newRule, metric_type: e.target.value }
+                                : { ...rule, metric_type: e.target.value });
+                        }}
                     >
                         <MenuItem value="cpu">CPU</MenuItem>
+                        <MenuItem value="cpu_core_max">CPU Busiest Core</MenuItem>
+                        <MenuItem value="cpu_iowait">CPU I/O Wait</MenuItem>
+                        <MenuItem value="cpu_steal">CPU Steal</MenuItem>
                         <MenuItem value="memory">Memory</MenuItem>
+                        <MenuItem value="mem_available_percent">Memory Available %</MenuItem>
+                        <MenuItem value="mem_pressure_some">Memory Pressure (some)</MenuItem>
+                        <MenuItem value="mem_pressure_full">Memory Pressure (full)</MenuItem>
+                        <MenuItem value="swap_percent">Swap</MenuItem>
                         <MenuItem value="disk">Disk</MenuItem>
+                        <MenuItem value="disk_inodes_percent">Disk Inodes</MenuItem>
+                        <MenuItem value="disk_util_percent">Disk Busy %</MenuItem>
+                        <MenuItem value="disk_await_ms">Disk Await (ms)</MenuItem>
+                        <MenuItem value="disk_read_bps">Disk Read (bytes/s)</MenuItem>
+                        <MenuItem value="disk_write_bps">Disk Write (bytes/s)</MenuItem>
+                        <MenuItem value="disk_iops">Disk IOPS</MenuItem>
+                        <MenuItem value="network_rx">Network Received (bytes/s)</MenuItem>
+                        <MenuItem value="network_tx">Network Sent (bytes/s)</MenuItem>
+                        <MenuItem value="network_errors">Network Errors (/s)</MenuItem>
+                        <MenuItem value="network_drops">Network Drops (/s)</MenuItem>
+                        <MenuItem value="watches_down">Watched Processes Down</MenuItem>
+                        <MenuItem value="watch_restarts">Watched Process Restarts</MenuItem>
+                        <MenuItem value="temperature">Temperature (°C)</MenuItem>
+                        <MenuItem value="fan_rpm">Fan Speed (RPM)</MenuItem>
+                        <MenuItem value="custom">Custom Metric</MenuItem>
                         <MenuItem value="load">Load Average</MenuItem>
+                        <MenuItem value="offline">Agent Offline (missed reports)</MenuItem>
                     </TextField>
+                    {targetLabels[newRule.metric_type] && (
+                        <TextField
+                            margin="dense"
+                            label={targetLabels[newRule.metric_type]}
+                            type="text"
+                            fullWidth
+                            value={newRule.target || ''}
+                            onChange={(e) => setNewRule({ ...newRule, target: e.target.value })}
+                        />
+                    )}
                     <TextField
                         margin="dense"
                         label="Operator"
@@ -169,7 +331,47 @@ function AlertRules({ token }) {
                     </TextField>
                     <TextField
                         margin="dense"
-                        label="Threshold"
+                        label="Severity"
+                        select
+                        fullWidth
+                        value={newRule.severity}
+                        onChange={(e) => setNewRule({ ...newRule, severity: e.target.value })}
+                    >
+                        <MenuItem value="info">Info</MenuItem>
+                        <MenuItem value="warning">Warning</MenuItem>
+                        <MenuItem value="critical">Critical</MenuItem>
+                    </TextField>
+                    {newRule.type === 'anomaly' && (
+                        <>
+                            <TextField
+                                margin="dense"
+                                label="Direction"
+                                select
+                                fullWidth
+                                value={newRule.direction}
+                                onChange={(e) => setNewRule({ ...newRule, direction: e.target.value })}
+                            >
+                                <MenuItem value="above">Above baseline</MenuItem>
+                                <MenuItem value="below">Below baseline</MenuItem>
+                                <MenuItem value="both">Either way</MenuItem>
+                            </TextField>
+                            <TextField
+                                margin="dense"
+                                label="Seasonality"
+                                select
+                                fullWidth
+                                value={newRule.seasonality}
+                                onChange={(e) => setNewRule({ ...newRule, seasonality: e.target.value })}
+                            >
+                                <MenuItem value="none">None</MenuItem>
+                                <MenuItem value="hour_of_day">Hour of day</MenuItem>
+                                <MenuItem value="hour_of_week">Hour of week</MenuItem>
+                            </TextField>
+                        </>
+                    )}
+                    <TextField
+                        margin="dense"
+                        label={{ anomaly: 'Standard deviations', forecast: 'Hours until full' }[newRule.type] || 'Threshold'}
                         type="number"
                         fullWidth
                         value={newRule.threshold}
@@ -205,6 +407,7 @@ function AlertRules({ token }) {
                 </DialogContent>
                 <DialogActions>
                     <Button onClick={() => setOpenDialog(false)}>Cancel</Button>
+                    <Button onClick={handleBacktest}>Backtest</Button>
                     <Button onClick={handleAddRule} variant="contained">
                         Add
                     </Button>
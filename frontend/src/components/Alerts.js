@@ -1,4 +1,4 @@
-import { useState, useEffect, useCallback } from 'react';
+import { useState, useEffect, useCallback, useRef } from 'react';
 import {
     Box,
     Typography,
@@ -11,29 +11,92 @@ import {
     Paper,
     Chip,
     CircularProgress,
+    Button,
+    Dialog,
+    DialogTitle,
+    DialogContent,
+    DialogActions,
+    TextField,
+    MenuItem,
 } from '@mui/material';
 import axios from 'axios';
 
 function Alerts({ token }) {
     const [alerts, setAlerts] = useState([]);
     const [loading, setLoading] = useState(true);
+    const [filters, setFilters] = useState({ resolved: '', severity: '' });
+    const [nextCursor, setNextCursor] = useState('');
+    const [detail, setDetail] = useState(null);
+    // Set once older pages are loaded, so polling does not drop them
+    const pagedRef = useRef(false);
+
+    const alertParams = useCallback((cursor) => {
+        const params = {};
+        if (filters.resolved) params.resolved = filters.resolved;
+        if (filters.severity) params.severity = filters.severity;
+        if (cursor) params.cursor = cursor;
+        return params;
+    }, [filters]);
 
     const fetchAlerts = useCallback(async () => {
         try {
             const response = await axios.get('/api/alerts', {
                 headers: { Authorization: `Bearer ${token}` },
+                params: alertParams(''),
             });
             setAlerts(response.data || []);
+            setNextCursor(response.headers['x-next-cursor'] || '');
+            pagedRef.current = false;
         } catch (error) {
             console.error('Failed to fetch alerts:', error);
         } finally {
             setLoading(false);
         }
-    }, [token]);
+    }, [token, alertParams]);
+
+    const handleLoadMore = async () => {
+        try {
+            const response = await axios.get('/api/alerts', {
+                headers: { Authorization: `Bearer ${token}` },
+                params: alertParams(nextCursor),
+            });
+            setAlerts((current) => [...current, ...(response.data || [])]);
+            setNextCursor(response.headers['x-next-cursor'] || '');
+            pagedRef.current = true;
+        } catch (error) {
+            console.error('Failed to fetch alerts:', error);
+        }
+    };
+
+    const handleShowDetail = async (id) => {
+        try {
+            const response = await axios.get(`/api/alerts/${id}`, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            setDetail(response.data);
+        } catch (error) {
+            console.error('Failed to fetch alert:', error);
+        }
+    };
+
+    const handleAcknowledge = async (id) => {
+        try {
+            await axios.post(`/api/alerts/${id}/ack`, {}, {
+                headers: { Authorization: `Bearer ${token}` },
+            });
+            fetchAlerts();
+        } catch (error) {
+            console.error('Failed to acknowledge alert:', error);
+        }
+    };
 
     useEffect(() => {
         fetchAlerts();
-        const interval = setInterval(fetchAlerts, 10000);
+        const interval = setInterval(() => {
+            if (!pagedRef.current) {
+                fetchAlerts();
+            }
+        }, 10000);
         return () => clearInterval(interval);
     }, [fetchAlerts]);
 
@@ -50,6 +113,33 @@ function Alerts({ token }) {
             <Typography variant="h4" gutterBottom>
                 Alerts
             </Typography>
+            <Box display="flex" gap={2} mb={2}>
+                <TextField
+                    select
+                    size="small"
+                    label="Status"
+                    value={filters.resolved}
+                    onChange={(e) => setFilters({ ...filters, resolved: e.target.value })}
+                    sx={{ minWidth: 140 }}
+                >
+                    <MenuItem value="">All</MenuItem>
+                    <MenuItem value="false">Active</MenuItem>
+                    <MenuItem value="true">Resolved</MenuItem>
+                </TextField>
+                <TextField
+                    select
+                    size="small"
+                    label="Severity"
+                    value={filters.severity}
+                    onChange={(e) => setFilters({ ...filters, severity: e.target.value })}
+                    sx={{ minWidth: 140 }}
+                >
+                    <MenuItem value="">All</MenuItem>
+                    <MenuItem value="info">Info</MenuItem>
+                    <MenuItem value="warning">Warning</MenuItem>
+                    <MenuItem value="critical">Critical</MenuItem>
+                </TextField>
+            </Box>
             <TableContainer component={Paper}>
                 <Table>
                     <TableHead>
@@ -59,12 +149,13 @@ function Alerts({ token }) {
                             <TableCell>Message</TableCell>
                             <TableCell>Value</TableCell>
                             <TableCell>Status</TableCell>
+                            <TableCell>Actions</TableCell>
                         </TableRow>
                     </TableHead>
                     <TableBody>
                         {alerts.length === 0 ? (
                             <TableRow>
-                                <TableCell colSpan={5} align="center">
+                                <TableCell colSpan={6} align="center">
                                     <Typography color="text.secondary">No alerts</Typography>
                                 </TableCell>
                             </TableRow>
@@ -83,6 +174,23 @@ function Alerts({ token }) {
                                             color={alert.resolved ? 'default' : 'error'}
                                             size="small"
                                         />
+                                        {alert.suppressed && (
+                                            <Chip label="Maintenance" size="small" sx={{ ml: 1 }} />
+                                        )}
+                                    </TableCell>
+                                    <TableCell>
+                                        <Button size="small" onClick={() => handleShowDetail(alert.id)}>
+                                            Details
+                                        </Button>
+                                        {alert.acknowledged_by ? (
+                                            <Typography variant="body2" color="text.secondary">
+                                                Acked by {alert.acknowledged_by}
+                                            </Typography>
+                                        ) : !alert.resolved && (
+                                            <Button size="small" onClick={() => handleAcknowledge(alert.id)}>
+                                                Acknowledge
+                                            </Button>
+                                        )}
                                     </TableCell>
                                 </TableRow>
                             ))
@@ -90,6 +198,38 @@ function Alerts({ token }) {
                     </TableBody>
                 </Table>
             </TableContainer>
+            {nextCursor && (
+                <Box display="flex" justifyContent="center" mt={2}>
+                    <Button onClick={handleLoadMore}>Load more</Button>
+                </Box>
+            )}
+
+            <Dialog open={!!detail} onClose={() => setDetail(null)} maxWidth="sm" fullWidth>
+                <DialogTitle>Alert {detail?.id}</DialogTitle>
+                <DialogContent>
+                    <Typography variant="body2" gutterBottom>
+                        {detail?.message}
+                    </Typography>
+                    <Table size="small">
+                        <TableBody>
+                            {(detail?.timeline || []).map((event, i) => (
+                                <TableRow key={i}>
+                                    <TableCell>
+                                        {event.timestamp.startsWith('0001') ? '-' : new Date(event.timestamp).toLocaleString()}
+                                    </TableCell>
+                                    <TableCell>{event.type}</TableCell>
+                                    <TableCell>
+                                        {[event.channel, event.recipient, event.detail].filter(Boolean).join(' · ')}
+                                    </TableCell>
+                                </TableRow>
+                            ))}
+                        </TableBody>
+                    </Table>
+                </DialogContent>
+                <DialogActions>
+                    <Button onClick={() => setDetail(null)}>Close</Button>
+                </DialogActions>
+            </Dialog>
         </Box>
     );
 }
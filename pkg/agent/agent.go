@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"runtime"
 	"time"
 
@@ -15,11 +16,21 @@ import (
 	"github.com/jyxjjj/Monitor/pkg/models"
 )
 
+// Version is the agent's version, reported in its host inventory. Release
+// builds set it with -ldflags "-X github.com/jyxjjj/Monitor/pkg/agent.Version=...".
+var Version = "1.0.0"
+
+// inventoryInterval is how often the host inventory is checked for changes
+const inventoryInterval = 10 * time.Minute
+
 // Agent represents the monitoring agent
 type Agent struct {
 	config    *models.AgentConfig
 	collector *collector.Collector
 	client    *http.Client
+
+	inventory        *models.HostInventory // last inventory the server accepted
+	inventoryChecked time.Time
 }
 
 // NewAgent creates a new agent
@@ -37,7 +48,7 @@ func NewAgent(config *models.AgentConfig) *Agent {
 
 	return &Agent{
 		config:    config,
-		collector: collector.NewCollector(config.AgentID),
+		collector: collector.NewCollector(config),
 		client:    client,
 	}
 }
@@ -46,7 +57,7 @@ func NewAgent(config *models.AgentConfig) *Agent {
 func (a *Agent) Run() error {
 	fmt.Printf("Agent %s starting, reporting to %s\n", a.config.AgentID, a.config.ServerURL)
 
-	ticker := time.NewTicker(time.Duration(a.config.ReportInterval) * time.Second)
+	ticker := time.NewTicker(time.Duration(a.config.ReportInterval * float64(time.Second)))
 	defer ticker.Stop()
 
 	// Send initial report immediately
@@ -72,6 +83,19 @@ func (a *Agent) report() error {
 
 	// Add platform information
 	metrics.AgentID = a.config.AgentID
+	metrics.Tags = a.config.Tags
+
+	// The host inventory goes out with the first report and whenever it
+	// changes. It is checked again sooner if the server did not get it.
+	checked := false
+	if a.inventory == nil || time.Since(a.inventoryChecked) >= inventoryInterval {
+		inventory := a.collector.Inventory()
+		inventory.AgentVersion = Version
+		if inventoryChanged(a.inventory, inventory) {
+			metrics.Inventory = inventory
+		}
+		checked = true
+	}
 
 	// Serialize to JSON
 	jsonData, err := json.Marshal(metrics)
@@ -106,9 +130,26 @@ func (a *Agent) report() error {
 		return fmt.Errorf("server returned error: %d - %s", resp.StatusCode, string(body))
 	}
 
+	if checked {
+		a.inventoryChecked = time.Now()
+		if metrics.Inventory != nil {
+			a.inventory = metrics.Inventory
+		}
+	}
 	return nil
 }
 
+// inventoryChanged reports whether the host inventory differs from the one
+// previously sent, ignoring the uptime which always does
+func inventoryChanged(prev, cur *models.HostInventory) bool {
+	if prev == nil {
+		return true
+	}
+	p, c := *prev, *cur
+	p.Uptime, c.Uptime = 0, 0
+	return !reflect.DeepEqual(p, c)
+}
+
 // GetPlatform returns the current platform
 func GetPlatform() string {
 	return runtime.GOOS
@@ -1,34 +1,72 @@
 package collector
 
 import (
+	"math"
 	"runtime"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/jyxjjj/Monitor/pkg/models"
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/load"
-	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/net"
 )
 
 // Collector collects system metrics
 type Collector struct {
 	agentID     string
-	lastNetRx   uint64
-	lastNetTx   uint64
+	config      *models.AgentConfig
+	lastNet     map[string]net.IOCountersStat
 	lastNetTime time.Time
+
+	lastDiskIO   map[string]disk.IOCountersStat
+	lastDiskTime time.Time
+
+	statMu   sync.Mutex
+	statting map[string]bool // mountpoints whose usage is still being read
+
+	lastCPU      *cpu.TimesStat
+	lastCPUCores []cpu.TimesStat
+
+	lastProcs    map[int32]processCPU
+	lastProcTime time.Time
+
+	watches       []watch
+	lastWatches   map[string]watchState
+	lastWatchCPU  map[int32]processCPU
+	lastWatchTime time.Time
+
+	hwmonReader hwmonReader
+	plugins     []*plugin
+
+	containerReader   containerReader
+	docker            *dockerClient
+	containerInfo     map[string]containerInfo
+	lastContainers    map[string]containerCounters
+	lastContainerTime time.Time
 }
 
 // NewCollector creates a new metrics collector
-func NewCollector(agentID string) *Collector {
+func NewCollector(config *models.AgentConfig) *Collector {
 	return &Collector{
-		agentID:     agentID,
+		agentID:     config.AgentID,
+		config:      config,
 		lastNetTime: time.Now(),
+		watches:     newWatches(config.Watches),
+		lastWatches: make(map[string]watchState),
+		statting:    make(map[string]bool),
+
+		hwmonReader:     hwmonReader{root: "/sys"},
+		plugins:         startPlugins(config.Plugins),
+		containerReader: containerReader{cgroupRoot: cgroupRoot, procRoot: "/proc"},
+		docker:          newDockerClient(config.Containers.DockerSocket),
 	}
 }
 
-// Collect gathers current system metrics
+// Collect gathers current system metrics. It does not block; rates and CPU
+// usage cover the time since the previous call.
 func (c *Collector) Collect() (*models.Metrics, error) {
 	metrics := &models.Metrics{
 		AgentID:   c.agentID,
@@ -37,17 +75,10 @@ func (c *Collector) Collect() (*models.Metrics, error) {
 	}
 
 	// CPU usage
-	cpuPercents, err := cpu.Percent(time.Second, false)
-	if err == nil && len(cpuPercents) > 0 {
-		metrics.CPUPercent = cpuPercents[0]
-	}
+	c.collectCPU(metrics)
 
 	// Memory usage
-	memInfo, err := mem.VirtualMemory()
-	if err == nil {
-		metrics.MemoryUsed = memInfo.Used
-		metrics.MemoryTotal = memInfo.Total
-	}
+	c.collectMemory(metrics)
 
 	// Disk usage
 	diskInfo, err := disk.Usage("/")
@@ -55,21 +86,27 @@ func (c *Collector) Collect() (*models.Metrics, error) {
 		metrics.DiskUsed = diskInfo.Used
 		metrics.DiskTotal = diskInfo.Total
 	}
+	metrics.Filesystems = c.collectFilesystems()
+	c.collectDiskIO(metrics)
 
 	// Network usage
-	netStats, err := net.IOCounters(false)
-	if err == nil && len(netStats) > 0 {
-		currentRx := netStats[0].BytesRecv
-		currentTx := netStats[0].BytesSent
-
-		// Calculate rate if we have previous data
-		if c.lastNetRx > 0 {
-			metrics.NetworkRx = currentRx - c.lastNetRx
-			metrics.NetworkTx = currentTx - c.lastNetTx
-		}
+	c.collectNetwork(metrics)
+
+	// Watched processes and services
+	c.collectWatches(metrics)
+
+	// Hardware sensors
+	c.collectSensors(metrics)
 
-		c.lastNetRx = currentRx
-		c.lastNetTx = currentTx
+	// Containers
+	c.collectContainers(metrics)
+
+	// Plugins
+	c.collectPlugins(metrics)
+
+	// Busiest processes, sampled less often than everything else
+	if c.processDue(metrics.Timestamp) {
+		metrics.Processes = c.collectProcesses()
 	}
 
 	// Load average (not available on Windows)
@@ -84,3 +121,27 @@ func (c *Collector) Collect() (*models.Metrics, error) {
 
 	return metrics, nil
 }
+
+// counters32Bit is whether the platform reports 32-bit counters. 32-bit
+// kernels keep the byte and operation counts of /proc/net/dev and
+// /proc/diskstats in an unsigned long.
+const counters32Bit = strconv.IntSize == 32
+
+// counterDelta returns how much a counter grew since prev. A counter that
+// went backwards either wrapped around 32 bits, on platforms that report
+// 32-bit counters, or was reset along with its device; a reset returns
+// false. 64-bit counters never wrap in practice, so there any decrease is a
+// reset, even one from between 2^31 and 2^32.
+func counterDelta(cur, prev uint64) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if counters32Bit && prev <= math.MaxUint32 {
+		// A wrap leaves a delta well under half the counter's range, while
+		// a reset to zero would look like a jump of almost the whole range
+		if delta := cur + (math.MaxUint32 + 1 - prev); delta < math.MaxUint32/2 {
+			return delta, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,278 @@
+package collector
+
+import (
+	"bufio"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// containerCgroupPattern matches the cgroup directories container runtimes
+// create, such as system.slice/docker-<id>.scope, docker/<id> and
+// kubepods.slice/.../cri-containerd-<id>.scope
+var containerCgroupPattern = regexp.MustCompile(`^(?:[a-z-]+-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// maxCgroupDepth bounds how deep container cgroups are looked for
+const maxCgroupDepth = 8
+
+// containerReader reads containers from a cgroup v2 hierarchy. The roots
+// are parameters so a fixture tree can stand in for /sys/fs/cgroup and /proc.
+type containerReader struct {
+	cgroupRoot string
+	procRoot   string
+}
+
+// containerCounters are the cumulative counters of a container's cgroup
+type containerCounters struct {
+	cpuUsec    uint64
+	readBytes  uint64
+	writeBytes uint64
+	rxBytes    uint64
+	txBytes    uint64
+}
+
+// containerSample is what was read for one container
+type containerSample struct {
+	id          string
+	memoryUsed  uint64
+	memoryLimit uint64 // 0 when unlimited
+	counters    containerCounters
+}
+
+// containers reads every container cgroup below the root. Hosts without a
+// cgroup v2 hierarchy have none.
+func (r containerReader) containers() ([]containerSample, error) {
+	if _, err := os.Stat(filepath.Join(r.cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, nil
+	}
+
+	var samples []containerSample
+	err := filepath.WalkDir(r.cgroupRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(r.cgroupRoot, path)
+		if rel != "." && strings.Count(rel, string(filepath.Separator)) >= maxCgroupDepth {
+			return filepath.SkipDir
+		}
+		match := containerCgroupPattern.FindStringSubmatch(d.Name())
+		if match == nil {
+			return nil
+		}
+		samples = append(samples, r.readContainer(match[1], path))
+		// Containers may create cgroups of their own below this one
+		return filepath.SkipDir
+	})
+	return samples, err
+}
+
+// readContainer reads the usage of a container from its cgroup directory.
+// Files a kernel does not provide are left at zero.
+func (r containerReader) readContainer(id, dir string) containerSample {
+	s := containerSample{id: id}
+	if stat := readKeyValues(filepath.Join(dir, "cpu.stat")); stat != nil {
+		s.counters.cpuUsec = stat["usage_usec"]
+	}
+	s.memoryUsed, _ = readUint(filepath.Join(dir, "memory.current"))
+	s.memoryLimit, _ = readUint(filepath.Join(dir, "memory.max")) // "max" when unlimited
+	s.counters.readBytes, s.counters.writeBytes = readIOStat(filepath.Join(dir, "io.stat"))
+
+	// Network counters belong to the container's network namespace, which
+	// any of its processes can be used to look into
+	if pids := readPids(filepath.Join(dir, "cgroup.procs")); len(pids) > 0 {
+		netDev := filepath.Join(r.procRoot, strconv.Itoa(int(pids[0])), "net", "dev")
+		s.counters.rxBytes, s.counters.txBytes = readNetDev(netDev)
+	}
+	return s
+}
+
+// collectContainers reports the usage of each running container since the
+// previous call, and the containers that started or stopped in between. The
+// first call only records which containers are running.
+func (c *Collector) collectContainers(metrics *models.Metrics) {
+	if c.config.Containers.Disabled {
+		return
+	}
+	samples, err := c.containerReader.containers()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(c.lastContainerTime).Seconds()
+	first := c.lastContainers == nil
+	current := make(map[string]containerCounters, len(samples))
+
+	// Names and labels only change with the set of containers, so Docker is
+	// only asked again when an unknown container shows up
+	for _, s := range samples {
+		if _, ok := c.containerInfo[s.id]; !ok {
+			info, err := c.docker.containers()
+			if err != nil {
+				info = make(map[string]containerInfo)
+			}
+			// Containers Docker does not know, such as those of other
+			// runtimes, are remembered so they are not asked about again
+			for _, s := range samples {
+				if _, ok := info[s.id]; !ok {
+					info[s.id] = containerInfo{}
+				}
+			}
+			c.containerInfo = info
+			break
+		}
+	}
+
+	for _, s := range samples {
+		current[s.id] = s.counters
+		info := c.containerInfo[s.id]
+		cm := models.ContainerMetrics{
+			ID:          s.id,
+			Name:        info.name,
+			Image:       info.image,
+			Labels:      info.labels,
+			MemoryUsed:  s.memoryUsed,
+			MemoryLimit: s.memoryLimit,
+		}
+		if cm.Name == "" {
+			cm.Name = s.id[:12]
+		}
+
+		prev, ok := c.lastContainers[s.id]
+		switch {
+		case ok && elapsed > 0:
+			rate := func(cur, prev uint64) float64 {
+				delta, ok := counterDelta(cur, prev)
+				if !ok {
+					return 0
+				}
+				return float64(delta) / elapsed
+			}
+			cm.CPUPercent = math.Round(rate(s.counters.cpuUsec, prev.cpuUsec)/1e4*10) / 10
+			cm.DiskReadBps = rate(s.counters.readBytes, prev.readBytes)
+			cm.DiskWriteBps = rate(s.counters.writeBytes, prev.writeBytes)
+			cm.NetworkRx = rate(s.counters.rxBytes, prev.rxBytes)
+			cm.NetworkTx = rate(s.counters.txBytes, prev.txBytes)
+		case !ok && !first:
+			metrics.ContainerEvents = append(metrics.ContainerEvents, models.ContainerEvent{
+				ContainerID: s.id, Name: cm.Name, Type: "start", Timestamp: now,
+			})
+		}
+		metrics.Containers = append(metrics.Containers, cm)
+	}
+
+	for id := range c.lastContainers {
+		if _, ok := current[id]; !ok {
+			name := c.containerInfo[id].name
+			if name == "" {
+				name = id[:12]
+			}
+			metrics.ContainerEvents = append(metrics.ContainerEvents, models.ContainerEvent{
+				ContainerID: id, Name: name, Type: "stop", Timestamp: now,
+			})
+		}
+	}
+
+	c.lastContainers = current
+	c.lastContainerTime = now
+}
+
+// readKeyValues reads a flat keyed file such as cpu.stat
+func readKeyValues(path string) map[string]uint64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values
+}
+
+// readUint reads a file holding a single number
+func readUint(path string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return v, err == nil
+}
+
+// readIOStat sums the bytes read and written over the devices in io.stat,
+// whose lines look like "8:0 rbytes=1459200 wbytes=314773504 rios=192 ..."
+func readIOStat(path string) (read, written uint64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				written += v
+			}
+		}
+	}
+	return read, written
+}
+
+// readNetDev sums the bytes received and sent over the interfaces in a
+// /proc/<pid>/net/dev file, leaving out loopback
+func readNetDev(path string) (rx, tx uint64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	// Two header lines, then "  eth0: <rx bytes> <7 more rx fields> <tx bytes> ..."
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			rx += v
+		}
+		if v, err := strconv.ParseUint(fields[8], 10, 64); err == nil {
+			tx += v
+		}
+	}
+	return rx, tx
+}
@@ -0,0 +1,270 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+var (
+	dockerID     = strings.Repeat("a1", 32)
+	containerdID = strings.Repeat("b2", 32)
+	cgroupfsID   = strings.Repeat("c3", 32)
+	nestedID     = strings.Repeat("d4", 32)
+)
+
+// writeFile writes a fixture file, creating the directories above it
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fixtureContainer is the usage written for one container
+type fixtureContainer struct {
+	pid       int
+	memoryMax string
+	cpuUsec   int
+	readBytes int
+	rxBytes   int
+}
+
+// writeContainer writes the cgroup files of a container, and the network
+// counters of its first process
+func writeContainer(t *testing.T, cgroupDir, procRoot string, c fixtureContainer) {
+	t.Helper()
+	pid := strconv.Itoa(c.pid)
+	writeFile(t, filepath.Join(cgroupDir, "cpu.stat"),
+		"usage_usec "+strconv.Itoa(c.cpuUsec)+"\nuser_usec 0\nsystem_usec 0\nnr_periods\n")
+	writeFile(t, filepath.Join(cgroupDir, "memory.current"), "1048576\n")
+	writeFile(t, filepath.Join(cgroupDir, "memory.max"), c.memoryMax+"\n")
+	writeFile(t, filepath.Join(cgroupDir, "io.stat"),
+		"8:0 rbytes="+strconv.Itoa(c.readBytes)+" wbytes=4096 rios=3 wios=1 dbytes=0 dios=0\n"+
+			"8:16 rbytes=100 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n")
+	writeFile(t, filepath.Join(cgroupDir, "cgroup.procs"), pid+"\n"+strconv.Itoa(c.pid+1)+"\n")
+	writeFile(t, filepath.Join(procRoot, pid, "net", "dev"),
+		"Inter-|   Receive                                                |  Transmit\n"+
+			" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n"+
+			"    lo:  999999 10 0 0 0 0 0 0  999999 10 0 0 0 0 0 0\n"+
+			"  eth0: "+strconv.Itoa(c.rxBytes)+" 10 0 0 0 0 0 0 500 5 0 0 0 0 0 0\n"+
+			"  eth1: 1000 1 0 0 0 0 0 0 250 1 0 0 0 0 0 0\n")
+}
+
+// newContainerFixture builds a cgroup v2 tree with a container in each
+// layout runtimes use, and a /proc with their network counters
+func newContainerFixture(t *testing.T) (cgroupRoot, procRoot string) {
+	t.Helper()
+	dir := t.TempDir()
+	cgroupRoot = filepath.Join(dir, "cgroup")
+	procRoot = filepath.Join(dir, "proc")
+	writeFile(t, filepath.Join(cgroupRoot, "cgroup.controllers"), "cpu io memory pids\n")
+
+	// Docker with the systemd cgroup driver
+	writeContainer(t, filepath.Join(cgroupRoot, "system.slice", "docker-"+dockerID+".scope"), procRoot,
+		fixtureContainer{pid: 100, memoryMax: "max", cpuUsec: 1000000, readBytes: 8192, rxBytes: 2000})
+	// containerd under Kubernetes
+	writeContainer(t, filepath.Join(cgroupRoot, "kubepods.slice", "kubepods-burstable.slice",
+		"kubepods-burstable-pod0a1b.slice", "cri-containerd-"+containerdID+".scope"), procRoot,
+		fixtureContainer{pid: 200, memoryMax: "536870912", cpuUsec: 5000000, readBytes: 0, rxBytes: 0})
+	// Docker with the cgroupfs driver, running a container of its own
+	outer := filepath.Join(cgroupRoot, "docker", cgroupfsID)
+	writeContainer(t, outer, procRoot,
+		fixtureContainer{pid: 300, memoryMax: "max", cpuUsec: 0, readBytes: 0, rxBytes: 0})
+	writeContainer(t, filepath.Join(outer, "docker", nestedID), procRoot,
+		fixtureContainer{pid: 400, memoryMax: "max", cpuUsec: 0, readBytes: 0, rxBytes: 0})
+
+	// Cgroups that are not containers
+	writeFile(t, filepath.Join(cgroupRoot, "system.slice", "sshd.service", "cgroup.procs"), "1\n")
+	writeFile(t, filepath.Join(cgroupRoot, "user.slice", "user-1000.slice", "cgroup.procs"), "2\n")
+	return cgroupRoot, procRoot
+}
+
+func newContainerCollector(t *testing.T, cgroupRoot, procRoot string) *Collector {
+	return &Collector{
+		config:          &models.AgentConfig{},
+		containerReader: containerReader{cgroupRoot: cgroupRoot, procRoot: procRoot},
+		// No daemon listens here, so containers are named by their short id
+		docker: newDockerClient(filepath.Join(t.TempDir(), "docker.sock")),
+	}
+}
+
+func TestContainersLayouts(t *testing.T) {
+	cgroupRoot, procRoot := newContainerFixture(t)
+	samples, err := containerReader{cgroupRoot: cgroupRoot, procRoot: procRoot}.containers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byID := make(map[string]containerSample)
+	for _, s := range samples {
+		byID[s.id] = s
+	}
+	if len(samples) != 3 {
+		t.Fatalf("got %d containers, want docker, containerd and cgroupfs: %+v", len(samples), samples)
+	}
+	if _, ok := byID[nestedID]; ok {
+		t.Error("a container nested in another was reported on its own")
+	}
+
+	docker, ok := byID[dockerID]
+	if !ok {
+		t.Fatal("docker container in system.slice not found")
+	}
+	want := containerCounters{cpuUsec: 1000000, readBytes: 8292, writeBytes: 4096, rxBytes: 3000, txBytes: 750}
+	if docker.counters != want {
+		t.Errorf("docker counters = %+v, want %+v", docker.counters, want)
+	}
+	if docker.memoryUsed != 1048576 || docker.memoryLimit != 0 {
+		t.Errorf("docker memory = %d of %d, want 1048576 of 0 (unlimited)", docker.memoryUsed, docker.memoryLimit)
+	}
+
+	containerd, ok := byID[containerdID]
+	if !ok {
+		t.Fatal("containerd container in kubepods.slice not found")
+	}
+	if containerd.memoryLimit != 536870912 {
+		t.Errorf("containerd memory limit = %d, want 536870912", containerd.memoryLimit)
+	}
+	if _, ok := byID[cgroupfsID]; !ok {
+		t.Error("docker container with the cgroupfs driver not found")
+	}
+}
+
+func TestContainersWithoutCgroupV2(t *testing.T) {
+	samples, err := containerReader{cgroupRoot: t.TempDir(), procRoot: t.TempDir()}.containers()
+	if err != nil || samples != nil {
+		t.Errorf("got %v, %v on a host without cgroup v2, want nothing", samples, err)
+	}
+}
+
+func TestCollectContainersEvents(t *testing.T) {
+	cgroupRoot, procRoot := newContainerFixture(t)
+	c := newContainerCollector(t, cgroupRoot, procRoot)
+
+	first := &models.Metrics{}
+	c.collectContainers(first)
+	if len(first.Containers) != 3 {
+		t.Fatalf("got %d containers on the first call, want 3", len(first.Containers))
+	}
+	if len(first.ContainerEvents) != 0 {
+		t.Errorf("the first call reported events %+v, want none", first.ContainerEvents)
+	}
+
+	// Ten seconds later the docker container used half a core, the
+	// containerd one stopped and a new one started
+	stopped := filepath.Join(cgroupRoot, "kubepods.slice", "kubepods-burstable.slice",
+		"kubepods-burstable-pod0a1b.slice", "cri-containerd-"+containerdID+".scope")
+	if err := os.RemoveAll(stopped); err != nil {
+		t.Fatal(err)
+	}
+	writeContainer(t, filepath.Join(cgroupRoot, "system.slice", "docker-"+dockerID+".scope"), procRoot,
+		fixtureContainer{pid: 100, memoryMax: "max", cpuUsec: 6000000, readBytes: 8192 + 40960, rxBytes: 2000 + 10240})
+	startedID := strings.Repeat("e5", 32)
+	writeContainer(t, filepath.Join(cgroupRoot, "system.slice", "docker-"+startedID+".scope"), procRoot,
+		fixtureContainer{pid: 500, memoryMax: "max", cpuUsec: 10, readBytes: 0, rxBytes: 0})
+	c.lastContainerTime = time.Now().Add(-10 * time.Second)
+
+	second := &models.Metrics{}
+	c.collectContainers(second)
+
+	events := make(map[string]models.ContainerEvent)
+	for _, e := range second.ContainerEvents {
+		events[e.ContainerID] = e
+	}
+	if len(events) != 2 {
+		t.Fatalf("got events %+v, want one start and one stop", second.ContainerEvents)
+	}
+	if e := events[startedID]; e.Type != "start" || e.Name != startedID[:12] {
+		t.Errorf("start event = %+v", e)
+	}
+	if e := events[containerdID]; e.Type != "stop" || e.Name != containerdID[:12] {
+		t.Errorf("stop event = %+v", e)
+	}
+
+	var docker *models.ContainerMetrics
+	for i := range second.Containers {
+		if second.Containers[i].ID == dockerID {
+			docker = &second.Containers[i]
+		}
+	}
+	if docker == nil {
+		t.Fatal("docker container missing from the second call")
+	}
+	if docker.CPUPercent < 49 || docker.CPUPercent > 50 {
+		t.Errorf("docker CPU = %v%%, want about 50%%", docker.CPUPercent)
+	}
+	if docker.DiskReadBps < 4000 || docker.DiskReadBps > 4096 {
+		t.Errorf("docker disk reads = %v B/s, want about 4096", docker.DiskReadBps)
+	}
+	if docker.NetworkRx < 1000 || docker.NetworkRx > 1024 {
+		t.Errorf("docker network rx = %v B/s, want about 1024", docker.NetworkRx)
+	}
+	if docker.MemoryLimit != 0 {
+		t.Errorf("docker memory limit = %d, want 0 for \"max\"", docker.MemoryLimit)
+	}
+}
+
+func TestCollectContainersDisabled(t *testing.T) {
+	cgroupRoot, procRoot := newContainerFixture(t)
+	c := newContainerCollector(t, cgroupRoot, procRoot)
+	c.config.Containers.Disabled = true
+
+	metrics := &models.Metrics{}
+	c.collectContainers(metrics)
+	if len(metrics.Containers) != 0 {
+		t.Errorf("got %d containers with container collection disabled", len(metrics.Containers))
+	}
+}
+
+func TestReadKeyValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cpu.stat")
+	writeFile(t, path, "usage_usec 123\nuser_usec 100\nnr_throttled\nthrottled_usec 12 extra\nbad_value x\n")
+
+	values := readKeyValues(path)
+	if len(values) != 2 || values["usage_usec"] != 123 || values["user_usec"] != 100 {
+		t.Errorf("readKeyValues = %v, want usage_usec 123 and user_usec 100", values)
+	}
+	if readKeyValues(filepath.Join(t.TempDir(), "missing")) != nil {
+		t.Error("readKeyValues of a missing file is not nil")
+	}
+}
+
+func TestReadIOStat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "io.stat")
+	writeFile(t, path, "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0\n"+
+		"253:0 rbytes=800 wbytes=496 rios=1 wios=1\n"+
+		"8:16 rbytes=bad wbytes=4\n")
+
+	read, written := readIOStat(path)
+	if read != 1460000 || written != 314774004 {
+		t.Errorf("readIOStat = %d, %d, want 1460000, 314774004", read, written)
+	}
+	if read, written := readIOStat(filepath.Join(t.TempDir(), "missing")); read != 0 || written != 0 {
+		t.Errorf("readIOStat of a missing file = %d, %d, want 0, 0", read, written)
+	}
+}
+
+func TestReadNetDev(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev")
+	writeFile(t, path,
+		"Inter-|   Receive                                                |  Transmit\n"+
+			" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n"+
+			"    lo: 5000 50 0 0 0 0 0 0 5000 50 0 0 0 0 0 0\n"+
+			"  eth0: 1200 12 0 0 0 0 0 0 3400 34 0 0 0 0 0 0\n"+
+			"  eth1:56 1 0 0 0 0 0 0 78 1 0 0 0 0 0 0\n"+
+			"  bad0: 1 2 3\n")
+
+	rx, tx := readNetDev(path)
+	if rx != 1256 || tx != 3478 {
+		t.Errorf("readNetDev = %d, %d, want 1256, 3478", rx, tx)
+	}
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// cpuTotal returns all the time a CPU has counted. Guest time is already
+// part of user time on Linux, so it is left out.
+func cpuTotal(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// cpuShare returns the share of the time between two readings a field
+// accounts for, in percent
+func cpuShare(cur, prev, elapsed float64) float64 {
+	return math.Max(cur-prev, 0) / elapsed * 100
+}
+
+// cpuBusy returns how busy a CPU was between two readings in percent, or
+// false if the counters did not advance, as after a CPU was taken offline
+func cpuBusy(cur, prev cpu.TimesStat) (float64, bool) {
+	elapsed := cpuTotal(cur) - cpuTotal(prev)
+	if elapsed <= 0 {
+		return 0, false
+	}
+	idle := cpuShare(cur.Idle, prev.Idle, elapsed) + cpuShare(cur.Iowait, prev.Iowait, elapsed)
+	return math.Min(math.Max(100-idle, 0), 100), true
+}
+
+// collectCPU reports CPU usage, per-core utilisation and how CPU time was
+// spent since the previous call, from the difference between two readings
+// of the CPU time counters, so it never waits for a measuring interval. The
+// first call reports the average since boot and has no per-core figures.
+func (c *Collector) collectCPU(metrics *models.Metrics) {
+	if times, err := cpu.Times(false); err == nil && len(times) > 0 {
+		cur := times[0]
+		var prev cpu.TimesStat
+		if c.lastCPU != nil {
+			prev = *c.lastCPU
+		}
+		// Calls closer together than the counters' resolution keep the
+		// older reading, so the next call measures a longer period
+		if elapsed := cpuTotal(cur) - cpuTotal(prev); elapsed > 0 {
+			metrics.CPUPercent, _ = cpuBusy(cur, prev)
+			metrics.CPUUser = cpuShare(cur.User+cur.Nice, prev.User+prev.Nice, elapsed)
+			metrics.CPUSystem = cpuShare(cur.System, prev.System, elapsed)
+			metrics.CPUIowait = cpuShare(cur.Iowait, prev.Iowait, elapsed)
+			metrics.CPUSteal = cpuShare(cur.Steal, prev.Steal, elapsed)
+			metrics.CPUIrq = cpuShare(cur.Irq+cur.Softirq, prev.Irq+prev.Softirq, elapsed)
+			c.lastCPU = &cur
+		}
+	}
+
+	if cores, err := cpu.Times(true); err == nil {
+		// Cores going on or offline change the list; start over then
+		if len(cores) != len(c.lastCPUCores) {
+			c.lastCPUCores = cores
+		} else if busy, ok := coresBusy(cores, c.lastCPUCores); ok {
+			metrics.CPUPerCore = busy
+			c.lastCPUCores = cores
+		}
+	}
+}
+
+// coresBusy returns how busy each core was between two readings in percent,
+// or false if no core's counters advanced, in which case the older reading
+// is kept like the aggregate one
+func coresBusy(cores, prev []cpu.TimesStat) ([]float64, bool) {
+	advanced := false
+	result := make([]float64, len(cores))
+	for i, cur := range cores {
+		busy, ok := cpuBusy(cur, prev[i])
+		advanced = advanced || ok
+		result[i] = math.Round(busy*10) / 10
+	}
+	return result, advanced
+}
@@ -0,0 +1,29 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestCoresBusy(t *testing.T) {
+	prev := []cpu.TimesStat{
+		{User: 100, Idle: 100},
+		{User: 100, Idle: 100},
+	}
+
+	// Readings closer together than the counters' resolution report nothing
+	if busy, ok := coresBusy(prev, prev); ok {
+		t.Errorf("coresBusy without elapsed time = %v, want no reading", busy)
+	}
+
+	cur := []cpu.TimesStat{
+		{User: 130, Idle: 110},
+		{User: 100, Idle: 100}, // offline, so its counters stood still
+	}
+	busy, ok := coresBusy(cur, prev)
+	if !ok || !reflect.DeepEqual(busy, []float64{75, 0}) {
+		t.Errorf("coresBusy = %v, %v, want [75 0]", busy, ok)
+	}
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// collectDiskIO reports the I/O of each block device since the previous
+// call. The first call only records the counters. Device mapper and RAID
+// devices are reported on their own but left out of the totals, which
+// already count the I/O of the disks beneath them.
+func (c *Collector) collectDiskIO(metrics *models.Metrics) {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	previous, elapsed := c.lastDiskIO, now.Sub(c.lastDiskTime).Seconds()
+	c.lastDiskIO, c.lastDiskTime = counters, now
+	if previous == nil || elapsed <= 0 {
+		return
+	}
+
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		if wholeDisk(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var ops, opTime uint64
+	for _, name := range names {
+		prev, ok := previous[name]
+		if !ok {
+			continue
+		}
+		cur := counters[name]
+		readBytes, ok1 := counterDelta(cur.ReadBytes, prev.ReadBytes)
+		writeBytes, ok2 := counterDelta(cur.WriteBytes, prev.WriteBytes)
+		reads, ok3 := counterDelta(cur.ReadCount, prev.ReadCount)
+		writes, ok4 := counterDelta(cur.WriteCount, prev.WriteCount)
+		busy, ok5 := counterDelta(cur.IoTime, prev.IoTime)
+		waited, ok6 := counterDelta(cur.ReadTime+cur.WriteTime, prev.ReadTime+prev.WriteTime)
+		if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 {
+			// The device was reset or replaced; start over next time
+			continue
+		}
+
+		d := models.DiskIOMetrics{
+			Device:      name,
+			ReadBps:     float64(readBytes) / elapsed,
+			WriteBps:    float64(writeBytes) / elapsed,
+			ReadIOPS:    float64(reads) / elapsed,
+			WriteIOPS:   float64(writes) / elapsed,
+			UtilPercent: math.Min(float64(busy)/(elapsed*1000)*100, 100),
+		}
+		if reads+writes > 0 {
+			d.AwaitMs = float64(waited) / float64(reads+writes)
+		}
+		metrics.Disks = append(metrics.Disks, d)
+		if stackedDevice(name) {
+			continue
+		}
+
+		metrics.DiskReadBps += d.ReadBps
+		metrics.DiskWriteBps += d.WriteBps
+		metrics.DiskReadIOPS += d.ReadIOPS
+		metrics.DiskWriteIOPS += d.WriteIOPS
+		metrics.DiskUtilPercent = math.Max(metrics.DiskUtilPercent, d.UtilPercent)
+		ops += reads + writes
+		opTime += waited
+	}
+	if ops > 0 {
+		metrics.DiskAwaitMs = float64(opTime) / float64(ops)
+	}
+}
+
+// wholeDisk reports whether a device is a disk rather than a partition of
+// one, so I/O is not counted twice. Loop and RAM devices are skipped.
+func wholeDisk(name string) bool {
+	if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") {
+		return false
+	}
+	// Only Linux lists block devices this way; elsewhere trust the counters
+	if _, err := os.Stat("/sys/block"); err != nil {
+		return true
+	}
+	_, err := os.Stat(filepath.Join("/sys/block", name))
+	return err == nil
+}
+
+// stackedDevice reports whether a device is built on top of other block
+// devices, as device mapper (LVM, dm-crypt) and md RAID devices are
+func stackedDevice(name string) bool {
+	slaves, err := os.ReadDir(filepath.Join("/sys/block", name, "slaves"))
+	return err == nil && len(slaves) > 0
+}
@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultDockerSocket is where the Docker daemon listens unless configured
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// containerInfo is what Docker knows about a container beyond its cgroup
+type containerInfo struct {
+	name   string
+	image  string
+	labels map[string]string
+}
+
+// dockerClient asks the Docker daemon about running containers over its
+// unix socket
+type dockerClient struct {
+	client *http.Client
+}
+
+// newDockerClient creates a client for the Docker socket at path
+func newDockerClient(path string) *dockerClient {
+	if path == "" {
+		path = defaultDockerSocket
+	}
+	dialer := &net.Dialer{}
+	return &dockerClient{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+			Timeout: 2 * time.Second,
+		},
+	}
+}
+
+// containers returns the names, images and labels of running containers by
+// their full id
+func (d *dockerClient) containers() (map[string]containerInfo, error) {
+	resp, err := d.client.Get("http://docker/containers/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker returned %d", resp.StatusCode)
+	}
+
+	var list []struct {
+		ID     string            `json:"Id"`
+		Names  []string          `json:"Names"`
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	info := make(map[string]containerInfo, len(list))
+	for _, c := range list {
+		ci := containerInfo{image: c.Image, labels: c.Labels}
+		if len(c.Names) > 0 {
+			ci.name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		info[c.ID] = ci
+	}
+	return info, nil
+}
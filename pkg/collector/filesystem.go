@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"path"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// defaultExcludedFSTypes are pseudo, in-memory and network filesystems
+// skipped unless the agent configures its own exclude list. Network and
+// FUSE mounts are left out because a server that stops answering blocks
+// every call that reads their usage.
+var defaultExcludedFSTypes = []string{
+	"tmpfs", "devtmpfs", "overlay", "squashfs", "proc", "sysfs", "cgroup", "cgroup2",
+	"devpts", "mqueue", "debugfs", "tracefs", "securityfs", "pstore", "bpf", "configfs",
+	"fusectl", "hugetlbfs", "autofs", "binfmt_misc", "nsfs", "rpc_pipefs", "ramfs",
+	"nfs", "nfs4", "cifs", "smb3", "smbfs", "9p", "afs", "ceph", "glusterfs", "lustre",
+	"fuse", "fuse.*", "fuseblk",
+}
+
+// usageTimeout bounds how long reading one filesystem's usage may take
+const usageTimeout = 2 * time.Second
+
+// collectFilesystems reports the usage of every mounted filesystem the
+// filter selects. A device mounted more than once is only reported once.
+func (c *Collector) collectFilesystems() []models.FilesystemMetrics {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return nil
+	}
+
+	var filesystems []models.FilesystemMetrics
+	seen := make(map[string]bool)
+	for _, p := range partitions {
+		if !includeFilesystem(c.config.Filesystems, p.Mountpoint, p.Fstype) {
+			continue
+		}
+		if p.Device != "" && p.Device != "none" && seen[p.Device] {
+			continue
+		}
+
+		usage := c.filesystemUsage(p.Mountpoint)
+		if usage == nil || usage.Total == 0 {
+			continue
+		}
+		seen[p.Device] = true
+		filesystems = append(filesystems, models.FilesystemMetrics{
+			Mountpoint:  p.Mountpoint,
+			Device:      p.Device,
+			FSType:      p.Fstype,
+			Used:        usage.Used,
+			Total:       usage.Total,
+			InodesUsed:  usage.InodesUsed,
+			InodesTotal: usage.InodesTotal,
+		})
+	}
+	return filesystems
+}
+
+// filesystemUsage reads the usage of a mounted filesystem, or returns nil if
+// it cannot be read within usageTimeout. A call that hangs, as on a network
+// mount whose server is gone, is left running in the background and the
+// mount is skipped until it returns, so hung calls do not pile up.
+func (c *Collector) filesystemUsage(mountpoint string) *disk.UsageStat {
+	c.statMu.Lock()
+	if c.statting[mountpoint] {
+		c.statMu.Unlock()
+		return nil
+	}
+	c.statting[mountpoint] = true
+	c.statMu.Unlock()
+
+	result := make(chan *disk.UsageStat, 1)
+	go func() {
+		usage, err := disk.Usage(mountpoint)
+		c.statMu.Lock()
+		delete(c.statting, mountpoint)
+		c.statMu.Unlock()
+		if err != nil {
+			usage = nil
+		}
+		result <- usage
+	}()
+
+	timer := time.NewTimer(usageTimeout)
+	defer timer.Stop()
+	select {
+	case usage := <-result:
+		return usage
+	case <-timer.C:
+		return nil
+	}
+}
+
+// includeFilesystem reports whether a filter selects a mount
+func includeFilesystem(filter models.FilesystemFilter, mountpoint, fstype string) bool {
+	excludedTypes := filter.ExcludeFSTypes
+	if excludedTypes == nil {
+		excludedTypes = defaultExcludedFSTypes
+	}
+
+	if len(filter.IncludeMountpoints) > 0 && !matchAny(filter.IncludeMountpoints, mountpoint) {
+		return false
+	}
+	if len(filter.IncludeFSTypes) > 0 && !matchAny(filter.IncludeFSTypes, fstype) {
+		return false
+	}
+	return !matchAny(filter.ExcludeMountpoints, mountpoint) && !matchAny(excludedTypes, fstype)
+}
+
+// matchAny reports whether value matches any of the glob patterns
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	stdnet "net"
+	"runtime"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// Inventory describes the host the agent runs on. Details that cannot be
+// read are left empty.
+func (c *Collector) Inventory() *models.HostInventory {
+	inv := &models.HostInventory{
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		CPUCount: runtime.NumCPU(),
+	}
+
+	if info, err := host.Info(); err == nil {
+		inv.Hostname = info.Hostname
+		inv.Platform = info.Platform
+		inv.PlatformVersion = info.PlatformVersion
+		inv.KernelVersion = info.KernelVersion
+		if info.KernelArch != "" {
+			inv.Arch = info.KernelArch
+		}
+		// A hypervisor's own host reports the role "host", and is bare metal
+		if info.VirtualizationRole == "guest" {
+			inv.Virtualization = info.VirtualizationSystem
+		}
+		if info.BootTime > 0 {
+			inv.BootTime = time.Unix(int64(info.BootTime), 0)
+		}
+		inv.Uptime = info.Uptime
+	}
+
+	if cpus, err := cpu.Info(); err == nil && len(cpus) > 0 {
+		inv.CPUModel = cpus[0].ModelName
+	}
+
+	if memInfo, err := mem.VirtualMemory(); err == nil {
+		inv.MemoryTotal = memInfo.Total
+	}
+
+	inv.IPAddresses = ipAddresses()
+	return inv
+}
+
+// ipAddresses lists the addresses of the host's interfaces, leaving out
+// loopback and link-local addresses, which are the same on every host
+func ipAddresses() []string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var addrs []string
+	for _, iface := range ifaces {
+		for _, addr := range iface.Addrs {
+			ip, _, err := stdnet.ParseCIDR(addr.Addr)
+			if err != nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			addrs = append(addrs, addr.Addr)
+		}
+	}
+	return addrs
+}
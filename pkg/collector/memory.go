@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// memoryPressurePath is where Linux reports pressure stall information for
+// memory, on kernels built with PSI
+const memoryPressurePath = "/proc/pressure/memory"
+
+// collectMemory reports memory, swap and, on Linux, memory pressure
+func (c *Collector) collectMemory(metrics *models.Metrics) {
+	if memInfo, err := mem.VirtualMemory(); err == nil {
+		metrics.MemoryUsed = memInfo.Used
+		metrics.MemoryTotal = memInfo.Total
+		metrics.MemoryAvailable = memInfo.Available
+		metrics.MemoryCached = memInfo.Cached
+		metrics.MemoryBuffers = memInfo.Buffers
+	}
+
+	if swapInfo, err := mem.SwapMemory(); err == nil {
+		metrics.SwapUsed = swapInfo.Used
+		metrics.SwapTotal = swapInfo.Total
+	}
+
+	if some, full, ok := readPressure(memoryPressurePath); ok {
+		metrics.MemoryPressureSome = some
+		metrics.MemoryPressureFull = full
+	}
+}
+
+// readPressure reads the 10 second averages of a PSI file, the share of time
+// some or all tasks were stalled waiting for the resource. It returns false
+// if the file is missing, as it is on other platforms and older kernels.
+func readPressure(path string) (some, full float64, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+
+	// Lines look like "some avg10=0.31 avg60=0.12 avg300=0.03 total=123456"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		avg10, found := strings.CutPrefix(fields[1], "avg10=")
+		if !found {
+			continue
+		}
+		value, err := strconv.ParseFloat(avg10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "some":
+			some, ok = value, true
+		case "full":
+			full = value
+		}
+	}
+	return some, full, ok
+}
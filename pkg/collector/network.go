@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// defaultExcludedInterfaces are loopback, container and bridge interfaces
+// skipped unless the agent configures its own exclude list. Their traffic
+// is either local or already counted on a physical interface.
+var defaultExcludedInterfaces = []string{
+	"lo", "lo0", "Loopback*", "docker*", "veth*", "br-*", "virbr*", "cni*", "flannel*", "cali*", "tun*", "ifb*",
+}
+
+// collectNetwork reports the traffic of each interface the filter selects
+// per second since the previous call. The first call only records the
+// counters, as does the first call after an interface appears or resets.
+func (c *Collector) collectNetwork(metrics *models.Metrics) {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	previous, elapsed := c.lastNet, now.Sub(c.lastNetTime).Seconds()
+	c.lastNet = make(map[string]net.IOCountersStat, len(counters))
+	c.lastNetTime = now
+	for _, stat := range counters {
+		if includeInterface(c.config.Interfaces, stat.Name) {
+			c.lastNet[stat.Name] = stat
+		}
+	}
+	if previous == nil || elapsed <= 0 {
+		return
+	}
+
+	names := make([]string, 0, len(c.lastNet))
+	for name := range c.lastNet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rx, tx float64
+	for _, name := range names {
+		prev, ok := previous[name]
+		if !ok {
+			continue
+		}
+		cur := c.lastNet[name]
+
+		var deltas [8]uint64
+		reset := false
+		for i, pair := range [][2]uint64{
+			{cur.BytesRecv, prev.BytesRecv}, {cur.BytesSent, prev.BytesSent},
+			{cur.PacketsRecv, prev.PacketsRecv}, {cur.PacketsSent, prev.PacketsSent},
+			{cur.Errin, prev.Errin}, {cur.Errout, prev.Errout},
+			{cur.Dropin, prev.Dropin}, {cur.Dropout, prev.Dropout},
+		} {
+			delta, ok := counterDelta(pair[0], pair[1])
+			if !ok {
+				reset = true
+				break
+			}
+			deltas[i] = delta
+		}
+		if reset {
+			continue
+		}
+
+		iface := models.InterfaceMetrics{
+			Name:      name,
+			RxBytes:   float64(deltas[0]) / elapsed,
+			TxBytes:   float64(deltas[1]) / elapsed,
+			RxPackets: float64(deltas[2]) / elapsed,
+			TxPackets: float64(deltas[3]) / elapsed,
+			RxErrors:  float64(deltas[4]) / elapsed,
+			TxErrors:  float64(deltas[5]) / elapsed,
+			RxDrops:   float64(deltas[6]) / elapsed,
+			TxDrops:   float64(deltas[7]) / elapsed,
+		}
+		metrics.Interfaces = append(metrics.Interfaces, iface)
+
+		rx += iface.RxBytes
+		tx += iface.TxBytes
+		metrics.NetworkRxPackets += iface.RxPackets
+		metrics.NetworkTxPackets += iface.TxPackets
+		metrics.NetworkErrors += iface.RxErrors + iface.TxErrors
+		metrics.NetworkDrops += iface.RxDrops + iface.TxDrops
+	}
+	metrics.NetworkRx = uint64(math.Round(rx))
+	metrics.NetworkTx = uint64(math.Round(tx))
+}
+
+// includeInterface reports whether a filter selects an interface
+func includeInterface(filter models.InterfaceFilter, name string) bool {
+	excluded := filter.Exclude
+	if excluded == nil {
+		excluded = defaultExcludedInterfaces
+	}
+	if len(filter.Include) > 0 && !matchAny(filter.Include, name) {
+		return false
+	}
+	return !matchAny(excluded, name)
+}
@@ -0,0 +1,175 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// Defaults for plugins that do not set their own, in seconds
+const (
+	defaultPluginInterval = 60
+	defaultPluginTimeout  = 10
+)
+
+// maxPluginOutput caps how much of a plugin's output is read
+const maxPluginOutput = 64 * 1024
+
+// maxPluginMetrics caps how many series one plugin may report
+const maxPluginMetrics = 100
+
+// Nagios plugin exit codes
+const (
+	nagiosOK      = 0
+	nagiosUnknown = 3
+)
+
+// plugin is a configured exec plugin and the metrics of its latest run
+type plugin struct {
+	config   models.PluginConfig
+	interval time.Duration
+	timeout  time.Duration
+	err      error // configuration problem, reported instead of running
+
+	mu      sync.Mutex
+	metrics []models.CustomMetric
+}
+
+// startPlugins starts running each configured plugin at its own interval.
+// Invalid plugins are reported once and never run.
+func startPlugins(configs []models.PluginConfig) []*plugin {
+	plugins := make([]*plugin, 0, len(configs))
+	for _, pc := range configs {
+		p := &plugin{
+			config:   pc,
+			interval: time.Duration(defaultPluginInterval * float64(time.Second)),
+			timeout:  time.Duration(defaultPluginTimeout * float64(time.Second)),
+		}
+		if pc.Interval > 0 {
+			p.interval = time.Duration(pc.Interval * float64(time.Second))
+		}
+		if pc.Timeout > 0 {
+			p.timeout = time.Duration(pc.Timeout * float64(time.Second))
+		}
+		switch {
+		case len(pc.Command) == 0 || pc.Command[0] == "":
+			p.err = errors.New("no command")
+		case pc.Format != "" && pc.Format != "nagios" && pc.Format != "influx":
+			p.err = fmt.Errorf("unknown format %q, must be nagios or influx", pc.Format)
+		}
+		if p.config.Name == "" && len(pc.Command) > 0 {
+			p.config.Name = filepath.Base(pc.Command[0])
+		}
+		plugins = append(plugins, p)
+		go p.run()
+	}
+	return plugins
+}
+
+// run runs the plugin now and then at every interval, keeping the metrics
+// of the latest run. A failed run reports what it can; for an Influx plugin
+// that is nothing, so its series stop until it succeeds again.
+func (p *plugin) run() {
+	if p.err != nil {
+		fmt.Printf("Plugin %s is not run: %v\n", p.config.Name, p.err)
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		metrics, err := p.exec()
+		if err != nil {
+			fmt.Printf("Plugin %s failed: %v\n", p.config.Name, err)
+		}
+		p.mu.Lock()
+		p.metrics = metrics
+		p.mu.Unlock()
+		<-ticker.C
+	}
+}
+
+// exec runs the plugin once and parses what it printed
+func (p *plugin) exec() ([]models.CustomMetric, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{max: maxPluginOutput}
+	cmd := exec.CommandContext(ctx, p.config.Command[0], p.config.Command[1:]...)
+	cmd.Stdout = stdout
+	// Children the plugin left behind may hold its output open
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %s", p.timeout)
+	}
+
+	if p.config.Format == "influx" {
+		if err != nil {
+			return nil, err
+		}
+		return limitMetrics(parseInflux(stdout.Bytes())), nil
+	}
+
+	// Nagios plugins exit with their status, so only failing to run or
+	// exiting with a code outside 0-3 is an error
+	status := nagiosOK
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 && exitErr.ExitCode() <= nagiosUnknown:
+		status, err = exitErr.ExitCode(), nil
+	case err != nil:
+		status = nagiosUnknown
+	}
+	metrics := limitMetrics(parseNagios(p.config.Name, stdout.Bytes()))
+	metrics = append(metrics, models.CustomMetric{Name: p.config.Name + ".status", Value: float64(status)})
+	return metrics, err
+}
+
+// latest returns the metrics of the plugin's latest run
+func (p *plugin) latest() []models.CustomMetric {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.metrics
+}
+
+// collectPlugins reports the latest metrics of each plugin. Plugins run at
+// their own interval, so a value is reported again until the next run.
+func (c *Collector) collectPlugins(metrics *models.Metrics) {
+	for _, p := range c.plugins {
+		metrics.Custom = append(metrics.Custom, p.latest()...)
+	}
+}
+
+// limitMetrics drops the series beyond maxPluginMetrics
+func limitMetrics(metrics []models.CustomMetric) []models.CustomMetric {
+	if len(metrics) > maxPluginMetrics {
+		return metrics[:maxPluginMetrics]
+	}
+	return metrics
+}
+
+// cappedBuffer keeps the first max bytes written to it and drops the rest,
+// so a plugin printing without end cannot exhaust the agent's memory
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(data []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		if len(data) > room {
+			b.Buffer.Write(data[:room])
+		} else {
+			b.Buffer.Write(data)
+		}
+	}
+	return len(data), nil
+}
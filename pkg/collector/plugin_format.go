@@ -0,0 +1,241 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// maxCustomNameLength bounds the name of a custom series
+const maxCustomNameLength = 255
+
+// perfValuePattern splits a performance data value such as "12.5ms" into
+// the number and its unit of measure
+var perfValuePattern = regexp.MustCompile(`^([-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)([a-zA-Z%]*)$`)
+
+// parseNagios reads the performance data of a Nagios plugin's output. It
+// follows a "|" on the first line, such as
+//
+//	OK - 12 jobs queued | queue=12;100;500;0 'oldest job'=3.5s
+//
+// and on a later line, after which every line is performance data.
+func parseNagios(name string, output []byte) []models.CustomMetric {
+	var perfdata []string
+	inPerfdata := false
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if inPerfdata {
+			perfdata = append(perfdata, line)
+			continue
+		}
+		if _, perf, ok := strings.Cut(line, "|"); ok {
+			perfdata = append(perfdata, perf)
+			// Only the performance data after the long text continues on
+			// the following lines
+			inPerfdata = !first
+		}
+	}
+
+	var metrics []models.CustomMetric
+	for _, label := range splitPerfdata(strings.Join(perfdata, " ")) {
+		// Each value is label=value[unit];warn;crit;min;max
+		value, _, _ := strings.Cut(label.value, ";")
+		match := perfValuePattern.FindStringSubmatch(value)
+		if match == nil {
+			// "U" marks a value the plugin could not determine
+			continue
+		}
+		v, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+		if series := name + "." + label.name; len(series) <= maxCustomNameLength {
+			metrics = append(metrics, models.CustomMetric{Name: series, Value: v, Unit: match[2]})
+		}
+	}
+	return metrics
+}
+
+// perfLabel is one label=value pair of performance data
+type perfLabel struct {
+	name  string
+	value string
+}
+
+// splitPerfdata splits performance data into its label=value pairs. Labels
+// with spaces are quoted, as in 'oldest job'=3.5s, and a quote inside them
+// is doubled.
+func splitPerfdata(perfdata string) []perfLabel {
+	var labels []perfLabel
+	s := strings.TrimSpace(perfdata)
+	for s != "" {
+		var name string
+		if strings.HasPrefix(s, "'") {
+			var b strings.Builder
+			i := 1
+			for i < len(s) {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			name = b.String()
+			s = s[min(i+1, len(s)):]
+			if !strings.HasPrefix(s, "=") {
+				// Unterminated or malformed; skip to the next pair
+				_, s, _ = strings.Cut(s, " ")
+				s = strings.TrimSpace(s)
+				continue
+			}
+			s = s[1:]
+		} else {
+			end := strings.IndexAny(s, " =")
+			if end < 0 || s[end] != '=' {
+				_, s, _ = strings.Cut(s, " ")
+				s = strings.TrimSpace(s)
+				continue
+			}
+			name, s = s[:end], s[end+1:]
+		}
+
+		value, rest, _ := strings.Cut(s, " ")
+		if name != "" {
+			labels = append(labels, perfLabel{name: name, value: value})
+		}
+		s = strings.TrimSpace(rest)
+	}
+	return labels
+}
+
+// parseInflux reads metrics written in Influx line protocol, such as
+//
+//	queue,name=emails depth=12i,oldest=3.5 1700000000000000000
+//
+// which reports the series queue.depth{name=emails} and
+// queue.oldest{name=emails}. String fields and timestamps are ignored.
+func parseInflux(output []byte) []models.CustomMetric {
+	var metrics []models.CustomMetric
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := splitEscaped(line, ' ')
+		if len(parts) < 2 {
+			continue
+		}
+
+		keys := splitEscaped(parts[0], ',')
+		measurement := unescapeInflux(keys[0])
+		var tags []string
+		for _, tag := range keys[1:] {
+			k, v, ok := cutEscaped(tag, '=')
+			if ok {
+				tags = append(tags, unescapeInflux(k)+"="+unescapeInflux(v))
+			}
+		}
+		sort.Strings(tags)
+		suffix := ""
+		if len(tags) > 0 {
+			suffix = "{" + strings.Join(tags, ",") + "}"
+		}
+
+		for _, field := range splitEscaped(parts[1], ',') {
+			k, v, ok := cutEscaped(field, '=')
+			if !ok {
+				continue
+			}
+			value, ok := influxValue(v)
+			if !ok {
+				continue
+			}
+			if series := measurement + "." + unescapeInflux(k) + suffix; len(series) <= maxCustomNameLength {
+				metrics = append(metrics, models.CustomMetric{Name: series, Value: value})
+			}
+		}
+	}
+	return metrics
+}
+
+// influxValue parses a field value: a float, an integer such as 12i or 12u,
+// or a boolean reported as 1 or 0. Strings are not numbers and are skipped.
+func influxValue(s string) (float64, bool) {
+	switch s {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, true
+	case "f", "F", "false", "False", "FALSE":
+		return 0, true
+	}
+	if strings.HasPrefix(s, `"`) {
+		return 0, false
+	}
+	s = strings.TrimRight(s, "iu")
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// splitEscaped splits a line protocol section on sep, leaving separators
+// that are escaped with a backslash or inside a quoted string alone
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			if i > start {
+				parts = append(parts, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// cutEscaped cuts s around the first unescaped sep
+func cutEscaped(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// unescapeInflux removes the backslashes escaping spaces, commas and equal
+// signs in measurements, tags and field keys
+func unescapeInflux(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// maxCmdlineLength caps the command line reported for a process
+const maxCmdlineLength = 1024
+
+// processCPU is the CPU time a process had used at the previous sample
+type processCPU struct {
+	created int64   // start time in milliseconds, to tell a reused pid apart
+	busy    float64 // user and system seconds
+}
+
+// readProcessCPU reads the CPU time a process has used so far
+func readProcessCPU(p *process.Process) (processCPU, error) {
+	times, err := p.Times()
+	if err != nil {
+		return processCPU{}, err
+	}
+	created, _ := p.CreateTime()
+	return processCPU{created: created, busy: times.User + times.System}, nil
+}
+
+// processPercent returns the share of one core a process used over the
+// elapsed seconds since prev was read, or over its lifetime if prev is
+// missing or belonged to an earlier process with the same pid
+func processPercent(cur processCPU, prev processCPU, hasPrev bool, elapsed float64, now time.Time) float64 {
+	var percent float64
+	if hasPrev && prev.created == cur.created && elapsed > 0 {
+		percent = (cur.busy - prev.busy) / elapsed * 100
+	} else if lifetime := now.Sub(time.UnixMilli(cur.created)).Seconds(); cur.created > 0 && lifetime > 0 {
+		percent = cur.busy / lifetime * 100
+	}
+	return math.Max(percent, 0)
+}
+
+// processDue reports whether the busiest processes should be sampled with
+// this report. Ticks may arrive a little early, so half a report interval
+// counts as on time.
+func (c *Collector) processDue(now time.Time) bool {
+	if c.config.Processes.TopN <= 0 {
+		return false
+	}
+	interval := (c.config.Processes.Interval - c.config.ReportInterval/2) * float64(time.Second)
+	return now.Sub(c.lastProcTime) >= time.Duration(interval)
+}
+
+// collectProcesses reports the TopN processes by CPU and the TopN by
+// resident memory, busiest first. CPU usage covers the time since the
+// previous call, or the lifetime of processes not seen then.
+func (c *Collector) collectProcesses() []models.ProcessMetrics {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil
+	}
+
+	type candidate struct {
+		proc *process.Process
+		cpu  float64
+		rss  uint64
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(c.lastProcTime).Seconds()
+	seen := make(map[int32]processCPU, len(procs))
+	candidates := make([]candidate, 0, len(procs))
+	for _, p := range procs {
+		cur, err := readProcessCPU(p)
+		if err != nil {
+			// Exited since it was listed, or not ours to read
+			continue
+		}
+		seen[p.Pid] = cur
+		prev, ok := c.lastProcs[p.Pid]
+		percent := processPercent(cur, prev, ok, elapsed, now)
+
+		var rss uint64
+		if memInfo, err := p.MemoryInfo(); err == nil {
+			rss = memInfo.RSS
+		}
+		candidates = append(candidates, candidate{proc: p, cpu: percent, rss: rss})
+	}
+	c.lastProcs = seen
+	c.lastProcTime = now
+
+	topN := c.config.Processes.TopN
+	selected := make(map[int32]bool)
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].rss > candidates[j].rss })
+	for i := 0; i < len(candidates) && i < topN; i++ {
+		selected[candidates[i].proc.Pid] = true
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].cpu > candidates[j].cpu })
+	for i := 0; i < len(candidates) && i < topN; i++ {
+		selected[candidates[i].proc.Pid] = true
+	}
+
+	var processes []models.ProcessMetrics
+	for _, cand := range candidates {
+		if selected[cand.proc.Pid] {
+			processes = append(processes, describeProcess(cand.proc, cand.cpu, cand.rss))
+		}
+	}
+	return processes
+}
+
+// describeProcess looks up the details reported for a selected process.
+// Details that cannot be read, such as another user's open files, are left
+// empty.
+func describeProcess(p *process.Process, cpuPercent float64, rss uint64) models.ProcessMetrics {
+	pm := models.ProcessMetrics{
+		PID:        p.Pid,
+		CPUPercent: math.Round(cpuPercent*10) / 10,
+		RSS:        rss,
+	}
+	pm.Name, _ = p.Name()
+	pm.User, _ = p.Username()
+	if cmdline, err := p.Cmdline(); err == nil {
+		if len(cmdline) > maxCmdlineLength {
+			cmdline = strings.ToValidUTF8(cmdline[:maxCmdlineLength], "")
+		}
+		pm.Cmdline = cmdline
+	}
+	pm.FDs, _ = p.NumFDs()
+	pm.Threads, _ = p.NumThreads()
+	return pm
+}
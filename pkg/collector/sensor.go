@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// hwmonReader reads temperature and fan sensors from the hwmon class in
+// sysfs. The root is a parameter so a fake tree can stand in for /sys.
+type hwmonReader struct {
+	root string
+}
+
+// sensors reads every temperature and fan input of every hwmon chip. Names
+// are the chip name and the input's label, such as "coretemp/Package id 0";
+// chips sharing a name, such as several nvme drives, get their hwmon
+// directory appended.
+func (r hwmonReader) sensors() []models.SensorMetrics {
+	dirs, _ := filepath.Glob(filepath.Join(r.root, "class", "hwmon", "hwmon*"))
+	sort.Slice(dirs, func(i, j int) bool { return hwmonIndex(dirs[i]) < hwmonIndex(dirs[j]) })
+
+	var sensors []models.SensorMetrics
+	chips := make(map[string]int)
+	for _, dir := range dirs {
+		chip := readString(filepath.Join(dir, "name"))
+		if chip == "" {
+			chip = filepath.Base(dir)
+		}
+		chips[chip]++
+		if chips[chip] > 1 {
+			chip += "-" + filepath.Base(dir)
+		}
+
+		inputs, _ := filepath.Glob(filepath.Join(dir, "*_input"))
+		sort.Strings(inputs)
+		for _, input := range inputs {
+			prefix := strings.TrimSuffix(filepath.Base(input), "_input")
+			var kind string
+			var scale float64
+			switch {
+			case strings.HasPrefix(prefix, "temp"):
+				kind, scale = "temperature", 1000 // millidegrees Celsius
+			case strings.HasPrefix(prefix, "fan"):
+				kind, scale = "fan", 1 // RPM
+			default:
+				continue
+			}
+
+			value, ok := readFloat(input)
+			if !ok {
+				// Reading a sensor that is not connected fails
+				continue
+			}
+			label := readString(filepath.Join(dir, prefix+"_label"))
+			if label == "" {
+				label = prefix
+			}
+			s := models.SensorMetrics{
+				Name:  chip + "/" + label,
+				Kind:  kind,
+				Value: math.Round(value/scale*10) / 10,
+			}
+			if kind == "temperature" {
+				if high, ok := readFloat(filepath.Join(dir, prefix+"_max")); ok {
+					s.High = high / scale
+				}
+				if crit, ok := readFloat(filepath.Join(dir, prefix+"_crit")); ok {
+					s.Critical = crit / scale
+				}
+			}
+			sensors = append(sensors, s)
+		}
+	}
+	return sensors
+}
+
+// collectSensors reports hardware sensors, from hwmon where sysfs has it and
+// from the platform's temperature sensors elsewhere
+func (c *Collector) collectSensors(metrics *models.Metrics) {
+	sensors := c.hwmonReader.sensors()
+	if len(sensors) == 0 {
+		temps, _ := host.SensorsTemperatures()
+		for _, t := range temps {
+			sensors = append(sensors, models.SensorMetrics{
+				Name:     t.SensorKey,
+				Kind:     "temperature",
+				Value:    math.Round(t.Temperature*10) / 10,
+				High:     t.High,
+				Critical: t.Critical,
+			})
+		}
+	}
+
+	for _, s := range sensors {
+		if s.Kind == "temperature" {
+			metrics.TemperatureMax = math.Max(metrics.TemperatureMax, s.Value)
+		}
+	}
+	metrics.Sensors = sensors
+}
+
+// hwmonIndex orders hwmon directories numerically, so hwmon10 follows hwmon9
+func hwmonIndex(dir string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "hwmon"))
+	return n
+}
+
+// readString reads a sysfs attribute holding a single line of text
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readFloat reads a sysfs attribute holding a single number
+func readFloat(path string) (float64, bool) {
+	v, err := strconv.ParseFloat(readString(path), 64)
+	return v, err == nil
+}
@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// newHwmonFixture builds a /sys with a CPU, two nvme drives and a fan
+// controller under class/hwmon
+func newHwmonFixture(t *testing.T) string {
+	root := t.TempDir()
+	hwmon := filepath.Join(root, "class", "hwmon")
+	files := map[string]string{
+		"hwmon0/name":         "coretemp\n",
+		"hwmon0/temp1_input":  "45000\n",
+		"hwmon0/temp1_label":  "Package id 0\n",
+		"hwmon0/temp1_max":    "80000\n",
+		"hwmon0/temp1_crit":   "100000\n",
+		"hwmon0/temp2_input":  "43500\n",
+		"hwmon0/temp2_label":  "Core 0\n",
+		"hwmon1/name":         "nvme\n",
+		"hwmon1/temp1_input":  "38850\n",
+		"hwmon9/name":         "nct6775\n",
+		"hwmon9/fan1_input":   "1200\n",
+		"hwmon9/fan1_label":   "CPU fan\n",
+		"hwmon9/fan2_input":   "0\n",
+		"hwmon9/in0_input":    "1032\n",
+		"hwmon10/name":        "nvme\n",
+		"hwmon10/temp1_input": "51000\n",
+		"hwmon10/temp1_label": "Composite\n",
+		"hwmon11/temp1_input": "30000\n",
+	}
+	for name, content := range files {
+		writeFile(t, filepath.Join(hwmon, name), content)
+	}
+	// A sensor that is not connected fails to read
+	if err := os.MkdirAll(filepath.Join(hwmon, "hwmon9", "fan3_input"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestHwmonSensors(t *testing.T) {
+	got := hwmonReader{root: newHwmonFixture(t)}.sensors()
+	want := []models.SensorMetrics{
+		{Name: "coretemp/Package id 0", Kind: "temperature", Value: 45, High: 80, Critical: 100},
+		{Name: "coretemp/Core 0", Kind: "temperature", Value: 43.5},
+		{Name: "nvme/temp1", Kind: "temperature", Value: 38.9},
+		{Name: "nct6775/CPU fan", Kind: "fan", Value: 1200},
+		{Name: "nct6775/fan2", Kind: "fan", Value: 0},
+		{Name: "nvme-hwmon10/Composite", Kind: "temperature", Value: 51},
+		{Name: "hwmon11/temp1", Kind: "temperature", Value: 30},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sensors =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestHwmonSensorsWithoutHwmon(t *testing.T) {
+	if got := (hwmonReader{root: t.TempDir()}).sensors(); len(got) != 0 {
+		t.Errorf("got %+v without a hwmon class, want none", got)
+	}
+}
+
+func TestCollectSensorsTemperatureMax(t *testing.T) {
+	c := &Collector{hwmonReader: hwmonReader{root: newHwmonFixture(t)}}
+	metrics := &models.Metrics{}
+	c.collectSensors(metrics)
+
+	if len(metrics.Sensors) != 7 {
+		t.Errorf("got %d sensors, want 7", len(metrics.Sensors))
+	}
+	// Fans are not temperatures, so 1200 RPM is not the hottest sensor
+	if metrics.TemperatureMax != 51 {
+		t.Errorf("TemperatureMax = %v, want 51", metrics.TemperatureMax)
+	}
+}
+
+func TestHwmonIndex(t *testing.T) {
+	for dir, want := range map[string]int{
+		"/sys/class/hwmon/hwmon0":  0,
+		"/sys/class/hwmon/hwmon9":  9,
+		"/sys/class/hwmon/hwmon10": 10,
+	} {
+		if got := hwmonIndex(dir); got != want {
+			t.Errorf("hwmonIndex(%q) = %d, want %d", dir, got, want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// systemctlTimeout bounds how long checking a systemd unit may take
+const systemctlTimeout = 2 * time.Second
+
+// cgroupRoot is where the cgroup hierarchy is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// watch is a configured watch with its command line pattern compiled
+type watch struct {
+	config  models.WatchConfig
+	pattern *regexp.Regexp
+	err     error // configuration problem, reported instead of checking
+}
+
+// watchState is what is remembered about a watch between samples
+type watchState struct {
+	main     processIdentity // its oldest process, zero while down
+	everUp   bool
+	restarts int
+}
+
+// processIdentity tells a process apart from a later one reusing its pid
+type processIdentity struct {
+	pid     int32
+	created int64 // milliseconds
+}
+
+// older reports whether a process started before another, taking the lower
+// pid when start times are only known to the second
+func (p processIdentity) older(than processIdentity) bool {
+	if p.created != than.created {
+		return p.created < than.created
+	}
+	return p.pid < than.pid
+}
+
+// newWatches compiles the configured watches. Invalid watches are kept, so
+// they are reported as down rather than silently dropped.
+func newWatches(configs []models.WatchConfig) []watch {
+	watches := make([]watch, 0, len(configs))
+	for _, wc := range configs {
+		w := watch{config: wc}
+		selectors := 0
+		for _, s := range []string{wc.Process, wc.Cmdline, wc.Pidfile, wc.Unit} {
+			if s != "" {
+				selectors++
+			}
+		}
+		switch {
+		case selectors != 1:
+			w.err = errors.New("exactly one of process, cmdline, pidfile and unit must be set")
+		case wc.Cmdline != "":
+			pattern, err := regexp.Compile(wc.Cmdline)
+			if err != nil {
+				w.err = fmt.Errorf("invalid cmdline pattern: %w", err)
+			}
+			w.pattern = pattern
+		}
+		if w.config.Name == "" {
+			w.config.Name = wc.Process + wc.Cmdline + wc.Pidfile + wc.Unit
+		}
+		watches = append(watches, w)
+	}
+	return watches
+}
+
+// collectWatches reports whether each watched process or unit is running,
+// how often it restarted and the resources its processes use
+func (c *Collector) collectWatches(metrics *models.Metrics) {
+	if len(c.watches) == 0 {
+		return
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(c.lastWatchTime).Seconds()
+	seen := make(map[int32]processCPU)
+
+	// Name and command line watches share a single process listing
+	var procs []*process.Process
+	var listErr error
+	listed := false
+
+	for _, w := range c.watches {
+		wm := models.WatchMetrics{Name: w.config.Name}
+		var pids []int32
+		unitActive, unitRestarts := false, -1
+		var err error
+
+		switch {
+		case w.err != nil:
+			err = w.err
+		case w.config.Unit != "":
+			pids, unitActive, unitRestarts, err = unitProcesses(w.config.Unit)
+		case w.config.Pidfile != "":
+			pids, err = pidfileProcesses(w.config.Pidfile)
+		default:
+			if !listed {
+				procs, listErr = process.Processes()
+				listed = true
+			}
+			pids, err = matchProcesses(procs, listErr, w)
+		}
+		if err != nil {
+			wm.Error = err.Error()
+		}
+
+		var main processIdentity
+		for _, pid := range pids {
+			p, err := process.NewProcess(pid)
+			if err != nil {
+				continue
+			}
+			// A zombie has exited and only waits for its parent to reap it
+			if status, err := p.Status(); err == nil && len(status) > 0 && status[0] == process.Zombie {
+				continue
+			}
+			cur, err := readProcessCPU(p)
+			if err != nil {
+				continue
+			}
+			wm.Processes++
+			if id := (processIdentity{pid: pid, created: cur.created}); main.pid == 0 || id.older(main) {
+				main = id
+			}
+			seen[pid] = cur
+			prev, ok := c.lastWatchCPU[pid]
+			wm.CPUPercent += processPercent(cur, prev, ok, elapsed, now)
+			if memInfo, err := p.MemoryInfo(); err == nil {
+				wm.RSS += memInfo.RSS
+			}
+		}
+		wm.CPUPercent = math.Round(wm.CPUPercent*10) / 10
+
+		state, known := c.lastWatches[wm.Name]
+		if w.config.Unit != "" {
+			// systemd counts restarts itself, including ones between samples
+			wm.Up = unitActive
+			if unitRestarts >= 0 {
+				if known && unitRestarts > state.restarts {
+					wm.Restarts = unitRestarts - state.restarts
+				}
+				state.restarts = unitRestarts
+			}
+		} else {
+			wm.Up = wm.Processes > 0
+			// A new oldest process after the watch had been seen running
+			// means it was restarted
+			if wm.Up && state.everUp && main != state.main {
+				wm.Restarts = 1
+				state.restarts++
+			}
+		}
+		if !wm.Up {
+			main = processIdentity{}
+		}
+		state.main = main
+		state.everUp = state.everUp || wm.Up
+		c.lastWatches[wm.Name] = state
+		wm.TotalRestarts = state.restarts
+
+		if !wm.Up {
+			metrics.WatchesDown++
+		}
+		metrics.WatchRestarts += wm.Restarts
+		metrics.Watches = append(metrics.Watches, wm)
+	}
+
+	c.lastWatchCPU = seen
+	c.lastWatchTime = now
+}
+
+// matchProcesses returns the processes a name or command line watch selects,
+// leaving out the agent itself
+func matchProcesses(procs []*process.Process, listErr error, w watch) ([]int32, error) {
+	if listErr != nil {
+		return nil, listErr
+	}
+	self := int32(os.Getpid())
+	var pids []int32
+	for _, p := range procs {
+		if p.Pid == self {
+			continue
+		}
+		if w.pattern != nil {
+			if cmdline, err := p.Cmdline(); err == nil && cmdline != "" && w.pattern.MatchString(cmdline) {
+				pids = append(pids, p.Pid)
+			}
+		} else if name, err := p.Name(); err == nil && name == w.config.Process {
+			pids = append(pids, p.Pid)
+		}
+	}
+	return pids, nil
+}
+
+// pidfileProcesses returns the process whose pid a pidfile holds, if it is
+// still running
+func pidfileProcesses(path string) ([]int32, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Daemons remove their pidfile when they stop
+			return nil, nil
+		}
+		return nil, err
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil || pid <= 0 {
+		return nil, fmt.Errorf("invalid pidfile %s", path)
+	}
+	if exists, err := process.PidExists(int32(pid)); err != nil || !exists {
+		return nil, err
+	}
+	return []int32{int32(pid)}, nil
+}
+
+// unitProcesses asks systemd whether a unit is active and how often it
+// restarted, and lists the processes in its cgroup. restarts is -1 on
+// systemd versions that do not count restarts.
+func unitProcesses(unit string) (pids []int32, active bool, restarts int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), systemctlTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "systemctl", "show",
+		"--property=ActiveState,NRestarts,ControlGroup", "--", unit).Output()
+	if err != nil {
+		return nil, false, -1, fmt.Errorf("systemctl: %w", err)
+	}
+
+	restarts = -1
+	var cgroup string
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "ActiveState":
+			active = value == "active"
+		case "NRestarts":
+			if n, err := strconv.Atoi(value); err == nil {
+				restarts = n
+			}
+		case "ControlGroup":
+			cgroup = value
+		}
+	}
+	if cgroup != "" {
+		pids = cgroupProcesses(cgroup)
+	}
+	return pids, active, restarts, nil
+}
+
+// cgroupProcesses lists the processes in a cgroup and its children, looking
+// in the unified hierarchy first and then in systemd's v1 hierarchy
+func cgroupProcesses(cgroup string) []int32 {
+	var pids []int32
+	for _, root := range []string{cgroupRoot, filepath.Join(cgroupRoot, "systemd")} {
+		dir := filepath.Join(root, cgroup)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || d.Name() != "cgroup.procs" {
+				return nil
+			}
+			pids = append(pids, readPids(path)...)
+			return nil
+		})
+		break
+	}
+	return pids
+}
+
+// readPids reads a file with one pid per line
+func readPids(path string) []int32 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var pids []int32
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.ParseInt(field, 10, 32); err == nil {
+			pids = append(pids, int32(pid))
+		}
+	}
+	return pids
+}
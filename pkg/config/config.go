@@ -30,6 +30,10 @@ func LoadServerConfig(path string) (*models.Config, error) {
 	return &config, nil
 }
 
+// minReportInterval is the shortest report interval an agent accepts, in
+// seconds. CPU time counters only advance every 10ms on most systems.
+const minReportInterval = 0.1
+
 // LoadAgentConfig loads agent configuration from file
 func LoadAgentConfig(path string) (*models.AgentConfig, error) {
 	data, err := os.ReadFile(path)
@@ -46,6 +50,15 @@ func LoadAgentConfig(path string) (*models.AgentConfig, error) {
 	if config.ReportInterval == 0 {
 		config.ReportInterval = 5 // 5 seconds
 	}
+	if config.ReportInterval < minReportInterval {
+		config.ReportInterval = minReportInterval
+	}
+	if config.Processes.TopN == 0 {
+		config.Processes.TopN = 5
+	}
+	if config.Processes.Interval <= 0 {
+		config.Processes.Interval = 60 // 1 minute
+	}
 
 	return &config, nil
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// EscalationPolicy notifies more people the longer an alert stays
+// unacknowledged
+type EscalationPolicy struct {
+	ID          int              `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Steps       []EscalationStep `json:"steps"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// EscalationStep is notified once an alert has been unacknowledged for Delay
+// seconds. Steps are notified in order.
+type EscalationStep struct {
+	Delay       int      `json:"delay"`        // seconds after the alert fired
+	Channels    []string `json:"channels"`     // notification channel names
+	ScheduleIDs []int    `json:"schedule_ids"` // on-call schedules whose current person is notified
+}
+
+// OnCallSchedule says who is on call at any time. The latest rotation that
+// has started applies, and overrides take precedence over rotations.
+type OnCallSchedule struct {
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	Channel   string           `json:"channel"` // channel used to reach the person on call
+	Rotations []OnCallRotation `json:"rotations"`
+	Overrides []OnCallOverride `json:"overrides"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// OnCallRotation hands over to the next participant every ShiftLength seconds
+// from Start
+type OnCallRotation struct {
+	Name         string    `json:"name"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`          // optional
+	ShiftLength  int       `json:"shift_length"` // seconds
+	Participants []string  `json:"participants"` // recipients on the schedule's channel
+}
+
+// OnCallOverride puts someone else on call for a period
+type OnCallOverride struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Participant string    `json:"participant"`
+}
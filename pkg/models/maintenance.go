@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// MaintenanceWindow silences notifications while planned work is under way.
+// A window is either one-off (StartsAt to EndsAt) or recurring, opening at
+// every match of Schedule and staying open for Duration seconds. Recurring
+// windows may still use StartsAt/EndsAt to limit the period they apply to.
+type MaintenanceWindow struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	Schedule    string    `json:"schedule"` // cron expression: minute hour day-of-month month day-of-week
+	Duration    int       `json:"duration"` // seconds; recurring windows only
+	Timezone    string    `json:"timezone"` // IANA name the schedule is evaluated in, default UTC
+	AgentIDs    []string  `json:"agent_ids"`
+	Tags        []string  `json:"tags"`
+	RuleIDs     []int     `json:"rule_ids"`
+	Enabled     bool      `json:"enabled"`
+	Active      bool      `json:"active"` // computed when the window is returned by the API
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -12,11 +12,181 @@ type Metrics struct {
 	MemoryTotal uint64    `json:"memory_total"`
 	DiskUsed    uint64    `json:"disk_used"`
 	DiskTotal   uint64    `json:"disk_total"`
-	NetworkRx   uint64    `json:"network_rx"`
+	NetworkRx   uint64    `json:"network_rx"` // bytes per second over the reported interfaces
 	NetworkTx   uint64    `json:"network_tx"`
 	LoadAvg1    float64   `json:"load_avg_1"`
 	LoadAvg5    float64   `json:"load_avg_5"`
 	LoadAvg15   float64   `json:"load_avg_15"`
+	Tags        []string  `json:"tags,omitempty"` // agent tags, stored on the agent rather than per sample
+
+	// Shares of CPU time since the previous sample, in percent. Irq
+	// includes soft interrupts; steal is time taken by the hypervisor.
+	CPUUser    float64   `json:"cpu_user"`
+	CPUSystem  float64   `json:"cpu_system"`
+	CPUIowait  float64   `json:"cpu_iowait"`
+	CPUSteal   float64   `json:"cpu_steal"`
+	CPUIrq     float64   `json:"cpu_irq"`
+	CPUPerCore []float64 `json:"cpu_per_core,omitempty"` // utilisation of each core in percent
+
+	// Disk I/O summed over all devices, except utilisation which is the
+	// busiest device's and await which is averaged over all operations
+	DiskReadBps     float64 `json:"disk_read_bps"`
+	DiskWriteBps    float64 `json:"disk_write_bps"`
+	DiskReadIOPS    float64 `json:"disk_read_iops"`
+	DiskWriteIOPS   float64 `json:"disk_write_iops"`
+	DiskUtilPercent float64 `json:"disk_util_percent"`
+	DiskAwaitMs     float64 `json:"disk_await_ms"`
+
+	// Memory the kernel can hand out without swapping, page cache and
+	// buffers, in bytes. MemoryUsed excludes cache and buffers.
+	MemoryAvailable uint64 `json:"memory_available"`
+	MemoryCached    uint64 `json:"memory_cached"`
+	MemoryBuffers   uint64 `json:"memory_buffers"`
+	SwapUsed        uint64 `json:"swap_used"`
+	SwapTotal       uint64 `json:"swap_total"`
+
+	// Share of the last 10 seconds in which some or all tasks were stalled
+	// waiting for memory, in percent (Linux pressure stall information)
+	MemoryPressureSome float64 `json:"memory_pressure_some"`
+	MemoryPressureFull float64 `json:"memory_pressure_full"`
+
+	// Network packets, errors and drops per second over the reported
+	// interfaces, counting both directions for errors and drops
+	NetworkRxPackets float64 `json:"network_rx_packets"`
+	NetworkTxPackets float64 `json:"network_tx_packets"`
+	NetworkErrors    float64 `json:"network_errors"`
+	NetworkDrops     float64 `json:"network_drops"`
+
+	Filesystems []FilesystemMetrics `json:"filesystems,omitempty"`
+	Disks       []DiskIOMetrics     `json:"disks,omitempty"`
+	Interfaces  []InterfaceMetrics  `json:"interfaces,omitempty"`
+
+	// Watched processes and units that are not running, and how often
+	// they restarted since the previous sample
+	WatchesDown   int            `json:"watches_down"`
+	WatchRestarts int            `json:"watch_restarts"`
+	Watches       []WatchMetrics `json:"watches,omitempty"`
+
+	// Hardware sensors, and the hottest temperature among them in degrees
+	// Celsius
+	TemperatureMax float64         `json:"temperature_max"`
+	Sensors        []SensorMetrics `json:"sensors,omitempty"`
+
+	// Containers found in the cgroup v2 hierarchy, and the containers that
+	// started or stopped since the previous sample
+	Containers      []ContainerMetrics `json:"containers,omitempty"`
+	ContainerEvents []ContainerEvent   `json:"container_events,omitempty"`
+
+	// Custom holds the latest values printed by the agent's plugins
+	Custom []CustomMetric `json:"custom,omitempty"`
+
+	// Processes are the busiest processes, only sent every
+	// ProcessConfig.Interval rather than with every sample
+	Processes []ProcessMetrics `json:"processes,omitempty"`
+
+	// Inventory describes the host, only sent with the agent's first report
+	// and when it changes
+	Inventory *HostInventory `json:"inventory,omitempty"`
+}
+
+// FilesystemMetrics is the usage of one mounted filesystem
+type FilesystemMetrics struct {
+	Mountpoint  string `json:"mountpoint"`
+	Device      string `json:"device"`
+	FSType      string `json:"fstype"`
+	Used        uint64 `json:"used"`
+	Total       uint64 `json:"total"`
+	InodesUsed  uint64 `json:"inodes_used"`
+	InodesTotal uint64 `json:"inodes_total"`
+}
+
+// DiskIOMetrics is the I/O of one block device since the previous sample
+type DiskIOMetrics struct {
+	Device      string  `json:"device"`
+	ReadBps     float64 `json:"read_bps"`
+	WriteBps    float64 `json:"write_bps"`
+	ReadIOPS    float64 `json:"read_iops"`
+	WriteIOPS   float64 `json:"write_iops"`
+	UtilPercent float64 `json:"util_percent"` // share of the time the device was busy
+	AwaitMs     float64 `json:"await_ms"`     // average time an operation took, including queueing
+}
+
+// InterfaceMetrics is the traffic of one network interface per second since
+// the previous sample
+type InterfaceMetrics struct {
+	Name      string  `json:"name"`
+	RxBytes   float64 `json:"rx_bytes"`
+	TxBytes   float64 `json:"tx_bytes"`
+	RxPackets float64 `json:"rx_packets"`
+	TxPackets float64 `json:"tx_packets"`
+	RxErrors  float64 `json:"rx_errors"`
+	TxErrors  float64 `json:"tx_errors"`
+	RxDrops   float64 `json:"rx_drops"`
+	TxDrops   float64 `json:"tx_drops"`
+}
+
+// ProcessMetrics is one of the processes using the most CPU or memory
+type ProcessMetrics struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	User       string  `json:"user"`
+	Cmdline    string  `json:"cmdline"`
+	CPUPercent float64 `json:"cpu_percent"` // of one core since the previous sample, so may exceed 100
+	RSS        uint64  `json:"rss"`         // resident memory in bytes
+	FDs        int32   `json:"fds"`         // open file descriptors, 0 if they cannot be read
+	Threads    int32   `json:"threads"`
+}
+
+// WatchMetrics is the state of a process or systemd unit an agent watches
+type WatchMetrics struct {
+	Name          string  `json:"name"`
+	Up            bool    `json:"up"`
+	Processes     int     `json:"processes"`      // matching processes, or processes in the unit
+	Restarts      int     `json:"restarts"`       // since the previous sample
+	TotalRestarts int     `json:"total_restarts"` // counted by systemd for units, since the agent started for processes
+	CPUPercent    float64 `json:"cpu_percent"`    // of one core, summed over the processes
+	RSS           uint64  `json:"rss"`
+	Error         string  `json:"error,omitempty"` // why the watch could not be checked
+}
+
+// SensorMetrics is the reading of one hardware sensor
+type SensorMetrics struct {
+	Name     string  `json:"name"` // chip and label, such as coretemp/Package id 0
+	Kind     string  `json:"kind"` // temperature (degrees Celsius) or fan (RPM)
+	Value    float64 `json:"value"`
+	High     float64 `json:"high,omitempty"`     // temperature the chip considers high
+	Critical float64 `json:"critical,omitempty"` // temperature the chip considers critical
+}
+
+// CustomMetric is one value of a series reported by a plugin
+type CustomMetric struct {
+	Name  string  `json:"name"` // such as queue.depth
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// ContainerMetrics is the resource usage of one container since the previous
+// sample, read from its cgroup
+type ContainerMetrics struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"` // from Docker, or the short id
+	Image        string            `json:"image,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	CPUPercent   float64           `json:"cpu_percent"` // of one core, so may exceed 100
+	MemoryUsed   uint64            `json:"memory_used"`
+	MemoryLimit  uint64            `json:"memory_limit"` // 0 when unlimited
+	NetworkRx    float64           `json:"network_rx"`   // bytes per second
+	NetworkTx    float64           `json:"network_tx"`
+	DiskReadBps  float64           `json:"disk_read_bps"`
+	DiskWriteBps float64           `json:"disk_write_bps"`
+}
+
+// ContainerEvent records a container starting or stopping
+type ContainerEvent struct {
+	ContainerID string    `json:"container_id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"` // start, stop
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 // Agent represents a monitored agent
@@ -28,6 +198,28 @@ type Agent struct {
 	Status   string    `json:"status"` // online, offline
 	Platform string    `json:"platform"`
 	Version  string    `json:"version"`
+	Tags     []string  `json:"tags"`
+
+	Inventory *HostInventory `json:"inventory,omitempty"` // only filled in for a single agent
+}
+
+// HostInventory describes the host an agent runs on
+type HostInventory struct {
+	Hostname        string    `json:"hostname"`
+	OS              string    `json:"os"`               // linux, windows, darwin
+	Platform        string    `json:"platform"`         // distribution, such as ubuntu
+	PlatformVersion string    `json:"platform_version"` // such as 22.04
+	KernelVersion   string    `json:"kernel_version"`
+	Arch            string    `json:"arch"`
+	CPUModel        string    `json:"cpu_model"`
+	CPUCount        int       `json:"cpu_count"` // logical CPUs
+	MemoryTotal     uint64    `json:"memory_total"`
+	IPAddresses     []string  `json:"ip_addresses"`   // with prefix length, such as 10.0.0.5/24
+	Virtualization  string    `json:"virtualization"` // such as kvm or docker, empty on bare metal
+	BootTime        time.Time `json:"boot_time"`
+	Uptime          uint64    `json:"uptime"` // seconds
+	AgentVersion    string    `json:"agent_version"`
+	UpdatedAt       time.Time `json:"updated_at"` // when the server last received it
 }
 
 // AlertRule represents an alert rule
@@ -40,17 +232,83 @@ type AlertRule struct {
 	Duration    int     `json:"duration"` // seconds
 	Enabled     bool    `json:"enabled"`
 	Description string  `json:"description"`
+	Expression  string  `json:"expression"`   // replaces metric_type/operator/threshold when set
+	Window      int     `json:"window"`       // seconds; evaluate over a sliding window instead of per sample
+	Aggregate   string  `json:"aggregate"`    // avg, min, max, p95 (window rules)
+	BreachRatio float64 `json:"breach_ratio"` // share of window samples that must breach (0-1)
+
+	EscalationPolicyID int `json:"escalation_policy_id"` // notify through a policy instead of every channel
+
+	Severity string           `json:"severity"` // info, warning, critical
+	Levels   []ThresholdLevel `json:"levels"`   // optional thresholds per severity, replacing threshold
+
+	// Type is "threshold" (default), "anomaly" or "forecast". Anomaly rules
+	// compare how many standard deviations a sample is from the agent's
+	// learned baseline against Threshold; forecast rules compare the hours
+	// until disk or memory is full.
+	Type        string `json:"type"`
+	Direction   string `json:"direction"`   // anomaly: above (default), below, both
+	Seasonality string `json:"seasonality"` // anomaly: none, hour_of_day, hour_of_week (default)
+
+	// Target limits the rule to one mountpoint, block device, network
+	// interface, watch or sensor. Disk rules without one use the root
+	// filesystem; temperature rules the hottest sensor; fan rules the first
+	// fan; other rules use the agent's totals.
+	Target string `json:"target"`
+}
+
+// ThresholdLevel is the threshold at which a multi-threshold rule reaches a severity
+type ThresholdLevel struct {
+	Severity  string  `json:"severity"`
+	Threshold float64 `json:"threshold"`
 }
 
 // Alert represents a triggered alert
 type Alert struct {
-	ID        int       `json:"id"`
-	RuleID    int       `json:"rule_id"`
-	AgentID   string    `json:"agent_id"`
+	ID         int       `json:"id"`
+	RuleID     int       `json:"rule_id"`
+	AgentID    string    `json:"agent_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Message    string    `json:"message"`
+	Value      float64   `json:"value"`
+	Resolved   bool      `json:"resolved"`
+	Suppressed bool      `json:"suppressed"` // raised during a maintenance window, not notified
+	Severity   string    `json:"severity"`
+
+	AcknowledgedAt time.Time `json:"acknowledged_at"` // zero until acknowledged
+	AcknowledgedBy string    `json:"acknowledged_by"`
+	EscalationStep int       `json:"escalation_step"` // escalation steps notified so far
+	ResolvedAt     time.Time `json:"resolved_at"`     // zero until resolved
+}
+
+// Forecast predicts when a resource of an agent fills up, from a straight
+// line fitted to its recent usage
+type Forecast struct {
+	AgentID        string     `json:"agent_id"`
+	Resource       string     `json:"resource"`   // disk or memory
+	Filesystem     string     `json:"filesystem"` // mountpoint of disk forecasts
+	Used           uint64     `json:"used"`
+	Total          uint64     `json:"total"`
+	GrowthPerHour  float64    `json:"growth_per_hour"`  // bytes per hour
+	HoursUntilFull *float64   `json:"hours_until_full"` // null while usage is not growing
+	FullAt         *time.Time `json:"full_at"`
+	Samples        int        `json:"samples"` // samples the trend was fitted to
+}
+
+// AlertEvent is one entry in an alert's timeline
+type AlertEvent struct {
+	Type      string    `json:"type"` // triggered, queued, notified, notification_failed, acknowledged, resolved
 	Timestamp time.Time `json:"timestamp"`
-	Message   string    `json:"message"`
-	Value     float64   `json:"value"`
-	Resolved  bool      `json:"resolved"`
+	Channel   string    `json:"channel,omitempty"`
+	Recipient string    `json:"recipient,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// AlertDetail is an alert together with its rule and timeline
+type AlertDetail struct {
+	*Alert
+	Rule     *AlertRule   `json:"rule"` // nil if the rule was deleted
+	Timeline []AlertEvent `json:"timeline"`
 }
 
 // DatabaseConfig represents database configuration
@@ -79,14 +337,105 @@ type Config struct {
 	SMTPPassword  string         `json:"smtp_password"`
 	EmailFrom     string         `json:"email_from"`
 	AlertEmail    string         `json:"alert_email"`
-	Installed     bool           `json:"installed"` // whether database is installed
+	DashboardURL  string         `json:"dashboard_url"` // public URL used for links in notifications
+	Installed     bool           `json:"installed"`     // whether database is installed
+
+	NotificationChannels []NotificationChannelConfig `json:"notification_channels"`
 }
 
 // AgentConfig represents agent configuration
 type AgentConfig struct {
-	ServerURL      string `json:"server_url"`
-	AgentID        string `json:"agent_id"`
-	AgentName      string `json:"agent_name"`
-	ReportInterval int    `json:"report_interval"` // seconds
-	TLSSkipVerify  bool   `json:"tls_skip_verify"`
+	ServerURL      string   `json:"server_url"`
+	AgentID        string   `json:"agent_id"`
+	AgentName      string   `json:"agent_name"`
+	ReportInterval float64  `json:"report_interval"` // seconds, may be fractional
+	TLSSkipVerify  bool     `json:"tls_skip_verify"`
+	Tags           []string `json:"tags"` // used to scope maintenance windows
+
+	Filesystems FilesystemFilter `json:"filesystems"`
+	Interfaces  InterfaceFilter  `json:"interfaces"`
+	Processes   ProcessConfig    `json:"processes"`
+	Watches     []WatchConfig    `json:"watches"`
+	Containers  ContainerConfig  `json:"containers"`
+	Plugins     []PluginConfig   `json:"plugins"`
+}
+
+// FilesystemFilter selects the filesystems an agent reports. Patterns are
+// globs such as "/var/lib/*". Empty include lists include everything; a nil
+// ExcludeFSTypes skips pseudo filesystems such as tmpfs and overlay, and
+// network and FUSE mounts such as nfs and fuse.sshfs.
+type FilesystemFilter struct {
+	IncludeMountpoints []string `json:"include_mountpoints"`
+	ExcludeMountpoints []string `json:"exclude_mountpoints"`
+	IncludeFSTypes     []string `json:"include_fstypes"`
+	ExcludeFSTypes     []string `json:"exclude_fstypes"`
+}
+
+// InterfaceFilter selects the network interfaces an agent reports. Patterns
+// are globs such as "eth*". An empty include list includes everything; a nil
+// exclude list skips loopback, container and bridge interfaces.
+type InterfaceFilter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+// ProcessConfig controls the reporting of the busiest processes. Each sample
+// holds the TopN processes by CPU and the TopN by memory; a negative TopN
+// turns process reporting off.
+type ProcessConfig struct {
+	TopN     int     `json:"top_n"`
+	Interval float64 `json:"interval"` // seconds between samples
+}
+
+// WatchConfig declares a process or systemd unit that must be running. One
+// of Process, Cmdline, Pidfile and Unit selects what is watched.
+type WatchConfig struct {
+	Name    string `json:"name"`    // reported name, which rules target
+	Process string `json:"process"` // process name such as nginx
+	Cmdline string `json:"cmdline"` // regular expression matched against command lines
+	Pidfile string `json:"pidfile"`
+	Unit    string `json:"unit"` // systemd unit such as nginx.service
+}
+
+// ContainerConfig controls the reporting of containers
+type ContainerConfig struct {
+	Disabled     bool   `json:"disabled"`
+	DockerSocket string `json:"docker_socket"` // for names and labels, default /var/run/docker.sock
+}
+
+// PluginConfig is a command the agent runs at its own interval, reporting
+// the metrics it prints as custom series. Nagios plugins report a
+// "<name>.<label>" series for each value of their performance data and
+// "<name>.status" from their exit code; Influx line protocol reports a
+// "<measurement>.<field>" series for each field, with any tags in braces.
+type PluginConfig struct {
+	Name     string   `json:"name"`     // defaults to the program's file name
+	Command  []string `json:"command"`  // program and arguments, run without a shell
+	Format   string   `json:"format"`   // nagios (default) or influx
+	Interval float64  `json:"interval"` // seconds between runs, defaults to 60
+	Timeout  float64  `json:"timeout"`  // seconds, defaults to 10
+}
+
+// BacktestResult lists the alerts a rule would have raised over a past period
+type BacktestResult struct {
+	From          time.Time        `json:"from"`
+	To            time.Time        `json:"to"`
+	Agents        int              `json:"agents"`  // agents the rule was replayed for
+	Samples       int              `json:"samples"` // samples evaluated
+	Alerts        []*BacktestAlert `json:"alerts"`
+	Count         int              `json:"count"`         // number of alerts
+	Notifications int              `json:"notifications"` // times the rule would have fired, including repeats
+}
+
+// BacktestAlert is one alert a rule would have raised. It starts when the
+// rule first fires and ends once the condition clears.
+type BacktestAlert struct {
+	AgentID       string    `json:"agent_id"`
+	Severity      string    `json:"severity"` // most severe level reached
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	Ongoing       bool      `json:"ongoing"` // still breached at the end of the period
+	Value         float64   `json:"value"`   // value when the alert started
+	Notifications int       `json:"notifications"`
+	Suppressed    bool      `json:"suppressed"` // started during a maintenance window
 }
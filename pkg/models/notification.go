@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// NotificationChannelConfig represents a configured notification channel
+type NotificationChannelConfig struct {
+	Name string   `json:"name"`
+	Type string   `json:"type"` // email, webhook
+	To   []string `json:"to"`   // recipients for email channels
+	URL  string   `json:"url"`  // endpoint for webhook channels
+}
+
+// Notification represents a queued notification in the outbox
+type Notification struct {
+	ID            int       `json:"id"`
+	AlertID       int       `json:"alert_id"`
+	Channel       string    `json:"channel"`
+	Recipient     string    `json:"recipient"`
+	Subject       string    `json:"subject"`
+	Body          string    `json:"body"`
+	Status        string    `json:"status"` // pending, sent, failed
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// NotificationDelivery represents a single delivery attempt of a notification
+type NotificationDelivery struct {
+	ID             int       `json:"id"`
+	NotificationID int       `json:"notification_id"`
+	AlertID        int       `json:"alert_id"`
+	Channel        string    `json:"channel"`
+	Attempt        int       `json:"attempt"`
+	Status         string    `json:"status"` // sent, failed
+	Error          string    `json:"error"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// NotificationTemplate represents a text/template used to render notifications.
+// An empty Channel applies to every channel and a zero RuleID to every rule;
+// the most specific template wins.
+type NotificationTemplate struct {
+	ID      int    `json:"id"`
+	Channel string `json:"channel"`
+	RuleID  int    `json:"rule_id"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// NotificationRoute sends alerts matching its criteria to a set of channels.
+// Empty criteria match everything. Routes are tried in position order and
+// matching stops at the first match unless Continue is set.
+type NotificationRoute struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Position    int      `json:"position"`
+	Severities  []string `json:"severities"`
+	Tags        []string `json:"tags"`         // agent tags, any of which must match
+	MetricTypes []string `json:"metric_types"` // rule metric types
+	Channels    []string `json:"channels"`
+	Continue    bool     `json:"continue"`
+}
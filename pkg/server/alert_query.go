@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	defaultAlertPageSize = 100
+	maxAlertPageSize     = 1000
+
+	// maxTimelineEntries bounds the notifications listed in an alert's timeline
+	maxTimelineEntries = 500
+)
+
+// parseAlertQuery reads the filters, sort order and page of an alert listing
+// from query parameters:
+//
+//	agent_id, rule_id, resolved (true/false), severity (comma separated),
+//	from, to (RFC3339), sort (created_at, value, severity), order (asc, desc),
+//	limit and cursor (from the X-Next-Cursor header of the previous page)
+func parseAlertQuery(values url.Values) (AlertQuery, error) {
+	q := AlertQuery{
+		AgentID: values.Get("agent_id"),
+		Sort:    values.Get("sort"),
+		Limit:   defaultAlertPageSize,
+	}
+
+	if v := values.Get("rule_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return q, &ValidationError{Field: "rule_id", Message: "must be a positive integer"}
+		}
+		q.RuleID = id
+	}
+	if v := values.Get("resolved"); v != "" {
+		resolved, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, &ValidationError{Field: "resolved", Message: "must be true or false"}
+		}
+		q.Resolved = &resolved
+	}
+	if v := values.Get("severity"); v != "" {
+		for _, severity := range strings.Split(v, ",") {
+			severity = strings.TrimSpace(severity)
+			if severityRanks[severity] == 0 {
+				return q, &ValidationError{Field: "severity", Message: fmt.Sprintf("unknown severity %q", severity)}
+			}
+			q.Severities = append(q.Severities, severity)
+		}
+	}
+	for _, field := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &q.From}, {"to", &q.To}} {
+		if v := values.Get(field.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return q, &ValidationError{Field: field.name, Message: "must be an RFC3339 time"}
+			}
+			*field.dest = t
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return q, &ValidationError{Field: "to", Message: "must be after from"}
+	}
+
+	switch q.Sort {
+	case "", "created_at", "value", "severity":
+	default:
+		return q, &ValidationError{Field: "sort", Message: "must be one of created_at, value, severity"}
+	}
+	switch values.Get("order") {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		return q, &ValidationError{Field: "order", Message: "must be asc or desc"}
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxAlertPageSize {
+			return q, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxAlertPageSize)}
+		}
+		q.Limit = limit
+	}
+	if v := values.Get("cursor"); v != "" {
+		cursor, err := decodeAlertCursor(v)
+		if err != nil {
+			return q, &ValidationError{Field: "cursor", Message: "is not a cursor returned by this endpoint"}
+		}
+		q.After = cursor
+	}
+	return q, nil
+}
+
+// encodeAlertCursor turns a cursor into an opaque URL-safe token
+func encodeAlertCursor(c *AlertCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAlertCursor(token string) (*AlertCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var c AlertCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.ID <= 0 {
+		return nil, fmt.Errorf("cursor has no alert id")
+	}
+	return &c, nil
+}
+
+// alertTimeline orders what happened to an alert: when it triggered, each
+// notification queued and delivery attempt, the acknowledgement and the
+// resolution
+func alertTimeline(alert *models.Alert, notifications []*models.Notification, deliveries []*models.NotificationDelivery) []models.AlertEvent {
+	timeline := []models.AlertEvent{{
+		Type:      "triggered",
+		Timestamp: alert.Timestamp,
+		Detail:    alert.Message,
+	}}
+	if alert.Suppressed {
+		timeline[0].Detail += " (suppressed by a maintenance window)"
+	}
+
+	recipients := make(map[int]string, len(notifications))
+	for _, n := range notifications {
+		recipients[n.ID] = n.Recipient
+		timeline = append(timeline, models.AlertEvent{
+			Type:      "queued",
+			Timestamp: n.CreatedAt,
+			Channel:   n.Channel,
+			Recipient: n.Recipient,
+			Detail:    n.Subject,
+		})
+	}
+	for _, d := range deliveries {
+		event := models.AlertEvent{
+			Type:      "notified",
+			Timestamp: d.Timestamp,
+			Channel:   d.Channel,
+			Recipient: recipients[d.NotificationID],
+		}
+		if d.Status != "sent" {
+			event.Type = "notification_failed"
+			event.Detail = fmt.Sprintf("attempt %d: %s", d.Attempt, d.Error)
+		}
+		timeline = append(timeline, event)
+	}
+
+	if !alert.AcknowledgedAt.IsZero() {
+		timeline = append(timeline, models.AlertEvent{
+			Type:      "acknowledged",
+			Timestamp: alert.AcknowledgedAt,
+			Detail:    "acknowledged by " + alert.AcknowledgedBy,
+		})
+	}
+	if alert.Resolved {
+		// Alerts resolved before resolution times were recorded have none
+		// and are kept at the end
+		timeline = append(timeline, models.AlertEvent{Type: "resolved", Timestamp: alert.ResolvedAt})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		ti, tj := timeline[i].Timestamp, timeline[j].Timestamp
+		if ti.IsZero() || tj.IsZero() {
+			return !ti.IsZero() && tj.IsZero()
+		}
+		return ti.Before(tj)
+	})
+	return timeline
+}
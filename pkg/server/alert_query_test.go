@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	db, err := NewDatabase(models.DatabaseConfig{Driver: "sqlite", Database: filepath.Join(t.TempDir(), "monitor.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Notifications are queued but never delivered, as the notifier is not run
+	config := &models.Config{NotificationChannels: []models.NotificationChannelConfig{
+		{Name: "ops", Type: "webhook", URL: "http://127.0.0.1:9/hook"},
+	}}
+	notifier := NewNotifier(db, config)
+	return &Server{db: db, config: config, alerter: NewAlerter(db, config, notifier), notifier: notifier}
+}
+
+func TestClearedThresholdAlertIsResolved(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "cpu", Operator: "gt", Threshold: 80, Enabled: true}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+
+	report := func(cpu float64) {
+		t.Helper()
+		if err := s.alerter.CheckMetrics(&models.Metrics{AgentID: "web-1", Timestamp: time.Now(), CPUPercent: cpu}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A rule without a duration fires on the second breaching sample
+	report(95)
+	report(95)
+	if open := listAlerts(t, s, "resolved=false"); len(open) != 1 {
+		t.Fatalf("got %d open alerts while breached, want 1", len(open))
+	}
+
+	report(20)
+	if open := listAlerts(t, s, "resolved=false"); len(open) != 0 {
+		t.Fatalf("got %d open alerts after clearing, want 0", len(open))
+	}
+	resolved := listAlerts(t, s, "resolved=true")
+	if len(resolved) != 1 {
+		t.Fatalf("got %d resolved alerts, want 1", len(resolved))
+	}
+	if resolved[0].ResolvedAt.IsZero() {
+		t.Error("resolved alert has no resolution time")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleAlert(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/alerts/%d", resolved[0].ID), nil))
+	if rec.Code != 200 {
+		t.Fatalf("alert detail returned %d: %s", rec.Code, rec.Body)
+	}
+	var detail models.AlertDetail
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+		t.Fatal(err)
+	}
+	var resolveEvent *models.AlertEvent
+	for i, event := range detail.Timeline {
+		if event.Type == "resolved" {
+			resolveEvent = &detail.Timeline[i]
+		}
+	}
+	if resolveEvent == nil || !resolveEvent.Timestamp.Equal(resolved[0].ResolvedAt) {
+		t.Errorf("timeline %+v has no resolve event at %v", detail.Timeline, resolved[0].ResolvedAt)
+	}
+}
+
+func listAlerts(t *testing.T, s *Server, query string) []*models.Alert {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handleAlerts(rec, httptest.NewRequest("GET", "/api/alerts?"+query, nil))
+	if rec.Code != 200 {
+		t.Fatalf("alert listing returned %d: %s", rec.Code, rec.Body)
+	}
+	var alerts []*models.Alert
+	if err := json.NewDecoder(rec.Body).Decode(&alerts); err != nil {
+		t.Fatal(err)
+	}
+	return alerts
+}
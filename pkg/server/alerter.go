@@ -2,7 +2,8 @@ package server
 
 import (
 	"fmt"
-	"net/smtp"
+	"log"
+	"math"
 	"sync"
 	"time"
 
@@ -13,16 +14,43 @@ import (
 type Alerter struct {
 	db          *Database
 	config      *models.Config
-	alertStates map[int]map[string]time.Time // rule_id -> agent_id -> first_trigger_time
+	notifier    *Notifier
+	alertStates map[int]map[string]time.Time // rule_id -> agent_id -> first_trigger_time (last fire time for window rules)
+	history     map[string]*sampleRing       // agent_id -> recent samples
+	expressions map[int]*Expression          // rule_id -> parsed expression
+	severities  map[int]map[string]string    // rule_id -> agent_id -> severity of the last alert
+	baselines   map[int]map[string]*baseline // rule_id -> agent_id -> learned baseline
+	learning    map[int]map[string]bool      // rule_id -> agent_id -> baseline being learned
+	learners    chan struct{}                // bounds the baselines and trends learned at once
+	learnInline bool                         // learn baselines and trends before judging, as replays need
+	trends      map[trendKey]*trend          // fitted usage trends
+	fitting     map[trendKey]bool            // trends being fitted
+
+	activeAlerts map[int]map[string][]*models.Alert // rule_id -> agent_id -> open alerts, oldest first
+	loadedRules  map[int]bool                       // rules whose open alerts were restored from the database
+
 	mu          sync.RWMutex
+	transitions sync.Mutex // serializes opening and resolving alerts
 }
 
 // NewAlerter creates a new alerter
-func NewAlerter(db *Database, config *models.Config) *Alerter {
+func NewAlerter(db *Database, config *models.Config, notifier *Notifier) *Alerter {
 	return &Alerter{
 		db:          db,
 		config:      config,
+		notifier:    notifier,
 		alertStates: make(map[int]map[string]time.Time),
+		history:     make(map[string]*sampleRing),
+		expressions: make(map[int]*Expression),
+		severities:  make(map[int]map[string]string),
+		baselines:   make(map[int]map[string]*baseline),
+		learning:    make(map[int]map[string]bool),
+		learners:    make(chan struct{}, maxBaselineLearners),
+		trends:      make(map[trendKey]*trend),
+		fitting:     make(map[trendKey]bool),
+
+		activeAlerts: make(map[int]map[string][]*models.Alert),
+		loadedRules:  make(map[int]bool),
 	}
 }
 
@@ -33,8 +61,20 @@ func (a *Alerter) CheckMetrics(metrics *models.Metrics) error {
 		return err
 	}
 
+	history, oldest := a.recordSample(metrics)
+
+	// Alerts raised before a restart are resolved once their rule clears
+	if err := a.loadActiveAlerts(rules); err != nil {
+		log.Printf("Failed to load active alerts: %v", err)
+	}
+	a.pruneBaselines(rules)
+	a.pruneTrends(rules)
+
+	// A report ends any offline alert for the agent
+	a.resolveOffline(rules, metrics.AgentID)
+
 	for _, rule := range rules {
-		if !rule.Enabled {
+		if !rule.Enabled || rule.MetricType == "offline" {
 			continue
 		}
 
@@ -43,10 +83,15 @@ func (a *Alerter) CheckMetrics(metrics *models.Metrics) error {
 			continue
 		}
 
-		value := a.getMetricValue(metrics, rule.MetricType)
-		if a.checkThreshold(value, rule.Threshold, rule.Operator) {
+		result, value, err := a.evaluate(rule, metrics, history, oldest)
+		if err != nil {
+			log.Printf("Failed to evaluate rule %d: %v", rule.ID, err)
+			continue
+		}
+		switch result {
+		case verdictBreached:
 			a.handleAlertTrigger(rule, metrics, value)
-		} else {
+		case verdictClear:
 			a.handleAlertClear(rule, metrics.AgentID)
 		}
 	}
@@ -54,27 +99,218 @@ func (a *Alerter) CheckMetrics(metrics *models.Metrics) error {
 	return nil
 }
 
-// getMetricValue extracts the metric value based on type
-func (a *Alerter) getMetricValue(metrics *models.Metrics, metricType string) float64 {
-	switch metricType {
-	case "cpu":
-		return metrics.CPUPercent
-	case "memory":
-		if metrics.MemoryTotal > 0 {
-			return float64(metrics.MemoryUsed) / float64(metrics.MemoryTotal) * 100
+// verdict is the outcome of judging a rule against a sample
+type verdict int
+
+const (
+	verdictClear verdict = iota
+	verdictBreached
+	// verdictUnknown means there is not enough data to judge the rule yet.
+	// It neither fires nor clears, so an open alert stays open.
+	verdictUnknown
+)
+
+// judge turns whether a rule is breached into a verdict
+func judge(breached bool) verdict {
+	if breached {
+		return verdictBreached
+	}
+	return verdictClear
+}
+
+// evaluate checks a rule against a sample and the agent's recent history,
+// returning its verdict and the value that was compared
+func (a *Alerter) evaluate(rule *models.AlertRule, metrics *models.Metrics, history []*models.Metrics, oldest *models.Metrics) (verdict, float64, error) {
+	if rule.Target != "" {
+		// Rules on a mountpoint or device judge only that one. A report
+		// without it, as when reading a hung mount timed out, says nothing
+		// about whether it recovered.
+		view, ok := targetView(metrics, rule.MetricType, rule.Target)
+		if !ok {
+			return verdictUnknown, 0, nil
+		}
+		metrics = view
+		history, oldest = targetHistory(history, oldest, rule.MetricType, rule.Target)
+	}
+
+	if rule.Expression != "" {
+		expr, err := a.expression(rule)
+		if err != nil {
+			return verdictUnknown, 0, err
 		}
-	case "disk":
-		if metrics.DiskTotal > 0 {
-			return float64(metrics.DiskUsed) / float64(metrics.DiskTotal) * 100
+		breached, value := expr.Eval(&exprEnv{current: metrics, history: history})
+		return judge(breached), value, nil
+	}
+
+	switch rule.Type {
+	case "anomaly":
+		return a.evaluateAnomaly(rule, metrics)
+	case "forecast":
+		return a.evaluateForecast(rule, metrics)
+	}
+
+	if rule.Window > 0 {
+		// Judge the whole window rather than the single sample, so one
+		// sample on the other side of the threshold does not reset the rule
+		result := evaluateWindow(rule, metrics.Timestamp, history, oldest)
+		if !result.complete {
+			// The history does not reach back a whole window yet, as after
+			// a restart
+			return verdictUnknown, result.value, nil
 		}
-	case "load":
-		return metrics.LoadAvg1
+		breached := checkThreshold(result.value, rule.Threshold, rule.Operator) &&
+			result.share >= rule.BreachRatio
+		return judge(breached), result.value, nil
+	}
+
+	value := metricValue(metrics, rule.MetricType)
+	return judge(checkThreshold(value, rule.Threshold, rule.Operator)), value, nil
+}
+
+// expression returns the parsed expression of a rule, parsing it again only
+// when the rule has changed
+func (a *Alerter) expression(rule *models.AlertRule) (*Expression, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if expr, ok := a.expressions[rule.ID]; ok && expr.String() == rule.Expression {
+		return expr, nil
+	}
+	expr, err := ParseExpression(rule.Expression)
+	if err != nil {
+		return nil, err
+	}
+	a.expressions[rule.ID] = expr
+	return expr, nil
+}
+
+// recordSample adds a sample to the agent's ring buffer and returns the
+// samples within the longest window along with the oldest sample kept
+func (a *Alerter) recordSample(metrics *models.Metrics) ([]*models.Metrics, *models.Metrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ring, ok := a.history[metrics.AgentID]
+	if !ok {
+		ring = newSampleRing(sampleRingSize)
+		a.history[metrics.AgentID] = ring
+	}
+	ring.add(metrics)
+
+	return ring.since(metrics.Timestamp.Add(-maxExprWindow)), ring.oldest()
+}
+
+// metricAccessor reads a single value from a metrics sample
+type metricAccessor struct {
+	value  func(m *models.Metrics) float64
+	unit   string
+	rate   bool   // the value is already a per-second rate, not a level
+	target string // what a rule's target names, if the value can be limited to one
+}
+
+// metricAccessors maps metric names usable in rules and expressions to their values
+var metricAccessors = map[string]metricAccessor{
+	"cpu":                   {value: func(m *models.Metrics) float64 { return m.CPUPercent }, unit: "%"},
+	"cpu_percent":           {value: func(m *models.Metrics) float64 { return m.CPUPercent }, unit: "%"},
+	"cpu_user":              {value: func(m *models.Metrics) float64 { return m.CPUUser }, unit: "%"},
+	"cpu_system":            {value: func(m *models.Metrics) float64 { return m.CPUSystem }, unit: "%"},
+	"cpu_iowait":            {value: func(m *models.Metrics) float64 { return m.CPUIowait }, unit: "%"},
+	"cpu_steal":             {value: func(m *models.Metrics) float64 { return m.CPUSteal }, unit: "%"},
+	"cpu_irq":               {value: func(m *models.Metrics) float64 { return m.CPUIrq }, unit: "%"},
+	"cpu_core_max":          {value: busiestCore, unit: "%"},
+	"cpu_cores":             {value: func(m *models.Metrics) float64 { return float64(m.CPUCores) }},
+	"memory":                {value: memoryPercent, unit: "%"},
+	"mem_percent":           {value: memoryPercent, unit: "%"},
+	"mem_used_bytes":        {value: func(m *models.Metrics) float64 { return float64(m.MemoryUsed) }, unit: "B"},
+	"mem_total_bytes":       {value: func(m *models.Metrics) float64 { return float64(m.MemoryTotal) }, unit: "B"},
+	"mem_free_bytes":        {value: func(m *models.Metrics) float64 { return float64(m.MemoryTotal) - float64(m.MemoryUsed) }, unit: "B"},
+	"mem_available_bytes":   {value: func(m *models.Metrics) float64 { return float64(m.MemoryAvailable) }, unit: "B"},
+	"mem_available_percent": {value: memoryAvailablePercent, unit: "%"},
+	"mem_cached_bytes":      {value: func(m *models.Metrics) float64 { return float64(m.MemoryCached) }, unit: "B"},
+	"mem_buffers_bytes":     {value: func(m *models.Metrics) float64 { return float64(m.MemoryBuffers) }, unit: "B"},
+	"mem_pressure_some":     {value: func(m *models.Metrics) float64 { return m.MemoryPressureSome }, unit: "%"},
+	"mem_pressure_full":     {value: func(m *models.Metrics) float64 { return m.MemoryPressureFull }, unit: "%"},
+	"swap_percent":          {value: swapPercent, unit: "%"},
+	"swap_used_bytes":       {value: func(m *models.Metrics) float64 { return float64(m.SwapUsed) }, unit: "B"},
+	"swap_total_bytes":      {value: func(m *models.Metrics) float64 { return float64(m.SwapTotal) }, unit: "B"},
+	"disk":                  {value: diskPercent, unit: "%", target: targetFilesystem},
+	"disk_percent":          {value: diskPercent, unit: "%", target: targetFilesystem},
+	"disk_used_bytes":       {value: func(m *models.Metrics) float64 { return float64(m.DiskUsed) }, unit: "B", target: targetFilesystem},
+	"disk_total_bytes":      {value: func(m *models.Metrics) float64 { return float64(m.DiskTotal) }, unit: "B", target: targetFilesystem},
+	"disk_free_bytes":       {value: func(m *models.Metrics) float64 { return float64(m.DiskTotal) - float64(m.DiskUsed) }, unit: "B", target: targetFilesystem},
+	"disk_inodes_percent":   {value: inodesPercent, unit: "%", target: targetFilesystem},
+	"disk_read_bps":         {value: func(m *models.Metrics) float64 { return m.DiskReadBps }, unit: "B/s", rate: true, target: targetDevice},
+	"disk_write_bps":        {value: func(m *models.Metrics) float64 { return m.DiskWriteBps }, unit: "B/s", rate: true, target: targetDevice},
+	"disk_read_iops":        {value: func(m *models.Metrics) float64 { return m.DiskReadIOPS }, unit: "/s", rate: true, target: targetDevice},
+	"disk_write_iops":       {value: func(m *models.Metrics) float64 { return m.DiskWriteIOPS }, unit: "/s", rate: true, target: targetDevice},
+	"disk_iops":             {value: func(m *models.Metrics) float64 { return m.DiskReadIOPS + m.DiskWriteIOPS }, unit: "/s", rate: true, target: targetDevice},
+	"disk_util_percent":     {value: func(m *models.Metrics) float64 { return m.DiskUtilPercent }, unit: "%", target: targetDevice},
+	"disk_await_ms":         {value: func(m *models.Metrics) float64 { return m.DiskAwaitMs }, unit: "ms", target: targetDevice},
+	"watches_down":          {value: func(m *models.Metrics) float64 { return float64(m.WatchesDown) }, target: targetWatch},
+	"watch_restarts":        {value: func(m *models.Metrics) float64 { return float64(m.WatchRestarts) }, target: targetWatch},
+	"temperature":           {value: func(m *models.Metrics) float64 { return m.TemperatureMax }, unit: "°C", target: targetSensor},
+	"fan_rpm":               {value: fanSpeed, unit: "RPM", target: targetSensor},
+	"custom":                {value: customValue, target: targetCustom},
+	"load":                  {value: func(m *models.Metrics) float64 { return m.LoadAvg1 }},
+	"load1":                 {value: func(m *models.Metrics) float64 { return m.LoadAvg1 }},
+	"load5":                 {value: func(m *models.Metrics) float64 { return m.LoadAvg5 }},
+	"load15":                {value: func(m *models.Metrics) float64 { return m.LoadAvg15 }},
+	"network_rx":            {value: func(m *models.Metrics) float64 { return float64(m.NetworkRx) }, unit: "B/s", rate: true, target: targetInterface},
+	"network_tx":            {value: func(m *models.Metrics) float64 { return float64(m.NetworkTx) }, unit: "B/s", rate: true, target: targetInterface},
+	"network_rx_packets":    {value: func(m *models.Metrics) float64 { return m.NetworkRxPackets }, unit: "/s", rate: true, target: targetInterface},
+	"network_tx_packets":    {value: func(m *models.Metrics) float64 { return m.NetworkTxPackets }, unit: "/s", rate: true, target: targetInterface},
+	"network_errors":        {value: func(m *models.Metrics) float64 { return m.NetworkErrors }, unit: "/s", rate: true, target: targetInterface},
+	"network_drops":         {value: func(m *models.Metrics) float64 { return m.NetworkDrops }, unit: "/s", rate: true, target: targetInterface},
+}
+
+// busiestCore returns the utilisation of the busiest core, which shows a
+// single-threaded process saturating its core while the average stays low
+func busiestCore(m *models.Metrics) float64 {
+	busiest := 0.0
+	for _, core := range m.CPUPerCore {
+		busiest = math.Max(busiest, core)
+	}
+	return busiest
+}
+
+func memoryPercent(m *models.Metrics) float64 {
+	if m.MemoryTotal == 0 {
+		return 0
+	}
+	return float64(m.MemoryUsed) / float64(m.MemoryTotal) * 100
+}
+
+func memoryAvailablePercent(m *models.Metrics) float64 {
+	if m.MemoryTotal == 0 {
+		return 0
+	}
+	return float64(m.MemoryAvailable) / float64(m.MemoryTotal) * 100
+}
+
+func swapPercent(m *models.Metrics) float64 {
+	if m.SwapTotal == 0 {
+		return 0
+	}
+	return float64(m.SwapUsed) / float64(m.SwapTotal) * 100
+}
+
+func diskPercent(m *models.Metrics) float64 {
+	if m.DiskTotal == 0 {
+		return 0
+	}
+	return float64(m.DiskUsed) / float64(m.DiskTotal) * 100
+}
+
+// metricValue extracts the metric value based on type
+func metricValue(metrics *models.Metrics, metricType string) float64 {
+	if accessor, ok := metricAccessors[metricType]; ok {
+		return accessor.value(metrics)
 	}
 	return 0
 }
 
 // checkThreshold checks if value meets threshold condition
-func (a *Alerter) checkThreshold(value, threshold float64, operator string) bool {
+func checkThreshold(value, threshold float64, operator string) bool {
 	switch operator {
 	case "gt":
 		return value > threshold
@@ -88,81 +324,237 @@ func (a *Alerter) checkThreshold(value, threshold float64, operator string) bool
 	return false
 }
 
-// handleAlertTrigger handles alert trigger logic
+// handleAlertTrigger handles alert trigger logic. Maintenance windows are
+// looked up before taking transitions and notifications queued after
+// releasing it, so their queries never hold up other alerts.
 func (a *Alerter) handleAlertTrigger(rule *models.AlertRule, metrics *models.Metrics, value float64) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	severity := ruleSeverity(rule, value)
+	if !a.shouldFire(rule, metrics.AgentID, severity, time.Now()) {
+		return
+	}
 
-	if a.alertStates[rule.ID] == nil {
-		a.alertStates[rule.ID] = make(map[string]time.Time)
+	alert := &models.Alert{
+		RuleID:    rule.ID,
+		AgentID:   metrics.AgentID,
+		Timestamp: time.Now(),
+		Message:   formatAlertMessage(rule, value),
+		Value:     value,
+		Resolved:  false,
+		Severity:  severity,
 	}
+	alert.Suppressed = a.inMaintenance(alert, rule, alert.Timestamp)
 
-	firstTrigger, exists := a.alertStates[rule.ID][metrics.AgentID]
-	if !exists {
-		a.alertStates[rule.ID][metrics.AgentID] = time.Now()
-		return
+	fired, raised := a.fireOrRaise(alert, rule)
+	if fired != nil {
+		a.notifyFired(fired, rule)
+	}
+	if raised != nil {
+		a.notifyRaised(raised, rule)
 	}
+}
 
-	// Check if alert has been triggered for the required duration
-	if time.Since(firstTrigger) >= time.Duration(rule.Duration)*time.Second {
-		alert := &models.Alert{
-			RuleID:    rule.ID,
-			AgentID:   metrics.AgentID,
-			Timestamp: time.Now(),
-			Message:   fmt.Sprintf("%s: %.2f%s %s %.2f%s", rule.MetricType, value, a.getUnit(rule.MetricType), rule.Operator, rule.Threshold, a.getUnit(rule.MetricType)),
-			Value:     value,
-			Resolved:  false,
+// fireOrRaise records a new alert unless its rule already has an open alert
+// for the agent. A breach that goes on keeps its one open alert, which is
+// only raised when the breach reaches a more severe level. It returns a copy
+// of the alert it fired or raised, to notify about once transitions is
+// released.
+func (a *Alerter) fireOrRaise(alert *models.Alert, rule *models.AlertRule) (fired, raised *models.Alert) {
+	a.transitions.Lock()
+	defer a.transitions.Unlock()
+
+	if open := a.activeAlert(rule.ID, alert.AgentID); open != nil {
+		if severityRanks[alert.Severity] > severityRanks[open.Severity] && a.raise(open, alert) {
+			snapshot := *open
+			return nil, &snapshot
 		}
+		return nil, nil
+	}
+	if !a.record(alert, rule) {
+		return nil, nil
+	}
+	snapshot := *alert
+	return &snapshot, nil
+}
 
-		if err := a.db.SaveAlert(alert); err == nil {
-			a.sendEmailNotification(alert, rule)
+// raise moves an open alert up to the severity of a new breach. Callers hold
+// transitions.
+func (a *Alerter) raise(open, breach *models.Alert) bool {
+	if err := a.db.RaiseAlertSeverity(open.ID, breach.Severity, breach.Message, breach.Value); err != nil {
+		log.Printf("Failed to raise alert %d to %s: %v", open.ID, breach.Severity, err)
+		return false
+	}
+	open.Severity, open.Message, open.Value = breach.Severity, breach.Message, breach.Value
+	return true
+}
+
+// record saves a new alert and records it as open. Callers hold transitions.
+func (a *Alerter) record(alert *models.Alert, rule *models.AlertRule) bool {
+	if err := a.db.SaveAlert(alert); err != nil {
+		log.Printf("Failed to save alert for rule %d: %v", rule.ID, err)
+		return false
+	}
+	a.setActiveAlert(alert)
+	return true
+}
+
+// notifyFired queues the notifications of a new alert. Notifications are
+// only queued here; the notifier delivers them in the background so a slow
+// channel never blocks metric ingestion. Alerts raised during a maintenance
+// window are saved as suppressed and not notified.
+func (a *Alerter) notifyFired(alert *models.Alert, rule *models.AlertRule) {
+	if alert.Suppressed {
+		return
+	}
+	if err := a.notifier.Notify(alert, rule); err != nil {
+		log.Printf("Failed to queue notifications for alert %d: %v", alert.ID, err)
+	}
+}
+
+// notifyRaised notifies the channels routed the new severity of a raised
+// alert. Alerts with an escalation policy are already being escalated, so
+// they are not notified again.
+func (a *Alerter) notifyRaised(alert *models.Alert, rule *models.AlertRule) {
+	if alert.Suppressed || rule.EscalationPolicyID != 0 {
+		return
+	}
+	if err := a.notifier.Enqueue(alert, rule); err != nil {
+		log.Printf("Failed to queue notifications for alert %d: %v", alert.ID, err)
+	}
+}
+
+// inMaintenance reports whether an active maintenance window covers an alert
+func (a *Alerter) inMaintenance(alert *models.Alert, rule *models.AlertRule, now time.Time) bool {
+	windows, err := a.db.GetMaintenanceWindows()
+	if err != nil {
+		log.Printf("Failed to load maintenance windows: %v", err)
+		return false
+	}
+
+	var agent *models.Agent
+	for _, w := range windows {
+		if !maintenanceActive(w, now) {
+			continue
 		}
+		if agent == nil {
+			agent, err = a.db.GetAgent(alert.AgentID)
+			if err != nil || agent == nil {
+				agent = &models.Agent{ID: alert.AgentID}
+			}
+		}
+		if maintenanceCovers(w, agent, rule.ID) {
+			return true
+		}
+	}
+	return false
+}
 
-		// Reset state after alert is sent
-		delete(a.alertStates[rule.ID], metrics.AgentID)
+// formatAlertMessage formats the short summary stored with an alert
+func formatAlertMessage(rule *models.AlertRule, value float64) string {
+	if rule.Expression != "" {
+		return fmt.Sprintf("%s (value: %.2f)", rule.Expression, value)
+	}
+	metric := rule.MetricType
+	if rule.Target != "" {
+		metric += " " + rule.Target
+	}
+	switch rule.Type {
+	case "anomaly":
+		return fmt.Sprintf("%s: %.2f standard deviations %s its baseline", metric, value, anomalyDirectionText(rule.Direction))
+	case "forecast":
+		return fmt.Sprintf("%s: predicted full in %.1fh (threshold %.0fh)", metric, value, rule.Threshold)
+	}
+	unit := metricUnit(rule.MetricType)
+	threshold := rule.Threshold
+	severity := ruleSeverity(rule, value)
+	for _, level := range rule.Levels {
+		if level.Severity == severity {
+			threshold = level.Threshold
+		}
 	}
+	return fmt.Sprintf("%s: %.2f%s %s %.2f%s", metric, value, unit, rule.Operator, threshold, unit)
 }
 
-// handleAlertClear clears alert state when condition is no longer met
-func (a *Alerter) handleAlertClear(rule *models.AlertRule, agentID string) {
+// shouldFire records the breach seen at now and reports whether it has
+// lasted for the rule's duration. The state is reset once the alert fires.
+func (a *Alerter) shouldFire(rule *models.AlertRule, agentID, severity string, now time.Time) bool {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if a.alertStates[rule.ID] != nil {
-		delete(a.alertStates[rule.ID], agentID)
+	if a.alertStates[rule.ID] == nil {
+		a.alertStates[rule.ID] = make(map[string]time.Time)
+	}
+	if a.severities[rule.ID] == nil {
+		a.severities[rule.ID] = make(map[string]string)
 	}
-}
 
-// getUnit returns the unit for a metric type
-func (a *Alerter) getUnit(metricType string) string {
-	switch metricType {
-	case "cpu", "memory", "disk":
-		return "%"
-	case "load":
-		return ""
+	fire := a.shouldFireLocked(rule, agentID, severity, now)
+	if fire {
+		a.severities[rule.ID][agentID] = severity
 	}
-	return ""
+	return fire
 }
 
-// sendEmailNotification sends email notification for an alert
-func (a *Alerter) sendEmailNotification(alert *models.Alert, rule *models.AlertRule) error {
-	if a.config.SMTPHost == "" || a.config.AlertEmail == "" {
-		return nil // Email not configured
+func (a *Alerter) shouldFireLocked(rule *models.AlertRule, agentID, severity string, now time.Time) bool {
+	// A breach that reaches a more severe level than the last alert fires
+	// right away, so a warning escalates to critical without waiting again
+	if last, ok := a.severities[rule.ID][agentID]; ok && severityRanks[severity] > severityRanks[last] {
+		if rule.Window > 0 {
+			a.alertStates[rule.ID][agentID] = now
+		} else {
+			delete(a.alertStates[rule.ID], agentID)
+		}
+		return true
 	}
 
-	subject := fmt.Sprintf("Alert: %s", rule.Description)
-	body := fmt.Sprintf("Alert triggered at %s\n\nAgent: %s\nRule: %s\nMessage: %s\n",
-		alert.Timestamp.Format("2006-01-02 15:04:05"),
-		alert.AgentID,
-		rule.Description,
-		alert.Message,
-	)
+	// Window rules already judge a span of samples, so they fire right away
+	// and then at most once per window while the breach continues
+	if rule.Window > 0 {
+		lastFired, fired := a.alertStates[rule.ID][agentID]
+		if fired && now.Sub(lastFired) < time.Duration(rule.Window)*time.Second {
+			return false
+		}
+		a.alertStates[rule.ID][agentID] = now
+		return true
+	}
 
-	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
-		a.config.EmailFrom, a.config.AlertEmail, subject, body)
+	firstTrigger, exists := a.alertStates[rule.ID][agentID]
+	if !exists {
+		a.alertStates[rule.ID][agentID] = now
+		return false
+	}
+
+	// Check if alert has been triggered for the required duration
+	if now.Sub(firstTrigger) < time.Duration(rule.Duration)*time.Second {
+		return false
+	}
+
+	// Reset state after alert is sent
+	delete(a.alertStates[rule.ID], agentID)
+	return true
+}
+
+// handleAlertClear clears alert state when condition is no longer met and
+// resolves the alerts the rule raised for the agent
+func (a *Alerter) handleAlertClear(rule *models.AlertRule, agentID string) {
+	a.mu.Lock()
+	if a.alertStates[rule.ID] != nil {
+		delete(a.alertStates[rule.ID], agentID)
+	}
+	if a.severities[rule.ID] != nil {
+		delete(a.severities[rule.ID], agentID)
+	}
+	a.mu.Unlock()
+
+	a.transitions.Lock()
+	resolved := a.resolveOpen(rule, agentID)
+	a.transitions.Unlock()
 
-	auth := smtp.PlainAuth("", a.config.SMTPUser, a.config.SMTPPassword, a.config.SMTPHost)
-	addr := fmt.Sprintf("%s:%d", a.config.SMTPHost, a.config.SMTPPort)
+	for _, alert := range resolved {
+		a.notifyResolved(alert, rule)
+	}
+}
 
-	return smtp.SendMail(addr, auth, a.config.EmailFrom, []string{a.config.AlertEmail}, []byte(msg))
+// metricUnit returns the unit for a metric type
+func metricUnit(metricType string) string {
+	return metricAccessors[metricType].unit
 }
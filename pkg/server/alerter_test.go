@@ -0,0 +1,205 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// reportCPU feeds a CPU sample from an agent through the alerter
+func reportCPU(t *testing.T, s *Server, agentID string, cpu float64) {
+	t.Helper()
+	if err := s.alerter.CheckMetrics(&models.Metrics{AgentID: agentID, Timestamp: time.Now(), CPUPercent: cpu}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// openAlerts returns the unresolved alerts of a rule
+func openAlerts(t *testing.T, s *Server, ruleID int) []*models.Alert {
+	t.Helper()
+	alerts, err := s.db.GetUnresolvedAlerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var open []*models.Alert
+	for _, alert := range alerts {
+		if alert.RuleID == ruleID {
+			open = append(open, alert)
+		}
+	}
+	return open
+}
+
+func notificationCount(t *testing.T, s *Server, alertID int) int {
+	t.Helper()
+	notifications, err := s.db.GetNotifications(alertID, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(notifications)
+}
+
+func TestSustainedBreachKeepsOneAlert(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "cpu", Operator: "gt", Threshold: 80, Enabled: true}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a duration the rule is satisfied on every other sample
+	for i := 0; i < 10; i++ {
+		reportCPU(t, s, "web-1", 95)
+	}
+
+	open := openAlerts(t, s, rule.ID)
+	if len(open) != 1 {
+		t.Fatalf("got %d open alerts for a sustained breach, want 1", len(open))
+	}
+	if n := notificationCount(t, s, open[0].ID); n != 1 {
+		t.Errorf("got %d notifications for a sustained breach, want 1", n)
+	}
+
+	reportCPU(t, s, "web-1", 20)
+	if n := notificationCount(t, s, open[0].ID); n != 2 {
+		t.Errorf("got %d notifications after the breach cleared, want the alert and its resolution", n)
+	}
+}
+
+func TestSustainedBreachRaisesSeverity(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "cpu", Operator: "gt", Enabled: true, Levels: []models.ThresholdLevel{
+		{Severity: "warning", Threshold: 80},
+		{Severity: "critical", Threshold: 95},
+	}}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+
+	reportCPU(t, s, "web-1", 85)
+	reportCPU(t, s, "web-1", 85)
+	reportCPU(t, s, "web-1", 99)
+	reportCPU(t, s, "web-1", 99)
+
+	open := openAlerts(t, s, rule.ID)
+	if len(open) != 1 {
+		t.Fatalf("got %d open alerts, want 1", len(open))
+	}
+	if open[0].Severity != "critical" || open[0].Value != 99 {
+		t.Errorf("open alert is %s at %v, want critical at 99", open[0].Severity, open[0].Value)
+	}
+	if n := notificationCount(t, s, open[0].ID); n != 2 {
+		t.Errorf("got %d notifications, want the warning and the raise to critical", n)
+	}
+}
+
+func TestAnomalyAlertStaysOpenWithoutBaseline(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "cpu", Type: "anomaly", Operator: "gt", Threshold: 3, Enabled: true}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	// An alert raised before a restart
+	alert := &models.Alert{RuleID: rule.ID, AgentID: "web-1", Timestamp: time.Now(), Severity: "warning"}
+	if err := s.db.SaveAlert(alert); err != nil {
+		t.Fatal(err)
+	}
+
+	// The baseline is still being learned
+	reportCPU(t, s, "web-1", 20)
+	if open := openAlerts(t, s, rule.ID); len(open) != 1 {
+		t.Fatalf("got %d open alerts while the baseline is learned, want 1", len(open))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.alerter.mu.RLock()
+		learned := s.alerter.baselines[rule.ID]["web-1"] != nil
+		s.alerter.mu.RUnlock()
+		if learned {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("baseline was not learned")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// There is too little history for the baseline to judge by
+	reportCPU(t, s, "web-1", 20)
+	if open := openAlerts(t, s, rule.ID); len(open) != 1 {
+		t.Errorf("got %d open alerts with too little history, want 1", len(open))
+	}
+}
+
+func TestWindowAlertStaysOpenUntilWindowIsComplete(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "cpu", Operator: "gt", Threshold: 80, Window: 300, Aggregate: "avg", Enabled: true}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	// An alert raised before a restart emptied the history
+	alert := &models.Alert{RuleID: rule.ID, AgentID: "web-1", Timestamp: time.Now(), Severity: "warning"}
+	if err := s.db.SaveAlert(alert); err != nil {
+		t.Fatal(err)
+	}
+
+	reportCPU(t, s, "web-1", 20)
+	reportCPU(t, s, "web-1", 20)
+	if open := openAlerts(t, s, rule.ID); len(open) != 1 {
+		t.Errorf("got %d open alerts before the window is complete, want 1", len(open))
+	}
+}
+
+func TestFilesystemAlertStaysOpenWhileUnreported(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "disk", Target: "/data", Operator: "gt", Threshold: 90, Enabled: true}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+
+	report := func(filesystems ...models.FilesystemMetrics) {
+		t.Helper()
+		m := &models.Metrics{AgentID: "web-1", Timestamp: time.Now(), Filesystems: filesystems}
+		if err := s.alerter.CheckMetrics(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	full := models.FilesystemMetrics{Mountpoint: "/data", Used: 95, Total: 100}
+	report(full)
+	report(full)
+	if open := openAlerts(t, s, rule.ID); len(open) != 1 {
+		t.Fatalf("got %d open alerts for a full filesystem, want 1", len(open))
+	}
+
+	// Reading the mount timed out, so the agent left it out
+	report()
+	if open := openAlerts(t, s, rule.ID); len(open) != 1 {
+		t.Errorf("got %d open alerts while the filesystem is unreported, want 1", len(open))
+	}
+
+	report(models.FilesystemMetrics{Mountpoint: "/data", Used: 50, Total: 100})
+	if open := openAlerts(t, s, rule.ID); len(open) != 0 {
+		t.Errorf("got %d open alerts once the filesystem recovered, want 0", len(open))
+	}
+}
+
+func TestFailedResolveKeepsAlertOpen(t *testing.T) {
+	s := newTestServer(t)
+	rule := &models.AlertRule{MetricType: "cpu", Operator: "gt", Threshold: 80, Enabled: true}
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	reportCPU(t, s, "web-1", 95)
+	reportCPU(t, s, "web-1", 95)
+	if len(openAlerts(t, s, rule.ID)) != 1 {
+		t.Fatal("breach did not open an alert")
+	}
+
+	// With the database gone the alert cannot be marked resolved
+	s.db.Close()
+	s.alerter.handleAlertClear(rule, "web-1")
+	if s.alerter.activeAlert(rule.ID, "web-1") == nil {
+		t.Error("alert that failed to resolve was forgotten")
+	}
+}
@@ -0,0 +1,331 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	// anomalyTrainingPeriod is how much history a baseline is learned from
+	anomalyTrainingPeriod = 14 * 24 * time.Hour
+
+	// baselineRefreshInterval is how often a baseline is learned again
+	baselineRefreshInterval = time.Hour
+
+	// minBaselineSamples is how many samples a band needs before it is
+	// trusted. Sparser bands fall back to the agent's overall baseline.
+	minBaselineSamples = 30
+
+	// maxAnomalyDeviations bounds the thresholds of anomaly rules
+	maxAnomalyDeviations = 100
+
+	// maxBaselineLearners bounds how many baselines and trends are learned at once
+	maxBaselineLearners = 2
+)
+
+// baselineStats accumulates the mean and variance of a band using Welford's
+// online algorithm
+type baselineStats struct {
+	count int
+	mean  float64
+	m2    float64
+}
+
+func (s *baselineStats) add(v float64) {
+	s.count++
+	delta := v - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (v - s.mean)
+}
+
+func (s *baselineStats) stddev() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.count-1))
+}
+
+// baseline is the learned behaviour of one metric of one agent, split into
+// seasonal bands such as the hour of the day
+type baseline struct {
+	settings    string // the rule settings it was learned for
+	seasonality string
+	overall     baselineStats
+	bands       []baselineStats
+	learnedAt   time.Time
+}
+
+// seasonalBands returns how many bands a seasonality splits a baseline into
+func seasonalBands(seasonality string) int {
+	switch seasonality {
+	case "hour_of_day":
+		return 24
+	case "hour_of_week":
+		return 7 * 24
+	}
+	return 1
+}
+
+// seasonalBand returns the band t falls into, in the server's time zone
+func seasonalBand(seasonality string, t time.Time) int {
+	t = t.Local()
+	switch seasonality {
+	case "hour_of_day":
+		return t.Hour()
+	case "hour_of_week":
+		return int(t.Weekday())*24 + t.Hour()
+	}
+	return 0
+}
+
+// expected returns the mean and standard deviation expected at t, or false
+// if there is too little history to judge
+func (b *baseline) expected(t time.Time) (float64, float64, bool) {
+	stats := &b.overall
+	if band := &b.bands[seasonalBand(b.seasonality, t)]; band.count >= minBaselineSamples {
+		stats = band
+	}
+	if stats.count < minBaselineSamples {
+		return 0, 0, false
+	}
+	return stats.mean, stats.stddev(), true
+}
+
+// anomalyStddevFloor keeps an almost flat baseline from turning every small
+// change into a large deviation
+func anomalyStddevFloor(metricType string, mean float64) float64 {
+	floor := math.Abs(mean) * 0.05
+	if metricUnit(metricType) == "%" && floor < 0.5 {
+		floor = 0.5
+	}
+	return math.Max(floor, 1e-9)
+}
+
+// learnBaseline learns the baseline of a rule's metric of an agent from the
+// training period before now
+func (a *Alerter) learnBaseline(agentID string, rule *models.AlertRule, now time.Time) (*baseline, error) {
+	accessor := metricAccessors[rule.MetricType]
+	seasonality := rule.Seasonality
+	b := &baseline{
+		settings:    baselineSettings(rule),
+		seasonality: seasonality,
+		bands:       make([]baselineStats, seasonalBands(seasonality)),
+		learnedAt:   now,
+	}
+	err := a.db.eachSample(agentID, rule.MetricType, rule.Target, now.Add(-anomalyTrainingPeriod), now, func(m *models.Metrics) {
+		v := accessor.value(m)
+		b.overall.add(v)
+		b.bands[seasonalBand(seasonality, m.Timestamp)].add(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// baselineSettings identifies the settings of a rule a baseline depends
+// on, so that editing them learns it again
+func baselineSettings(rule *models.AlertRule) string {
+	return fmt.Sprintf("%s|%s|%s", rule.MetricType, rule.Seasonality, rule.Target)
+}
+
+// baseline returns the cached baseline a rule is judged against for an
+// agent, or nil while there is none yet. Learning reads two weeks of
+// samples, so once the baseline is missing or older than
+// baselineRefreshInterval it is learned again in the background, one
+// learner per rule and agent, and the old one is used until then.
+func (a *Alerter) baseline(rule *models.AlertRule, agentID string, now time.Time) (*baseline, error) {
+	a.mu.Lock()
+	b := a.baselines[rule.ID][agentID]
+	if b != nil && b.settings != baselineSettings(rule) {
+		b = nil
+	}
+	if b != nil && now.Sub(b.learnedAt) < baselineRefreshInterval {
+		a.mu.Unlock()
+		return b, nil
+	}
+
+	if a.learnInline {
+		a.mu.Unlock()
+		b, err := a.learnBaseline(agentID, rule, now)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		a.setBaselineLocked(rule.ID, agentID, b)
+		a.mu.Unlock()
+		return b, nil
+	}
+
+	if !a.learning[rule.ID][agentID] {
+		if a.learning[rule.ID] == nil {
+			a.learning[rule.ID] = make(map[string]bool)
+		}
+		a.learning[rule.ID][agentID] = true
+		go a.refreshBaseline(rule, agentID, now)
+	}
+	a.mu.Unlock()
+	return b, nil
+}
+
+// refreshBaseline learns a baseline in the background and caches it, unless
+// the rule stopped being an anomaly rule in the meantime
+func (a *Alerter) refreshBaseline(rule *models.AlertRule, agentID string, now time.Time) {
+	a.learners <- struct{}{}
+	b, err := a.learnBaseline(agentID, rule, now)
+	<-a.learners
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.learning[rule.ID][agentID] {
+		return
+	}
+	delete(a.learning[rule.ID], agentID)
+	if err != nil {
+		log.Printf("Failed to learn the baseline of rule %d for agent %s: %v", rule.ID, agentID, err)
+		return
+	}
+	a.setBaselineLocked(rule.ID, agentID, b)
+}
+
+func (a *Alerter) setBaselineLocked(ruleID int, agentID string, b *baseline) {
+	if a.baselines[ruleID] == nil {
+		a.baselines[ruleID] = make(map[string]*baseline)
+	}
+	a.baselines[ruleID][agentID] = b
+}
+
+// pruneBaselines drops the baselines of rules that were deleted, disabled or
+// are no longer anomaly rules, and of agents a rule no longer applies to
+func (a *Alerter) pruneBaselines(rules []*models.AlertRule) {
+	anomalyRules := make(map[int]*models.AlertRule)
+	for _, rule := range rules {
+		if rule.Enabled && rule.Type == "anomaly" {
+			anomalyRules[rule.ID] = rule
+		}
+	}
+	wanted := func(ruleID int, agentID string) bool {
+		rule := anomalyRules[ruleID]
+		return rule != nil && (rule.AgentID == "" || rule.AgentID == agentID)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for ruleID, agents := range a.baselines {
+		for agentID := range agents {
+			if !wanted(ruleID, agentID) {
+				delete(agents, agentID)
+			}
+		}
+		if len(agents) == 0 {
+			delete(a.baselines, ruleID)
+		}
+	}
+	// Learners still running find their mark gone and discard the result
+	for ruleID, agents := range a.learning {
+		for agentID := range agents {
+			if !wanted(ruleID, agentID) {
+				delete(agents, agentID)
+			}
+		}
+		if len(agents) == 0 {
+			delete(a.learning, ruleID)
+		}
+	}
+}
+
+// evaluateAnomaly checks how many standard deviations a sample is from the
+// agent's baseline in the rule's direction. Rules do not fire until enough
+// history has been stored to learn a baseline, and until then neither fire
+// nor clear.
+func (a *Alerter) evaluateAnomaly(rule *models.AlertRule, metrics *models.Metrics) (verdict, float64, error) {
+	b, err := a.baseline(rule, metrics.AgentID, metrics.Timestamp)
+	if err != nil {
+		return verdictUnknown, 0, err
+	}
+	if b == nil {
+		// Still being learned in the background
+		return verdictUnknown, 0, nil
+	}
+	mean, stddev, ok := b.expected(metrics.Timestamp)
+	if !ok {
+		return verdictUnknown, 0, nil
+	}
+
+	deviation := (metricValue(metrics, rule.MetricType) - mean) / math.Max(stddev, anomalyStddevFloor(rule.MetricType, mean))
+	switch rule.Direction {
+	case "below":
+		deviation = -deviation
+	case "both":
+		deviation = math.Abs(deviation)
+	}
+	return judge(checkThreshold(deviation, rule.Threshold, rule.Operator)), deviation, nil
+}
+
+// anomalyDirectionText describes the direction of an anomaly rule in alert messages
+func anomalyDirectionText(direction string) string {
+	switch direction {
+	case "below":
+		return "below"
+	case "both":
+		return "away from"
+	}
+	return "above"
+}
+
+// normalizeRuleType fills in the defaults of a rule's type and its
+// anomaly settings
+func normalizeRuleType(rule *models.AlertRule) {
+	if rule.Type == "" {
+		rule.Type = "threshold"
+	}
+	if rule.Type != "anomaly" {
+		return
+	}
+	if rule.Direction == "" {
+		rule.Direction = "above"
+	}
+	if rule.Seasonality == "" {
+		rule.Seasonality = "hour_of_week"
+	}
+}
+
+// validateAnomalyRule checks the settings of an anomaly rule, whose
+// thresholds are numbers of standard deviations
+func validateAnomalyRule(rule *models.AlertRule) error {
+	switch rule.Operator {
+	case "gt", "gte":
+	default:
+		return &ValidationError{Field: "operator", Message: "must be gt or gte for anomaly rules; use direction to flag drops"}
+	}
+	switch rule.Direction {
+	case "", "above", "below", "both":
+	default:
+		return &ValidationError{Field: "direction", Message: "must be one of above, below, both"}
+	}
+	switch rule.Seasonality {
+	case "", "none", "hour_of_day", "hour_of_week":
+	default:
+		return &ValidationError{Field: "seasonality", Message: "must be one of none, hour_of_day, hour_of_week"}
+	}
+	if rule.Window != 0 {
+		return &ValidationError{Field: "window", Message: "cannot be used with anomaly rules"}
+	}
+
+	thresholds := []float64{rule.Threshold}
+	for _, level := range rule.Levels {
+		thresholds = append(thresholds, level.Threshold)
+	}
+	for _, threshold := range thresholds {
+		if math.IsNaN(threshold) || threshold <= 0 || threshold > maxAnomalyDeviations {
+			return &ValidationError{Field: "threshold", Message: fmt.Sprintf("must be between 0 and %d standard deviations", maxAnomalyDeviations)}
+		}
+	}
+	return nil
+}
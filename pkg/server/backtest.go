@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	// maxBacktestRange bounds how far back a single backtest may replay
+	maxBacktestRange = 31 * 24 * time.Hour
+
+	// defaultBacktestRange is replayed when no start time is given
+	defaultBacktestRange = 24 * time.Hour
+)
+
+// Backtest replays stored metrics between from and to through an unsaved
+// rule and returns the alerts it would have raised. Samples from the hour
+// before from are only used as history for window and expression rules.
+func (a *Alerter) Backtest(rule *models.AlertRule, from, to time.Time) (*models.BacktestResult, error) {
+	var agentIDs []string
+	if rule.AgentID != "" {
+		agentIDs = []string{rule.AgentID}
+	} else {
+		agents, err := a.db.GetAgents()
+		if err != nil {
+			return nil, err
+		}
+		for _, agent := range agents {
+			agentIDs = append(agentIDs, agent.ID)
+		}
+	}
+
+	result := &models.BacktestResult{From: from, To: to, Alerts: []*models.BacktestAlert{}}
+	for _, agentID := range agentIDs {
+		var samples []*models.Metrics
+		err := a.db.eachSample(agentID, rule.MetricType, rule.Target, from.Add(-maxExprWindow), to, func(m *models.Metrics) {
+			samples = append(samples, m)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		result.Agents++
+
+		var alerts []*models.BacktestAlert
+		if rule.MetricType == "offline" {
+			alerts = replayOffline(rule, samples, from, to)
+		} else {
+			alerts, err = a.replayRule(rule, samples, from)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range samples {
+			if !m.Timestamp.Before(from) {
+				result.Samples++
+			}
+		}
+
+		for _, alert := range alerts {
+			alert.Suppressed = a.inMaintenance(&models.Alert{AgentID: agentID}, rule, alert.StartedAt)
+			result.Notifications += alert.Notifications
+		}
+		result.Alerts = append(result.Alerts, alerts...)
+	}
+
+	sort.SliceStable(result.Alerts, func(i, j int) bool {
+		return result.Alerts[i].StartedAt.Before(result.Alerts[j].StartedAt)
+	})
+	result.Count = len(result.Alerts)
+	return result, nil
+}
+
+// replayRule feeds an agent's samples, oldest first, through the evaluation
+// CheckMetrics uses. A separate alerter keeps the replayed breach state and
+// baselines away from the live ones, and learns each baseline before the
+// sample it judges.
+func (a *Alerter) replayRule(rule *models.AlertRule, samples []*models.Metrics, from time.Time) ([]*models.BacktestAlert, error) {
+	sim := NewAlerter(a.db, a.config, nil)
+	sim.learnInline = true
+
+	var alerts []*models.BacktestAlert
+	var open *models.BacktestAlert
+	var last time.Time
+	for _, m := range samples {
+		history, oldest := sim.recordSample(m)
+		if m.Timestamp.Before(from) {
+			continue
+		}
+		last = m.Timestamp
+
+		result, value, err := sim.evaluate(rule, m, history, oldest)
+		if err != nil {
+			return nil, err
+		}
+		if result == verdictUnknown {
+			continue
+		}
+		if result == verdictClear {
+			sim.handleAlertClear(rule, m.AgentID)
+			if open != nil {
+				open.EndedAt = m.Timestamp
+				open = nil
+			}
+			continue
+		}
+
+		severity := ruleSeverity(rule, value)
+		if !sim.shouldFire(rule, m.AgentID, severity, m.Timestamp) {
+			continue
+		}
+		if open == nil {
+			open = &models.BacktestAlert{
+				AgentID:   m.AgentID,
+				Severity:  severity,
+				StartedAt: m.Timestamp,
+				Value:     value,
+			}
+			alerts = append(alerts, open)
+		}
+		if severityRanks[severity] > severityRanks[open.Severity] {
+			open.Severity = severity
+		}
+		open.Notifications++
+	}
+
+	if open != nil {
+		open.EndedAt = last
+		open.Ongoing = true
+	}
+	return alerts, nil
+}
+
+// replayOffline finds the gaps in an agent's reports that would have fired
+// an offline rule, judging them the way CheckHeartbeats does
+func replayOffline(rule *models.AlertRule, samples []*models.Metrics, from, to time.Time) []*models.BacktestAlert {
+	deadline := offlineDeadline(rule, estimateReportInterval(samples, fallbackReportInterval))
+
+	var alerts []*models.BacktestAlert
+	add := func(prev, next time.Time, ongoing bool) {
+		started := prev.Add(deadline)
+		if started.Before(from) || !started.Before(to) {
+			return
+		}
+		silence := started.Sub(prev).Seconds()
+		alerts = append(alerts, &models.BacktestAlert{
+			AgentID:       samples[0].AgentID,
+			Severity:      ruleSeverity(rule, silence),
+			StartedAt:     started,
+			EndedAt:       next,
+			Ongoing:       ongoing,
+			Value:         silence,
+			Notifications: 1,
+		})
+	}
+
+	for i := 1; i < len(samples); i++ {
+		if samples[i].Timestamp.Sub(samples[i-1].Timestamp) > deadline {
+			add(samples[i-1].Timestamp, samples[i].Timestamp, false)
+		}
+	}
+	if last := samples[len(samples)-1].Timestamp; to.Sub(last) > deadline {
+		add(last, to, true)
+	}
+	return alerts
+}
+
+// validateBacktestRange checks the period a backtest replays
+func validateBacktestRange(from, to time.Time) error {
+	if !to.After(from) {
+		return &ValidationError{Field: "to", Message: "must be after from"}
+	}
+	if to.Sub(from) > maxBacktestRange {
+		return &ValidationError{Field: "from", Message: fmt.Sprintf("range must not exceed %d days", int(maxBacktestRange.Hours()/24))}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField is the set of values a cron field matches
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+// covers reports whether the field matches every value from min to max
+func (f cronField) covers(min, max int) bool {
+	for v := min; v <= max; v++ {
+		if !f.has(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// latest returns the largest value of the field no greater than v
+func (f cronField) latest(v int) (int, bool) {
+	for ; v >= 0; v-- {
+		if f.has(v) {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// CronSchedule is a parsed five-field cron expression:
+// minute hour day-of-month month day-of-week
+type CronSchedule struct {
+	source string
+
+	minute, hour, dom, month, dow cronField
+
+	// Like cron, when both day fields are restricted a day matches if
+	// either of them does
+	domAny, dowAny bool
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseCronSchedule parses a cron expression such as "30 2 * * sun"
+func ParseCronSchedule(source string) (*CronSchedule, error) {
+	fields := strings.Fields(source)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron schedule must have 5 fields, got %d", len(fields))
+	}
+
+	s := &CronSchedule{source: source}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// 7 is another name for Sunday
+	if s.dow.has(7) {
+		s.dow |= 1
+	}
+	// A field listing every day, such as "*/1" or "1-31", is as
+	// unrestricted as "*"
+	s.domAny = s.dom.covers(1, 31)
+	s.dowAny = s.dow.covers(0, 6)
+
+	return s, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+func parseCronField(field string, min, max int, names map[string]int) (cronField, error) {
+	var result cronField
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseCronValue(part, names)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" means from 5 to the end in steps of 15
+			hi = v
+			if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			result |= 1 << uint(v)
+		}
+	}
+	return result, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// String returns the source expression
+func (s *CronSchedule) String() string {
+	return s.source
+}
+
+// Matches reports whether the schedule fires in the minute containing t
+func (s *CronSchedule) Matches(t time.Time) bool {
+	return s.matchesDay(t) && s.hour.has(t.Hour()) && s.minute.has(t.Minute())
+}
+
+// matchesDay reports whether the schedule fires on the day containing t
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	if !s.month.has(int(t.Month())) {
+		return false
+	}
+
+	domMatch := s.dom.has(t.Day())
+	dowMatch := s.dow.has(int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Previous returns the latest time the schedule fired at or before t, looking
+// back no further than limit. ok is false if it did not fire in that span.
+// Days and hours that cannot match are skipped whole.
+func (s *CronSchedule) Previous(t time.Time, limit time.Duration) (time.Time, bool) {
+	earliest := t.Add(-limit)
+	m := t.Truncate(time.Minute)
+	for !m.Before(earliest) {
+		switch {
+		case !s.matchesDay(m):
+			// Back to the last minute of the day before
+			m = cronBack(m, time.Duration(m.Hour())*time.Hour+time.Duration(m.Minute()+1)*time.Minute)
+		case !s.hour.has(m.Hour()):
+			m = cronBack(m, time.Duration(m.Minute()+1)*time.Minute)
+		default:
+			minute, ok := s.minute.latest(m.Minute())
+			if !ok {
+				m = cronBack(m, time.Duration(m.Minute()+1)*time.Minute)
+				continue
+			}
+			if minute == m.Minute() {
+				return m, true
+			}
+			m = cronBack(m, time.Duration(m.Minute()-minute)*time.Minute)
+		}
+	}
+	return time.Time{}, false
+}
+
+// cronBack moves m back by the wall-clock span d. Across a clock change
+// the wall clock and elapsed time disagree, so it then steps back a single
+// minute instead.
+func cronBack(m time.Time, d time.Duration) time.Time {
+	prev := m.Add(-d)
+	_, before := prev.Zone()
+	_, after := m.Zone()
+	if before != after {
+		return m.Add(-time.Minute)
+	}
+	return prev
+}
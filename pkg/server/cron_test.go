@@ -0,0 +1,166 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		msg    string
+	}{
+		{"", "must have 5 fields, got 0"},
+		{"* * * *", "must have 5 fields, got 4"},
+		{"* * * * * *", "must have 5 fields, got 6"},
+		{"60 * * * *", `minute: "60" is out of range 0-59`},
+		{"* 24 * * *", `hour: "24" is out of range 0-23`},
+		{"* * 0 * *", `day of month: "0" is out of range 1-31`},
+		{"* * 32 * *", `day of month: "32" is out of range 1-31`},
+		{"* * * 13 *", `month: "13" is out of range 1-12`},
+		{"* * * * 8", `day of week: "8" is out of range 0-7`},
+		{"30-10 * * * *", `minute: "30-10" is out of range 0-59`},
+		{"*/0 * * * *", `minute: invalid step in "*/0"`},
+		{"*/x * * * *", `minute: invalid step in "*/x"`},
+		{"1-5/-2 * * * *", `minute: invalid step in "1-5/-2"`},
+		{"x * * * *", `minute: invalid value "x"`},
+		{"1-x * * * *", `minute: invalid value "x"`},
+		{"1,,2 * * * *", `minute: invalid value ""`},
+		{"* * * foo *", `month: invalid value "foo"`},
+		{"* * * * mon-funday", `day of week: invalid value "funday"`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseCronSchedule(tt.source)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("ParseCronSchedule(%q) = %v, want %q", tt.source, err, tt.msg)
+		}
+	}
+}
+
+func TestCronScheduleMatches(t *testing.T) {
+	// 2026-06-15 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		source string
+		t      time.Time
+		want   bool
+	}{
+		{"* * * * *", at(15, 13, 47), true},
+		{"30 2 * * *", at(15, 2, 30), true},
+		{"30 2 * * *", at(15, 2, 31), false},
+
+		// Ranges, steps and lists
+		{"10-20 * * * *", at(15, 0, 20), true},
+		{"10-20 * * * *", at(15, 0, 21), false},
+		{"*/15 * * * *", at(15, 0, 45), true},
+		{"*/15 * * * *", at(15, 0, 50), false},
+		{"5/20 * * * *", at(15, 0, 45), true},
+		{"5/20 * * * *", at(15, 0, 40), false},
+		{"10-40/10 * * * *", at(15, 0, 30), true},
+		{"10-40/10 * * * *", at(15, 0, 50), false},
+		{"0 1,13 * * *", at(15, 13, 0), true},
+		{"0 1,13 * * *", at(15, 12, 0), false},
+		{"0,30 9-17/4 * * *", at(15, 13, 30), true},
+		{"0,30 9-17/4 * * *", at(15, 11, 30), false},
+
+		// Names, case-insensitive, and 7 as Sunday
+		{"0 0 * jun mon", at(15, 0, 0), true},
+		{"0 0 * JUL MON", at(15, 0, 0), false},
+		{"0 0 * * sat,SUN", at(14, 0, 0), true},
+		{"0 0 * * 7", at(14, 0, 0), true},
+		{"0 0 * * 7", at(15, 0, 0), false},
+
+		// With one day field restricted, both must match
+		{"0 0 15 * *", at(15, 0, 0), true},
+		{"0 0 15 * *", at(16, 0, 0), false},
+		{"0 0 * * fri", at(19, 0, 0), true},
+		{"0 0 * * fri", at(15, 0, 0), false},
+		{"0 0 */1 * fri", at(15, 0, 0), false},
+		{"0 0 1-31 * fri", at(19, 0, 0), true},
+		{"0 0 15 * 0-6", at(16, 0, 0), false},
+
+		// With both restricted, either may match
+		{"0 0 1 * fri", at(1, 0, 0), true},
+		{"0 0 1 * fri", at(19, 0, 0), true},
+		{"0 0 1 * fri", at(15, 0, 0), false},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseCronSchedule(tt.source)
+		if err != nil {
+			t.Errorf("ParseCronSchedule(%q): %v", tt.source, err)
+			continue
+		}
+		if got := schedule.Matches(tt.t); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.source, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCronSchedulePrevious(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data unavailable:", err)
+	}
+	lordHowe, err := time.LoadLocation("Australia/Lord_Howe")
+	if err != nil {
+		t.Skip("time zone data unavailable:", err)
+	}
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		source string
+		t      time.Time
+		limit  time.Duration
+		want   time.Time // zero if the schedule did not fire
+	}{
+		{"same minute", "30 2 * * *", utc(2026, 6, 15, 2, 30).Add(45 * time.Second), time.Hour, utc(2026, 6, 15, 2, 30)},
+		{"earlier today", "30 2 * * *", utc(2026, 6, 15, 9, 0), 24 * time.Hour, utc(2026, 6, 15, 2, 30)},
+		{"yesterday", "30 2 * * *", utc(2026, 6, 15, 1, 0), 24 * time.Hour, utc(2026, 6, 14, 2, 30)},
+		{"beyond the limit", "30 2 * * *", utc(2026, 6, 15, 1, 0), 22 * time.Hour, time.Time{}},
+		{"at the limit", "30 2 * * *", utc(2026, 6, 15, 1, 0), 22*time.Hour + 30*time.Minute, utc(2026, 6, 14, 2, 30)},
+		{"latest step", "*/20 8-10 * * *", utc(2026, 6, 15, 12, 0), 24 * time.Hour, utc(2026, 6, 15, 10, 40)},
+		{"last weekday", "0 22 * * fri", utc(2026, 6, 15, 12, 0), 7 * 24 * time.Hour, utc(2026, 6, 12, 22, 0)},
+		{"last month", "0 0 1 * *", utc(2026, 6, 15, 12, 0), 62 * 24 * time.Hour, utc(2026, 6, 1, 0, 0)},
+		{"either day", "0 0 13 * sun", utc(2026, 6, 15, 12, 0), 7 * 24 * time.Hour, utc(2026, 6, 14, 0, 0)},
+		{"leap day", "0 12 29 feb *", utc(2026, 6, 15, 12, 0), 3 * 366 * 24 * time.Hour, utc(2024, 2, 29, 12, 0)},
+
+		// New York springs forward from 02:00 EST to 03:00 EDT on 8 March
+		{"skipped by spring forward", "30 2 * * *", time.Date(2026, 3, 8, 4, 0, 0, 0, newYork), 48 * time.Hour, utc(2026, 3, 7, 7, 30)},
+		{"first minute after spring forward", "0 3 * * *", time.Date(2026, 3, 8, 3, 30, 0, 0, newYork), time.Hour, utc(2026, 3, 8, 7, 0)},
+		{"hourly across spring forward", "59 * * * *", time.Date(2026, 3, 8, 3, 10, 0, 0, newYork), time.Hour, utc(2026, 3, 8, 6, 59)},
+
+		// and falls back from 02:00 EDT to 01:00 EST on 1 November, so
+		// 01:30 happens twice
+		{"second 01:30 after fall back", "30 1 * * *", utc(2026, 11, 1, 6, 45).In(newYork), time.Hour, utc(2026, 11, 1, 6, 30)},
+		{"first 01:30 before fall back", "30 1 * * *", utc(2026, 11, 1, 6, 10).In(newYork), time.Hour, utc(2026, 11, 1, 5, 30)},
+		{"hourly across fall back", "0 * * * *", utc(2026, 11, 1, 6, 50).In(newYork), time.Hour, utc(2026, 11, 1, 6, 0)},
+		{"last minute before fall back", "59 1 * * *", utc(2026, 11, 1, 6, 10).In(newYork), time.Hour, utc(2026, 11, 1, 5, 59)},
+
+		// Lord Howe Island shifts by half an hour, from 02:00 to 02:30 on
+		// 4 October
+		{"half hour spring forward", "45 2 * * *", time.Date(2026, 10, 4, 3, 0, 0, 0, lordHowe), 2 * time.Hour, utc(2026, 10, 3, 15, 45)},
+		{"half hour skipped", "15 2 * * *", time.Date(2026, 10, 4, 3, 0, 0, 0, lordHowe), 2 * time.Hour, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseCronSchedule(tt.source)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		loc := tt.t.Location()
+		got, ok := schedule.Previous(tt.t, tt.limit)
+		if ok != !tt.want.IsZero() || !got.Equal(tt.want) {
+			t.Errorf("%s: %q before %v = %v (%v), want %v", tt.name, tt.source, tt.t, got.In(loc), ok, tt.want.In(loc))
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package server
+
+import "github.com/jyxjjj/Monitor/pkg/models"
+
+// findCustom returns the value of a custom series in a sample
+func findCustom(m *models.Metrics, name string) (models.CustomMetric, bool) {
+	for _, c := range m.Custom {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return models.CustomMetric{}, false
+}
+
+// customView returns a copy of a sample holding only one custom series
+func customView(m *models.Metrics, c models.CustomMetric) *models.Metrics {
+	view := *m
+	view.Custom = []models.CustomMetric{c}
+	return &view
+}
+
+// customValue returns the value of the custom series in a view of one
+func customValue(m *models.Metrics) float64 {
+	if len(m.Custom) == 0 {
+		return 0
+	}
+	return m.Custom[0].Value
+}
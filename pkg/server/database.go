@@ -2,6 +2,7 @@ package server
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -94,9 +95,334 @@ func (d *Database) InitSchema() error {
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := d.addMissingColumns(); err != nil {
+		return err
+	}
+	return d.allowGlobalRules()
+}
+
+// allowGlobalRules makes alert_rules.agent_id nullable on databases created
+// before global rules were stored as NULL. An empty agent_id violated the
+// foreign key to agents.
+func (d *Database) allowGlobalRules() error {
+	nullable, err := d.ruleAgentNullable()
+	if err != nil {
+		return err
+	}
+	if nullable {
+		return nil
+	}
+
+	switch d.driver {
+	case "mysql":
+		if _, err := d.db.Exec(`ALTER TABLE alert_rules MODIFY agent_id VARCHAR(255) NULL`); err != nil {
+			return err
+		}
+	case "postgres":
+		if _, err := d.db.Exec(`ALTER TABLE alert_rules ALTER COLUMN agent_id DROP NOT NULL`); err != nil {
+			return err
+		}
+	default: // sqlite3
+		if err := d.rebuildSQLiteRuleTable(); err != nil {
+			return err
+		}
+	}
+
+	_, err = d.db.Exec(`UPDATE alert_rules SET agent_id = NULL WHERE agent_id = ''`)
+	return err
+}
+
+// ruleAgentNullable reports whether alert_rules.agent_id already allows NULL
+func (d *Database) ruleAgentNullable() (bool, error) {
+	var nullable string
+	var err error
+	switch d.driver {
+	case "mysql":
+		err = d.db.QueryRow(`SELECT is_nullable FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND table_name = 'alert_rules' AND column_name = 'agent_id'`).Scan(&nullable)
+	case "postgres":
+		err = d.db.QueryRow(`SELECT is_nullable FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'alert_rules' AND column_name = 'agent_id'`).Scan(&nullable)
+	default: // sqlite3
+		var notNull bool
+		err = d.db.QueryRow(`SELECT "notnull" FROM pragma_table_info('alert_rules') WHERE name = 'agent_id'`).Scan(&notNull)
+		if !notNull {
+			nullable = "YES"
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return nullable == "YES", nil
+}
+
+// rebuildSQLiteRuleTable recreates alert_rules without NOT NULL on agent_id,
+// as sqlite cannot alter a column in place
+func (d *Database) rebuildSQLiteRuleTable() error {
+	var definition string
+	err := d.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type='table' AND name='alert_rules'`).Scan(&definition)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(definition, "agent_id TEXT NOT NULL") {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	definition = strings.Replace(definition, "agent_id TEXT NOT NULL", "agent_id TEXT NULL", 1)
+	definition = strings.Replace(definition, "alert_rules", "alert_rules_rebuild", 1)
+	for _, stmt := range []string{
+		definition,
+		`INSERT INTO alert_rules_rebuild SELECT * FROM alert_rules`,
+		`DROP TABLE alert_rules`,
+		`ALTER TABLE alert_rules_rebuild RENAME TO alert_rules`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to rebuild alert_rules: %w", err)
+		}
+	}
 	return tx.Commit()
 }
 
+// Migrate brings an already installed database up to date. All tables are
+// created with IF NOT EXISTS, so re-running the schema only adds new tables
+// and columns.
+func (d *Database) Migrate() error {
+	return d.InitSchema()
+}
+
+// schemaColumn is a column added to a table after it was first released
+type schemaColumn struct {
+	table    string
+	column   string
+	sqlite   string
+	mysql    string
+	postgres string
+}
+
+// schemaColumns lists added columns in the order they were introduced
+var schemaColumns = []schemaColumn{
+	{"alert_rules", "expression", "TEXT NOT NULL DEFAULT ''", "VARCHAR(1024) NOT NULL DEFAULT ''", "TEXT NOT NULL DEFAULT ''"},
+	{"alert_rules", "window_seconds", "INTEGER NOT NULL DEFAULT 0", "INT NOT NULL DEFAULT 0", "INTEGER NOT NULL DEFAULT 0"},
+	{"alert_rules", "aggregate", "TEXT NOT NULL DEFAULT ''", "VARCHAR(10) NOT NULL DEFAULT ''", "VARCHAR(10) NOT NULL DEFAULT ''"},
+	{"alert_rules", "breach_ratio", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"agents", "tags", "TEXT NOT NULL DEFAULT '[]'", "VARCHAR(1024) NOT NULL DEFAULT '[]'", "TEXT NOT NULL DEFAULT '[]'"},
+	{"alerts", "suppressed", "INTEGER NOT NULL DEFAULT 0", "TINYINT(1) NOT NULL DEFAULT 0", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"alert_rules", "escalation_policy_id", "INTEGER NOT NULL DEFAULT 0", "BIGINT UNSIGNED NOT NULL DEFAULT 0", "BIGINT NOT NULL DEFAULT 0"},
+	{"alerts", "acknowledged_at", "DATETIME(3) NULL", "DATETIME(3) NULL", "TIMESTAMP(3) NULL"},
+	{"alerts", "acknowledged_by", "TEXT NOT NULL DEFAULT ''", "VARCHAR(255) NOT NULL DEFAULT ''", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"alerts", "escalation_step", "INTEGER NOT NULL DEFAULT 0", "INT NOT NULL DEFAULT 0", "INTEGER NOT NULL DEFAULT 0"},
+	{"alert_rules", "severity", "TEXT NOT NULL DEFAULT 'warning'", "VARCHAR(20) NOT NULL DEFAULT 'warning'", "VARCHAR(20) NOT NULL DEFAULT 'warning'"},
+	{"alert_rules", "levels", "TEXT NOT NULL DEFAULT '[]'", "VARCHAR(1024) NOT NULL DEFAULT '[]'", "TEXT NOT NULL DEFAULT '[]'"},
+	{"alerts", "severity", "TEXT NOT NULL DEFAULT ''", "VARCHAR(20) NOT NULL DEFAULT ''", "VARCHAR(20) NOT NULL DEFAULT ''"},
+	{"alerts", "resolved_at", "DATETIME(3) NULL", "DATETIME(3) NULL", "TIMESTAMP(3) NULL"},
+	{"alert_rules", "rule_type", "TEXT NOT NULL DEFAULT 'threshold'", "VARCHAR(20) NOT NULL DEFAULT 'threshold'", "VARCHAR(20) NOT NULL DEFAULT 'threshold'"},
+	{"alert_rules", "direction", "TEXT NOT NULL DEFAULT ''", "VARCHAR(20) NOT NULL DEFAULT ''", "VARCHAR(20) NOT NULL DEFAULT ''"},
+	{"alert_rules", "seasonality", "TEXT NOT NULL DEFAULT ''", "VARCHAR(20) NOT NULL DEFAULT ''", "VARCHAR(20) NOT NULL DEFAULT ''"},
+	{"alert_rules", "target", "TEXT NOT NULL DEFAULT ''", "VARCHAR(255) NOT NULL DEFAULT ''", "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{"metrics", "disk_read_bps", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "disk_write_bps", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "disk_read_iops", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "disk_write_iops", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "disk_util_percent", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "disk_await_ms", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "network_rx_packets", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "network_tx_packets", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "network_errors", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "network_drops", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "cpu_user", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "cpu_system", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "cpu_iowait", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "cpu_steal", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "cpu_irq", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "cpu_per_core", "TEXT NOT NULL DEFAULT '[]'", "VARCHAR(4096) NOT NULL DEFAULT '[]'", "TEXT NOT NULL DEFAULT '[]'"},
+	{"metrics", "memory_available", "INTEGER NOT NULL DEFAULT 0", "BIGINT UNSIGNED NOT NULL DEFAULT 0", "BIGINT NOT NULL DEFAULT 0"},
+	{"metrics", "memory_cached", "INTEGER NOT NULL DEFAULT 0", "BIGINT UNSIGNED NOT NULL DEFAULT 0", "BIGINT NOT NULL DEFAULT 0"},
+	{"metrics", "memory_buffers", "INTEGER NOT NULL DEFAULT 0", "BIGINT UNSIGNED NOT NULL DEFAULT 0", "BIGINT NOT NULL DEFAULT 0"},
+	{"metrics", "swap_used", "INTEGER NOT NULL DEFAULT 0", "BIGINT UNSIGNED NOT NULL DEFAULT 0", "BIGINT NOT NULL DEFAULT 0"},
+	{"metrics", "swap_total", "INTEGER NOT NULL DEFAULT 0", "BIGINT UNSIGNED NOT NULL DEFAULT 0", "BIGINT NOT NULL DEFAULT 0"},
+	{"metrics", "memory_pressure_some", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "memory_pressure_full", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+	{"metrics", "watches_down", "INTEGER NOT NULL DEFAULT 0", "INT NOT NULL DEFAULT 0", "INTEGER NOT NULL DEFAULT 0"},
+	{"metrics", "watch_restarts", "INTEGER NOT NULL DEFAULT 0", "INT NOT NULL DEFAULT 0", "INTEGER NOT NULL DEFAULT 0"},
+	{"metrics", "temperature_max", "REAL NOT NULL DEFAULT 0", "DOUBLE NOT NULL DEFAULT 0", "DOUBLE PRECISION NOT NULL DEFAULT 0"},
+}
+
+// addMissingColumns adds any columns from schemaColumns the database lacks
+func (d *Database) addMissingColumns() error {
+	for _, c := range schemaColumns {
+		// Probe the column; the query fails if it does not exist
+		rows, err := d.db.Query(fmt.Sprintf("SELECT %s FROM %s WHERE 1=0", c.column, c.table))
+		if err == nil {
+			rows.Close()
+			continue
+		}
+
+		definition := c.sqlite
+		switch d.driver {
+		case "mysql":
+			definition = c.mysql
+		case "postgres":
+			definition = c.postgres
+		}
+
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, definition)
+		if _, err := d.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
+
+// rebind converts '?' placeholders to the '$n' form expected by PostgreSQL
+func (d *Database) rebind(query string) string {
+	if d.driver != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// timeArg formats a time for comparison against stored timestamps. sqlite
+// stores timestamps as local time text, so it gets the same layout
+// GetMetricsHistory uses, converted to local time in case t came from a
+// client in another zone.
+func (d *Database) timeArg(t time.Time) interface{} {
+	if d.driver == "sqlite3" {
+		return t.Local().Format("2006-01-02 15:04:05")
+	}
+	return t
+}
+
+// dbTime scans timestamp columns from any driver. sqlite reports DATETIME(3)
+// columns as text, which database/sql cannot store into a time.Time directly.
+type dbTime struct {
+	time.Time
+}
+
+// Scan implements sql.Scanner
+func (t *dbTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("cannot scan %T into time", value)
+	}
+	return nil
+}
+
+func (t *dbTime) parse(s string) error {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02T15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05",
+	} {
+		if parsed, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse time %q", s)
+}
+
+// dbBool converts a boolean column, which drivers report as bool or integer
+func dbBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case int64:
+		return b != 0
+	case int:
+		return b != 0
+	case []byte:
+		return string(b) == "1" || string(b) == "true"
+	}
+	return false
+}
+
+// insertReturningID executes an INSERT and returns the generated id. The
+// query must use '?' placeholders and must not contain a RETURNING clause.
+func (d *Database) insertReturningID(query string, args ...interface{}) (int, error) {
+	return d.insertReturningIDIn(d.db, query, args...)
+}
+
+// sqlRunner is a database or a transaction to run statements in
+type sqlRunner interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertReturningIDIn is insertReturningID run in a transaction
+func (d *Database) insertReturningIDIn(runner sqlRunner, query string, args ...interface{}) (int, error) {
+	if d.driver == "postgres" {
+		var id int
+		err := runner.QueryRow(d.rebind(query)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := runner.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := result.LastInsertId()
+	return int(id), nil
+}
+
+// jsonList encodes a list for a TEXT column, storing nil as an empty list
+func jsonList(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return "[]"
+	}
+	return string(data)
+}
+
+// jsonObject encodes a map for storage in a text column, storing nil as an
+// empty object
+func jsonObject(v map[string]string) string {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return "{}"
+	}
+	return string(data)
+}
+
+// parseJSONList decodes a list stored by jsonList, ignoring malformed values
+func parseJSONList(s string, v interface{}) {
+	if s == "" {
+		return
+	}
+	json.Unmarshal([]byte(s), v)
+}
+
 // getSQLiteSchema returns SQLite schema with Laravel-style naming
 func (d *Database) getSQLiteSchema() string {
 	return `
@@ -134,7 +460,7 @@ func (d *Database) getSQLiteSchema() string {
 
 	CREATE TABLE IF NOT EXISTS alert_rules (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		agent_id TEXT NOT NULL,
+		agent_id TEXT NULL,
 		metric_type TEXT NOT NULL,
 		threshold REAL NOT NULL,
 		operator TEXT NOT NULL,
@@ -161,6 +487,273 @@ func (d *Database) getSQLiteSchema() string {
 
 	CREATE INDEX IF NOT EXISTS idx_alerts_agent_created ON alerts(agent_id, created_at);
 	CREATE INDEX IF NOT EXISTS idx_alerts_agent_created ON alerts(agent_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS notification_outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		alert_id INTEGER NOT NULL,
+		channel TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME(3) NOT NULL,
+		last_error TEXT NOT NULL,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		updated_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (alert_id) REFERENCES alerts(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_outbox_status_next ON notification_outbox(status, next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS notification_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		notification_id INTEGER NOT NULL,
+		alert_id INTEGER NOT NULL,
+		channel TEXT NOT NULL,
+		attempt INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		error TEXT NOT NULL,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (notification_id) REFERENCES notification_outbox(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_deliveries_alert ON notification_deliveries(alert_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS notification_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		channel TEXT NOT NULL,
+		rule_id INTEGER NOT NULL DEFAULT 0,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		updated_at DATETIME(3) DEFAULT (datetime('now','localtime'))
+	);
+
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		starts_at DATETIME(3),
+		ends_at DATETIME(3),
+		schedule TEXT NOT NULL DEFAULT '',
+		duration INTEGER NOT NULL DEFAULT 0,
+		timezone TEXT NOT NULL DEFAULT '',
+		agent_ids TEXT NOT NULL DEFAULT '[]',
+		tags TEXT NOT NULL DEFAULT '[]',
+		rule_ids TEXT NOT NULL DEFAULT '[]',
+		enabled INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		updated_at DATETIME(3) DEFAULT (datetime('now','localtime'))
+	);
+
+	CREATE TABLE IF NOT EXISTS escalation_policies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		steps TEXT NOT NULL DEFAULT '[]',
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		updated_at DATETIME(3) DEFAULT (datetime('now','localtime'))
+	);
+
+	CREATE TABLE IF NOT EXISTS oncall_schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		channel TEXT NOT NULL,
+		rotations TEXT NOT NULL DEFAULT '[]',
+		overrides TEXT NOT NULL DEFAULT '[]',
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		updated_at DATETIME(3) DEFAULT (datetime('now','localtime'))
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_routes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		severities TEXT NOT NULL DEFAULT '[]',
+		tags TEXT NOT NULL DEFAULT '[]',
+		metric_types TEXT NOT NULL DEFAULT '[]',
+		channels TEXT NOT NULL DEFAULT '[]',
+		continue_matching INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		updated_at DATETIME(3) DEFAULT (datetime('now','localtime'))
+	);
+
+	CREATE TABLE IF NOT EXISTS metric_filesystems (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		mountpoint TEXT NOT NULL,
+		device TEXT NOT NULL,
+		fstype TEXT NOT NULL,
+		used BIGINT NOT NULL,
+		total BIGINT NOT NULL,
+		inodes_used BIGINT NOT NULL DEFAULT 0,
+		inodes_total BIGINT NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_filesystems_agent_mount ON metric_filesystems(agent_id, mountpoint, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_filesystems_metric ON metric_filesystems(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_disks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		device TEXT NOT NULL,
+		read_bps REAL NOT NULL DEFAULT 0,
+		write_bps REAL NOT NULL DEFAULT 0,
+		read_iops REAL NOT NULL DEFAULT 0,
+		write_iops REAL NOT NULL DEFAULT 0,
+		util_percent REAL NOT NULL DEFAULT 0,
+		await_ms REAL NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_disks_agent_device ON metric_disks(agent_id, device, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_disks_metric ON metric_disks(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_interfaces (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		rx_bytes REAL NOT NULL DEFAULT 0,
+		tx_bytes REAL NOT NULL DEFAULT 0,
+		rx_packets REAL NOT NULL DEFAULT 0,
+		tx_packets REAL NOT NULL DEFAULT 0,
+		rx_errors REAL NOT NULL DEFAULT 0,
+		tx_errors REAL NOT NULL DEFAULT 0,
+		rx_drops REAL NOT NULL DEFAULT 0,
+		tx_drops REAL NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_interfaces_agent_name ON metric_interfaces(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_interfaces_metric ON metric_interfaces(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_processes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		pid INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		username TEXT NOT NULL DEFAULT '',
+		cmdline TEXT NOT NULL DEFAULT '',
+		cpu_percent REAL NOT NULL DEFAULT 0,
+		rss INTEGER NOT NULL DEFAULT 0,
+		fds INTEGER NOT NULL DEFAULT 0,
+		threads INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_processes_agent ON metric_processes(agent_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_processes_metric ON metric_processes(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_watches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		up INTEGER NOT NULL DEFAULT 0,
+		processes INTEGER NOT NULL DEFAULT 0,
+		restarts INTEGER NOT NULL DEFAULT 0,
+		total_restarts INTEGER NOT NULL DEFAULT 0,
+		cpu_percent REAL NOT NULL DEFAULT 0,
+		rss INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_watches_agent_name ON metric_watches(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_watches_metric ON metric_watches(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_containers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		container_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		image TEXT NOT NULL DEFAULT '',
+		labels TEXT NOT NULL DEFAULT '{}',
+		cpu_percent REAL NOT NULL DEFAULT 0,
+		memory_used INTEGER NOT NULL DEFAULT 0,
+		memory_limit INTEGER NOT NULL DEFAULT 0,
+		network_rx REAL NOT NULL DEFAULT 0,
+		network_tx REAL NOT NULL DEFAULT 0,
+		disk_read_bps REAL NOT NULL DEFAULT 0,
+		disk_write_bps REAL NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_containers_agent_name ON metric_containers(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_containers_metric ON metric_containers(metric_id);
+
+	CREATE TABLE IF NOT EXISTS container_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		agent_id TEXT NOT NULL,
+		container_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_container_events_agent ON container_events(agent_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS metric_sensors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL DEFAULT '',
+		value REAL NOT NULL DEFAULT 0,
+		high REAL NOT NULL DEFAULT 0,
+		critical REAL NOT NULL DEFAULT 0,
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_sensors_agent_name ON metric_sensors(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_sensors_metric ON metric_sensors(metric_id);
+
+	CREATE TABLE IF NOT EXISTS agent_inventory (
+		agent_id TEXT PRIMARY KEY,
+		hostname TEXT NOT NULL DEFAULT '',
+		os TEXT NOT NULL DEFAULT '',
+		platform TEXT NOT NULL DEFAULT '',
+		platform_version TEXT NOT NULL DEFAULT '',
+		kernel_version TEXT NOT NULL DEFAULT '',
+		arch TEXT NOT NULL DEFAULT '',
+		cpu_model TEXT NOT NULL DEFAULT '',
+		cpu_count INTEGER NOT NULL DEFAULT 0,
+		memory_total INTEGER NOT NULL DEFAULT 0,
+		ip_addresses TEXT NOT NULL DEFAULT '[]',
+		virtualization TEXT NOT NULL DEFAULT '',
+		boot_time DATETIME(3),
+		uptime INTEGER NOT NULL DEFAULT 0,
+		agent_version TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS metric_custom (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		metric_id INTEGER NOT NULL,
+		agent_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		value REAL NOT NULL DEFAULT 0,
+		unit TEXT NOT NULL DEFAULT '',
+		created_at DATETIME(3) DEFAULT (datetime('now','localtime')),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_custom_agent_name ON metric_custom(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_custom_metric ON metric_custom(metric_id);
 	`
 }
 
@@ -202,7 +795,7 @@ func (d *Database) getMySQLSchema() string {
 
 	CREATE TABLE IF NOT EXISTS alert_rules (
 		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
-		agent_id VARCHAR(255) NOT NULL,
+		agent_id VARCHAR(255) NULL,
 		metric_type VARCHAR(50) NOT NULL,
 		threshold DOUBLE NOT NULL,
 		operator VARCHAR(10) NOT NULL,
@@ -229,232 +822,1448 @@ func (d *Database) getMySQLSchema() string {
 		FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
 		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
-	`
-}
 
-// getPostgreSQLSchema returns PostgreSQL schema with Laravel-style naming
-func (d *Database) getPostgreSQLSchema() string {
-	return `
-	CREATE TABLE IF NOT EXISTS agents (
-		id VARCHAR(255) PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		host VARCHAR(255) NOT NULL,
-		last_seen_at TIMESTAMP NOT NULL,
-		status VARCHAR(50) NOT NULL,
-		platform VARCHAR(50) NOT NULL,
-		version VARCHAR(50) NOT NULL,
-		created_at TIMESTAMP(3) NOT NULL,
-		updated_at TIMESTAMP(3) NOT NULL
-	);
+	CREATE TABLE IF NOT EXISTS notification_outbox (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		alert_id BIGINT UNSIGNED NOT NULL,
+		channel VARCHAR(255) NOT NULL,
+		recipient VARCHAR(255) NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME(3) NOT NULL,
+		last_error TEXT NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL,
+		INDEX idx_notification_outbox_status_next (status, next_attempt_at),
+		FOREIGN KEY (alert_id) REFERENCES alerts(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
-	CREATE INDEX IF NOT EXISTS idx_agents_status ON agents(status);
-	CREATE INDEX IF NOT EXISTS idx_agents_last_seen ON agents(last_seen_at);
+	CREATE TABLE IF NOT EXISTS notification_deliveries (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		notification_id BIGINT UNSIGNED NOT NULL,
+		alert_id BIGINT UNSIGNED NOT NULL,
+		channel VARCHAR(255) NOT NULL,
+		attempt INT NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		error TEXT NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_notification_deliveries_alert (alert_id, created_at),
+		FOREIGN KEY (notification_id) REFERENCES notification_outbox(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
-	CREATE TABLE IF NOT EXISTS metrics (
-		id BIGSERIAL PRIMARY KEY,
-		agent_id VARCHAR(255) NOT NULL,
-		cpu_percent DOUBLE PRECISION NOT NULL,
-		cpu_cores INTEGER NOT NULL DEFAULT 1,
-		memory_used BIGINT NOT NULL,
-		memory_total BIGINT NOT NULL,
-		disk_used BIGINT NOT NULL,
-		disk_total BIGINT NOT NULL,
-		network_rx BIGINT NOT NULL,
-		network_tx BIGINT NOT NULL,
-		load_avg_1 DOUBLE PRECISION NOT NULL,
-		load_avg_5 DOUBLE PRECISION NOT NULL,
-		load_avg_15 DOUBLE PRECISION NOT NULL,
+	CREATE TABLE IF NOT EXISTS notification_templates (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		channel VARCHAR(255) NOT NULL,
+		rule_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL,
+		starts_at DATETIME(3) NULL,
+		ends_at DATETIME(3) NULL,
+		schedule VARCHAR(255) NOT NULL DEFAULT '',
+		duration INT NOT NULL DEFAULT 0,
+		timezone VARCHAR(64) NOT NULL DEFAULT '',
+		agent_ids TEXT NOT NULL,
+		tags TEXT NOT NULL,
+		rule_ids TEXT NOT NULL,
+		enabled TINYINT(1) NOT NULL DEFAULT 1,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS escalation_policies (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL,
+		steps TEXT NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS oncall_schedules (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		channel VARCHAR(255) NOT NULL,
+		rotations TEXT NOT NULL,
+		overrides TEXT NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS notification_routes (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		position INT NOT NULL DEFAULT 0,
+		severities TEXT NOT NULL,
+		tags TEXT NOT NULL,
+		metric_types TEXT NOT NULL,
+		channels TEXT NOT NULL,
+		continue_matching TINYINT(1) NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_filesystems (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		mountpoint VARCHAR(255) NOT NULL,
+		device VARCHAR(255) NOT NULL,
+		fstype VARCHAR(64) NOT NULL,
+		used BIGINT UNSIGNED NOT NULL,
+		total BIGINT UNSIGNED NOT NULL,
+		inodes_used BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		inodes_total BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_filesystems_agent_mount (agent_id, mountpoint, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_disks (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		device VARCHAR(255) NOT NULL,
+		read_bps DOUBLE NOT NULL DEFAULT 0,
+		write_bps DOUBLE NOT NULL DEFAULT 0,
+		read_iops DOUBLE NOT NULL DEFAULT 0,
+		write_iops DOUBLE NOT NULL DEFAULT 0,
+		util_percent DOUBLE NOT NULL DEFAULT 0,
+		await_ms DOUBLE NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_disks_agent_device (agent_id, device, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_interfaces (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		rx_bytes DOUBLE NOT NULL DEFAULT 0,
+		tx_bytes DOUBLE NOT NULL DEFAULT 0,
+		rx_packets DOUBLE NOT NULL DEFAULT 0,
+		tx_packets DOUBLE NOT NULL DEFAULT 0,
+		rx_errors DOUBLE NOT NULL DEFAULT 0,
+		tx_errors DOUBLE NOT NULL DEFAULT 0,
+		rx_drops DOUBLE NOT NULL DEFAULT 0,
+		tx_drops DOUBLE NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_interfaces_agent_name (agent_id, name, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_processes (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		pid INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		username VARCHAR(255) NOT NULL DEFAULT '',
+		cmdline VARCHAR(1024) NOT NULL DEFAULT '',
+		cpu_percent DOUBLE NOT NULL DEFAULT 0,
+		rss BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		fds INT NOT NULL DEFAULT 0,
+		threads INT NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_processes_agent (agent_id, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_watches (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		up TINYINT(1) NOT NULL DEFAULT 0,
+		processes INT NOT NULL DEFAULT 0,
+		restarts INT NOT NULL DEFAULT 0,
+		total_restarts INT NOT NULL DEFAULT 0,
+		cpu_percent DOUBLE NOT NULL DEFAULT 0,
+		rss BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		error VARCHAR(1024) NOT NULL DEFAULT '',
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_watches_agent_name (agent_id, name, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_containers (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		container_id VARCHAR(64) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		image VARCHAR(255) NOT NULL DEFAULT '',
+		labels TEXT NOT NULL,
+		cpu_percent DOUBLE NOT NULL DEFAULT 0,
+		memory_used BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		memory_limit BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		network_rx DOUBLE NOT NULL DEFAULT 0,
+		network_tx DOUBLE NOT NULL DEFAULT 0,
+		disk_read_bps DOUBLE NOT NULL DEFAULT 0,
+		disk_write_bps DOUBLE NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_containers_agent_name (agent_id, name, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS container_events (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		agent_id VARCHAR(255) NOT NULL,
+		container_id VARCHAR(64) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		event_type VARCHAR(20) NOT NULL,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_container_events_agent (agent_id, created_at)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_sensors (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		kind VARCHAR(32) NOT NULL DEFAULT '',
+		value DOUBLE NOT NULL DEFAULT 0,
+		high DOUBLE NOT NULL DEFAULT 0,
+		critical DOUBLE NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_sensors_agent_name (agent_id, name, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS agent_inventory (
+		agent_id VARCHAR(255) PRIMARY KEY,
+		hostname VARCHAR(255) NOT NULL DEFAULT '',
+		os VARCHAR(50) NOT NULL DEFAULT '',
+		platform VARCHAR(100) NOT NULL DEFAULT '',
+		platform_version VARCHAR(100) NOT NULL DEFAULT '',
+		kernel_version VARCHAR(255) NOT NULL DEFAULT '',
+		arch VARCHAR(50) NOT NULL DEFAULT '',
+		cpu_model VARCHAR(255) NOT NULL DEFAULT '',
+		cpu_count INT NOT NULL DEFAULT 0,
+		memory_total BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		ip_addresses TEXT NOT NULL,
+		virtualization VARCHAR(50) NOT NULL DEFAULT '',
+		boot_time DATETIME(3) NULL,
+		uptime BIGINT UNSIGNED NOT NULL DEFAULT 0,
+		agent_version VARCHAR(50) NOT NULL DEFAULT '',
+		updated_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+	CREATE TABLE IF NOT EXISTS metric_custom (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		metric_id BIGINT UNSIGNED NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		value DOUBLE NOT NULL DEFAULT 0,
+		unit VARCHAR(32) NOT NULL DEFAULT '',
+		created_at DATETIME(3) NOT NULL,
+		INDEX idx_metric_custom_agent_name (agent_id, name, created_at),
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+}
+
+// getPostgreSQLSchema returns PostgreSQL schema with Laravel-style naming
+func (d *Database) getPostgreSQLSchema() string {
+	return `
+	CREATE TABLE IF NOT EXISTS agents (
+		id VARCHAR(255) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		host VARCHAR(255) NOT NULL,
+		last_seen_at TIMESTAMP NOT NULL,
+		status VARCHAR(50) NOT NULL,
+		platform VARCHAR(50) NOT NULL,
+		version VARCHAR(50) NOT NULL,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_agents_status ON agents(status);
+	CREATE INDEX IF NOT EXISTS idx_agents_last_seen ON agents(last_seen_at);
+
+	CREATE TABLE IF NOT EXISTS metrics (
+		id BIGSERIAL PRIMARY KEY,
+		agent_id VARCHAR(255) NOT NULL,
+		cpu_percent DOUBLE PRECISION NOT NULL,
+		cpu_cores INTEGER NOT NULL DEFAULT 1,
+		memory_used BIGINT NOT NULL,
+		memory_total BIGINT NOT NULL,
+		disk_used BIGINT NOT NULL,
+		disk_total BIGINT NOT NULL,
+		network_rx BIGINT NOT NULL,
+		network_tx BIGINT NOT NULL,
+		load_avg_1 DOUBLE PRECISION NOT NULL,
+		load_avg_5 DOUBLE PRECISION NOT NULL,
+		load_avg_15 DOUBLE PRECISION NOT NULL,
 		created_at TIMESTAMP(3) NOT NULL,
 		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_metrics_agent_created ON metrics(agent_id, created_at);
 
-	CREATE TABLE IF NOT EXISTS alert_rules (
-		id BIGSERIAL PRIMARY KEY,
-		agent_id VARCHAR(255) NOT NULL,
-		metric_type VARCHAR(50) NOT NULL,
-		threshold DOUBLE PRECISION NOT NULL,
-		operator VARCHAR(10) NOT NULL,
-		duration INTEGER NOT NULL,
-		enabled BOOLEAN NOT NULL DEFAULT TRUE,
-		description TEXT NOT NULL,
-		created_at TIMESTAMP(3) NOT NULL,
-		updated_at TIMESTAMP(3) NOT NULL,
-		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
-	);
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id BIGSERIAL PRIMARY KEY,
+		agent_id VARCHAR(255) NULL,
+		metric_type VARCHAR(50) NOT NULL,
+		threshold DOUBLE PRECISION NOT NULL,
+		operator VARCHAR(10) NOT NULL,
+		duration INTEGER NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		description TEXT NOT NULL,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alert_rules_enabled ON alert_rules(enabled);
+
+	CREATE TABLE IF NOT EXISTS alerts (
+		id BIGSERIAL PRIMARY KEY,
+		rule_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		message TEXT NOT NULL,
+		value DOUBLE PRECISION NOT NULL,
+		resolved BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
+		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alerts_agent_created ON alerts(agent_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_alerts_resolved ON alerts(resolved);
+
+	CREATE TABLE IF NOT EXISTS notification_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		alert_id BIGINT NOT NULL,
+		channel VARCHAR(255) NOT NULL,
+		recipient VARCHAR(255) NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP(3) NOT NULL,
+		last_error TEXT NOT NULL,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (alert_id) REFERENCES alerts(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_outbox_status_next ON notification_outbox(status, next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS notification_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		notification_id BIGINT NOT NULL,
+		alert_id BIGINT NOT NULL,
+		channel VARCHAR(255) NOT NULL,
+		attempt INTEGER NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		error TEXT NOT NULL,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (notification_id) REFERENCES notification_outbox(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_deliveries_alert ON notification_deliveries(alert_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS notification_templates (
+		id BIGSERIAL PRIMARY KEY,
+		channel VARCHAR(255) NOT NULL,
+		rule_id BIGINT NOT NULL DEFAULT 0,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS maintenance_windows (
+		id BIGSERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		starts_at TIMESTAMP(3),
+		ends_at TIMESTAMP(3),
+		schedule VARCHAR(255) NOT NULL DEFAULT '',
+		duration INTEGER NOT NULL DEFAULT 0,
+		timezone VARCHAR(64) NOT NULL DEFAULT '',
+		agent_ids TEXT NOT NULL DEFAULT '[]',
+		tags TEXT NOT NULL DEFAULT '[]',
+		rule_ids TEXT NOT NULL DEFAULT '[]',
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS escalation_policies (
+		id BIGSERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		steps TEXT NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS oncall_schedules (
+		id BIGSERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		channel VARCHAR(255) NOT NULL,
+		rotations TEXT NOT NULL DEFAULT '[]',
+		overrides TEXT NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_routes (
+		id BIGSERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		severities TEXT NOT NULL DEFAULT '[]',
+		tags TEXT NOT NULL DEFAULT '[]',
+		metric_types TEXT NOT NULL DEFAULT '[]',
+		channels TEXT NOT NULL DEFAULT '[]',
+		continue_matching BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP(3) NOT NULL,
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS metric_filesystems (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		mountpoint VARCHAR(255) NOT NULL,
+		device VARCHAR(255) NOT NULL,
+		fstype VARCHAR(64) NOT NULL,
+		used BIGINT NOT NULL,
+		total BIGINT NOT NULL,
+		inodes_used BIGINT NOT NULL DEFAULT 0,
+		inodes_total BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_filesystems_agent_mount ON metric_filesystems(agent_id, mountpoint, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_filesystems_metric ON metric_filesystems(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_disks (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		device VARCHAR(255) NOT NULL,
+		read_bps DOUBLE PRECISION NOT NULL DEFAULT 0,
+		write_bps DOUBLE PRECISION NOT NULL DEFAULT 0,
+		read_iops DOUBLE PRECISION NOT NULL DEFAULT 0,
+		write_iops DOUBLE PRECISION NOT NULL DEFAULT 0,
+		util_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+		await_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_disks_agent_device ON metric_disks(agent_id, device, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_disks_metric ON metric_disks(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_interfaces (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		rx_bytes DOUBLE PRECISION NOT NULL DEFAULT 0,
+		tx_bytes DOUBLE PRECISION NOT NULL DEFAULT 0,
+		rx_packets DOUBLE PRECISION NOT NULL DEFAULT 0,
+		tx_packets DOUBLE PRECISION NOT NULL DEFAULT 0,
+		rx_errors DOUBLE PRECISION NOT NULL DEFAULT 0,
+		tx_errors DOUBLE PRECISION NOT NULL DEFAULT 0,
+		rx_drops DOUBLE PRECISION NOT NULL DEFAULT 0,
+		tx_drops DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_interfaces_agent_name ON metric_interfaces(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_interfaces_metric ON metric_interfaces(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_processes (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		pid INTEGER NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		username VARCHAR(255) NOT NULL DEFAULT '',
+		cmdline VARCHAR(1024) NOT NULL DEFAULT '',
+		cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+		rss BIGINT NOT NULL DEFAULT 0,
+		fds INTEGER NOT NULL DEFAULT 0,
+		threads INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_processes_agent ON metric_processes(agent_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_processes_metric ON metric_processes(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_watches (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		up BOOLEAN NOT NULL DEFAULT FALSE,
+		processes INTEGER NOT NULL DEFAULT 0,
+		restarts INTEGER NOT NULL DEFAULT 0,
+		total_restarts INTEGER NOT NULL DEFAULT 0,
+		cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+		rss BIGINT NOT NULL DEFAULT 0,
+		error VARCHAR(1024) NOT NULL DEFAULT '',
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_watches_agent_name ON metric_watches(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_watches_metric ON metric_watches(metric_id);
+
+	CREATE TABLE IF NOT EXISTS metric_containers (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		container_id VARCHAR(64) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		image VARCHAR(255) NOT NULL DEFAULT '',
+		labels TEXT NOT NULL DEFAULT '{}',
+		cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+		memory_used BIGINT NOT NULL DEFAULT 0,
+		memory_limit BIGINT NOT NULL DEFAULT 0,
+		network_rx DOUBLE PRECISION NOT NULL DEFAULT 0,
+		network_tx DOUBLE PRECISION NOT NULL DEFAULT 0,
+		disk_read_bps DOUBLE PRECISION NOT NULL DEFAULT 0,
+		disk_write_bps DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_containers_agent_name ON metric_containers(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_containers_metric ON metric_containers(metric_id);
+
+	CREATE TABLE IF NOT EXISTS container_events (
+		id BIGSERIAL PRIMARY KEY,
+		agent_id VARCHAR(255) NOT NULL,
+		container_id VARCHAR(64) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		event_type VARCHAR(20) NOT NULL,
+		created_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_container_events_agent ON container_events(agent_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS metric_sensors (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		kind VARCHAR(32) NOT NULL DEFAULT '',
+		value DOUBLE PRECISION NOT NULL DEFAULT 0,
+		high DOUBLE PRECISION NOT NULL DEFAULT 0,
+		critical DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_sensors_agent_name ON metric_sensors(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_sensors_metric ON metric_sensors(metric_id);
+
+	CREATE TABLE IF NOT EXISTS agent_inventory (
+		agent_id VARCHAR(255) PRIMARY KEY,
+		hostname VARCHAR(255) NOT NULL DEFAULT '',
+		os VARCHAR(50) NOT NULL DEFAULT '',
+		platform VARCHAR(100) NOT NULL DEFAULT '',
+		platform_version VARCHAR(100) NOT NULL DEFAULT '',
+		kernel_version VARCHAR(255) NOT NULL DEFAULT '',
+		arch VARCHAR(50) NOT NULL DEFAULT '',
+		cpu_model VARCHAR(255) NOT NULL DEFAULT '',
+		cpu_count INTEGER NOT NULL DEFAULT 0,
+		memory_total BIGINT NOT NULL DEFAULT 0,
+		ip_addresses TEXT NOT NULL DEFAULT '[]',
+		virtualization VARCHAR(50) NOT NULL DEFAULT '',
+		boot_time TIMESTAMP(3),
+		uptime BIGINT NOT NULL DEFAULT 0,
+		agent_version VARCHAR(50) NOT NULL DEFAULT '',
+		updated_at TIMESTAMP(3) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS metric_custom (
+		id BIGSERIAL PRIMARY KEY,
+		metric_id BIGINT NOT NULL,
+		agent_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		value DOUBLE PRECISION NOT NULL DEFAULT 0,
+		unit VARCHAR(32) NOT NULL DEFAULT '',
+		created_at TIMESTAMP(3) NOT NULL,
+		FOREIGN KEY (metric_id) REFERENCES metrics(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metric_custom_agent_name ON metric_custom(agent_id, name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_metric_custom_metric ON metric_custom(metric_id);
+	`
+}
+
+// SaveMetrics saves metrics to database, along with the usage of each
+// filesystem reported with them. A sample is stored whole or not at all.
+func (d *Database) SaveMetrics(m *models.Metrics) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	metricID, err := d.insertReturningIDIn(tx, `
+		INSERT INTO metrics (`+metricColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.AgentID, m.CPUPercent, m.CPUCores, m.MemoryUsed, m.MemoryTotal,
+		m.DiskUsed, m.DiskTotal, m.NetworkRx, m.NetworkTx,
+		m.LoadAvg1, m.LoadAvg5, m.LoadAvg15, m.Timestamp,
+		m.DiskReadBps, m.DiskWriteBps, m.DiskReadIOPS, m.DiskWriteIOPS, m.DiskUtilPercent, m.DiskAwaitMs,
+		m.NetworkRxPackets, m.NetworkTxPackets, m.NetworkErrors, m.NetworkDrops,
+		m.CPUUser, m.CPUSystem, m.CPUIowait, m.CPUSteal, m.CPUIrq, jsonList(m.CPUPerCore),
+		m.MemoryAvailable, m.MemoryCached, m.MemoryBuffers, m.SwapUsed, m.SwapTotal,
+		m.MemoryPressureSome, m.MemoryPressureFull, m.WatchesDown, m.WatchRestarts, m.TemperatureMax,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, fs := range m.Filesystems {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_filesystems (metric_id, agent_id, mountpoint, device, fstype,
+				used, total, inodes_used, inodes_total, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, fs.Mountpoint, fs.Device, fs.FSType,
+			fs.Used, fs.Total, fs.InodesUsed, fs.InodesTotal, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, disk := range m.Disks {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_disks (metric_id, agent_id, device, read_bps, write_bps,
+				read_iops, write_iops, util_percent, await_ms, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, disk.Device, disk.ReadBps, disk.WriteBps,
+			disk.ReadIOPS, disk.WriteIOPS, disk.UtilPercent, disk.AwaitMs, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, iface := range m.Interfaces {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_interfaces (metric_id, agent_id, name, rx_bytes, tx_bytes,
+				rx_packets, tx_packets, rx_errors, tx_errors, rx_drops, tx_drops, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, iface.Name, iface.RxBytes, iface.TxBytes,
+			iface.RxPackets, iface.TxPackets, iface.RxErrors, iface.TxErrors, iface.RxDrops, iface.TxDrops, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, w := range m.Watches {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_watches (metric_id, agent_id, name, up, processes,
+				restarts, total_restarts, cpu_percent, rss, error, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, w.Name, w.Up, w.Processes,
+			w.Restarts, w.TotalRestarts, w.CPUPercent, w.RSS, w.Error, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range m.Sensors {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_sensors (metric_id, agent_id, name, kind, value, high, critical, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, s.Name, s.Kind, s.Value, s.High, s.Critical, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range m.Custom {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_custom (metric_id, agent_id, name, value, unit, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, c.Name, c.Value, c.Unit, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range m.Containers {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_containers (metric_id, agent_id, container_id, name, image, labels,
+				cpu_percent, memory_used, memory_limit, network_rx, network_tx,
+				disk_read_bps, disk_write_bps, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, c.ID, c.Name, c.Image, jsonObject(c.Labels),
+			c.CPUPercent, c.MemoryUsed, c.MemoryLimit, c.NetworkRx, c.NetworkTx,
+			c.DiskReadBps, c.DiskWriteBps, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range m.ContainerEvents {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO container_events (agent_id, container_id, name, event_type, created_at)
+			VALUES (?, ?, ?, ?, ?)`),
+			m.AgentID, e.ContainerID, e.Name, e.Type, e.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if len(m.Processes) > 0 {
+		if err := d.saveProcesses(tx, metricID, m); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// metricColumns are the columns of the metrics table scanned by scanMetric
+const metricColumns = `agent_id, cpu_percent, cpu_cores, memory_used, memory_total,
+	disk_used, disk_total, network_rx, network_tx, load_avg_1, load_avg_5, load_avg_15, created_at,
+	disk_read_bps, disk_write_bps, disk_read_iops, disk_write_iops, disk_util_percent, disk_await_ms,
+	network_rx_packets, network_tx_packets, network_errors, network_drops,
+	cpu_user, cpu_system, cpu_iowait, cpu_steal, cpu_irq, cpu_per_core,
+	memory_available, memory_cached, memory_buffers, swap_used, swap_total,
+	memory_pressure_some, memory_pressure_full, watches_down, watch_restarts, temperature_max`
+
+// scanMetric scans a row selected with metricColumns
+func scanMetric(scan func(dest ...interface{}) error) (*models.Metrics, error) {
+	m := &models.Metrics{}
+	var created dbTime
+	var perCore string
+	err := scan(&m.AgentID, &m.CPUPercent, &m.CPUCores, &m.MemoryUsed, &m.MemoryTotal,
+		&m.DiskUsed, &m.DiskTotal, &m.NetworkRx, &m.NetworkTx,
+		&m.LoadAvg1, &m.LoadAvg5, &m.LoadAvg15, &created,
+		&m.DiskReadBps, &m.DiskWriteBps, &m.DiskReadIOPS, &m.DiskWriteIOPS, &m.DiskUtilPercent, &m.DiskAwaitMs,
+		&m.NetworkRxPackets, &m.NetworkTxPackets, &m.NetworkErrors, &m.NetworkDrops,
+		&m.CPUUser, &m.CPUSystem, &m.CPUIowait, &m.CPUSteal, &m.CPUIrq, &perCore,
+		&m.MemoryAvailable, &m.MemoryCached, &m.MemoryBuffers, &m.SwapUsed, &m.SwapTotal,
+		&m.MemoryPressureSome, &m.MemoryPressureFull, &m.WatchesDown, &m.WatchRestarts, &m.TemperatureMax)
+	if err != nil {
+		return nil, err
+	}
+	m.Timestamp = created.Time
+	parseJSONList(perCore, &m.CPUPerCore)
+	return m, nil
+}
+
+// UpdateAgent updates or inserts agent information
+func (d *Database) UpdateAgent(agent *models.Agent) error {
+	now := time.Now()
+	tags := jsonList(agent.Tags)
+
+	// Use UPSERT pattern based on driver
+	switch d.driver {
+	case "mysql":
+		_, err := d.db.Exec(`
+			INSERT INTO agents (id, name, host, last_seen_at, status, platform, version, tags, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				name=VALUES(name), host=VALUES(host), last_seen_at=VALUES(last_seen_at),
+				status=VALUES(status), platform=VALUES(platform), version=VALUES(version), tags=VALUES(tags), updated_at=VALUES(updated_at)`,
+			agent.ID, agent.Name, agent.Host, agent.LastSeen, agent.Status, agent.Platform, agent.Version, tags, now, now,
+		)
+		return err
+	case "postgres":
+		_, err := d.db.Exec(`
+			INSERT INTO agents (id, name, host, last_seen_at, status, platform, version, tags, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (id) DO UPDATE SET
+				name=EXCLUDED.name, host=EXCLUDED.host, last_seen_at=EXCLUDED.last_seen_at,
+				status=EXCLUDED.status, platform=EXCLUDED.platform, version=EXCLUDED.version, tags=EXCLUDED.tags, updated_at=EXCLUDED.updated_at`,
+			agent.ID, agent.Name, agent.Host, agent.LastSeen, agent.Status, agent.Platform, agent.Version, tags, now, now,
+		)
+		return err
+	default: // sqlite3
+		_, err := d.db.Exec(`
+			INSERT OR REPLACE INTO agents (id, name, host, last_seen_at, status, platform, version, tags, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			agent.ID, agent.Name, agent.Host, agent.LastSeen, agent.Status, agent.Platform, agent.Version, tags, now, now,
+		)
+		return err
+	}
+}
+
+// GetAgents retrieves all agents
+func (d *Database) GetAgents() ([]*models.Agent, error) {
+	rows, err := d.db.Query(`
+		SELECT id, name, host, last_seen_at, status, platform, version, tags FROM agents
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []*models.Agent
+	for rows.Next() {
+		agent := &models.Agent{}
+		var lastSeen dbTime
+		var tags string
+		err := rows.Scan(&agent.ID, &agent.Name, &agent.Host, &lastSeen,
+			&agent.Status, &agent.Platform, &agent.Version, &tags)
+		if err != nil {
+			return nil, err
+		}
+		agent.LastSeen = lastSeen.Time
+		parseJSONList(tags, &agent.Tags)
+		agents = append(agents, agent)
+	}
+
+	return agents, nil
+}
+
+// UpdateAgentStatus updates the status of an agent
+func (d *Database) UpdateAgentStatus(id, status string) error {
+	_, err := d.db.Exec(d.rebind(`UPDATE agents SET status=?, updated_at=? WHERE id=?`), status, time.Now(), id)
+	return err
+}
+
+// GetAgent retrieves a single agent, returning nil if it does not exist
+func (d *Database) GetAgent(id string) (*models.Agent, error) {
+	agent := &models.Agent{}
+	var lastSeen dbTime
+	var tags string
+	err := d.db.QueryRow(d.rebind(`
+		SELECT id, name, host, last_seen_at, status, platform, version, tags FROM agents
+		WHERE id = ?`), id).Scan(&agent.ID, &agent.Name, &agent.Host, &lastSeen,
+		&agent.Status, &agent.Platform, &agent.Version, &tags)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	agent.LastSeen = lastSeen.Time
+	parseJSONList(tags, &agent.Tags)
+	return agent, nil
+}
+
+// GetMetricsHistory retrieves metrics history for an agent
+func (d *Database) GetMetricsHistory(agentID string, since time.Time) ([]*models.Metrics, error) {
+	// Some drivers/store formats (e.g. sqlite's CURRENT_TIMESTAMP) store timestamps
+	// as 'YYYY-MM-DD HH:MM:SS'. To ensure comparisons work reliably, format the
+	// 'since' parameter appropriately for sqlite. For other drivers we pass the
+	// time.Time value directly.
+	var sinceParam interface{} = since
+	var query string
+	// Use local formatted string for sqlite; other drivers can accept time.Time
+	var layout = "2006-01-02 15:04:05"
+	if d.driver == "sqlite3" {
+		sinceParam = since.Format(layout)
+		query = `
+		SELECT ` + metricColumns + `
+		FROM metrics
+		WHERE agent_id = ? AND created_at >= ?
+		ORDER BY created_at DESC
+		LIMIT 1000
+	`
+	} else {
+		query = `
+		SELECT ` + metricColumns + `
+		FROM metrics
+		WHERE agent_id = ? AND created_at >= ?
+		ORDER BY created_at DESC
+		LIMIT 1000
+	`
+	}
+
+	rows, err := d.db.Query(query, agentID, sinceParam)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []*models.Metrics
+	for rows.Next() {
+		m, err := scanMetric(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, nil
+}
+
+// EachMetric calls fn with each of an agent's metrics between from and to,
+// oldest first, without holding them all in memory
+func (d *Database) EachMetric(agentID string, from, to time.Time, fn func(*models.Metrics)) error {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+metricColumns+`
+		FROM metrics
+		WHERE agent_id = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC
+	`), agentID, d.timeArg(from), d.timeArg(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMetric(rows.Scan)
+		if err != nil {
+			return err
+		}
+		fn(m)
+	}
+	return rows.Err()
+}
+
+// GetLatestFilesystems retrieves the filesystems reported with an agent's
+// newest metrics
+func (d *Database) GetLatestFilesystems(agentID string) ([]models.FilesystemMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT mountpoint, device, fstype, used, total, inodes_used, inodes_total
+		FROM metric_filesystems
+		WHERE metric_id = (SELECT MAX(metric_id) FROM metric_filesystems WHERE agent_id = ?)
+		ORDER BY mountpoint
+	`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	filesystems := []models.FilesystemMetrics{}
+	for rows.Next() {
+		var fs models.FilesystemMetrics
+		err := rows.Scan(&fs.Mountpoint, &fs.Device, &fs.FSType, &fs.Used, &fs.Total,
+			&fs.InodesUsed, &fs.InodesTotal)
+		if err != nil {
+			return nil, err
+		}
+		filesystems = append(filesystems, fs)
+	}
+	return filesystems, rows.Err()
+}
+
+// EachFilesystemSample calls fn with the usage of one of an agent's
+// filesystems between from and to, oldest first. Each sample is a view of
+// the metrics with the disk fields taken from the filesystem.
+func (d *Database) EachFilesystemSample(agentID, mountpoint string, from, to time.Time, fn func(*models.Metrics)) error {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT device, fstype, used, total, inodes_used, inodes_total, created_at
+		FROM metric_filesystems
+		WHERE agent_id = ? AND mountpoint = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC
+	`), agentID, mountpoint, d.timeArg(from), d.timeArg(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		fs := models.FilesystemMetrics{Mountpoint: mountpoint}
+		var created dbTime
+		err := rows.Scan(&fs.Device, &fs.FSType, &fs.Used, &fs.Total,
+			&fs.InodesUsed, &fs.InodesTotal, &created)
+		if err != nil {
+			return err
+		}
+		fn(filesystemView(&models.Metrics{AgentID: agentID, Timestamp: created.Time}, fs))
+	}
+	return rows.Err()
+}
+
+// diskColumns are the columns of metric_disks scanned by scanDisk
+const diskColumns = `device, read_bps, write_bps, read_iops, write_iops, util_percent, await_ms`
+
+func scanDisk(scan func(dest ...interface{}) error, extra ...interface{}) (models.DiskIOMetrics, error) {
+	var disk models.DiskIOMetrics
+	dest := []interface{}{&disk.Device, &disk.ReadBps, &disk.WriteBps,
+		&disk.ReadIOPS, &disk.WriteIOPS, &disk.UtilPercent, &disk.AwaitMs}
+	err := scan(append(dest, extra...)...)
+	return disk, err
+}
+
+// GetLatestDisks retrieves the disk I/O reported with an agent's newest metrics
+func (d *Database) GetLatestDisks(agentID string) ([]models.DiskIOMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+diskColumns+`
+		FROM metric_disks
+		WHERE metric_id = (SELECT MAX(metric_id) FROM metric_disks WHERE agent_id = ?)
+		ORDER BY device
+	`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disks := []models.DiskIOMetrics{}
+	for rows.Next() {
+		disk, err := scanDisk(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, disk)
+	}
+	return disks, rows.Err()
+}
+
+// EachDiskSample calls fn with the disk I/O of an agent between from and
+// to, oldest first. Each sample is a view of the metrics with the disk I/O
+// fields taken from device, or from all devices if device is empty.
+func (d *Database) EachDiskSample(agentID, device string, from, to time.Time, fn func(*models.Metrics)) error {
+	query := `SELECT ` + diskColumns + `, metric_id, created_at
+		FROM metric_disks
+		WHERE agent_id = ? AND created_at >= ? AND created_at < ?`
+	args := []interface{}{agentID, d.timeArg(from), d.timeArg(to)}
+	if device != "" {
+		query += ` AND device = ?`
+		args = append(args, device)
+	}
+	rows, err := d.db.Query(d.rebind(query+` ORDER BY created_at ASC, metric_id ASC`), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Rows of the same report are adjacent and make up one sample
+	var sample *models.Metrics
+	lastID := -1
+	for rows.Next() {
+		var metricID int
+		var created dbTime
+		disk, err := scanDisk(rows.Scan, &metricID, &created)
+		if err != nil {
+			return err
+		}
+		if metricID != lastID {
+			if sample != nil {
+				fn(diskView(sample, sample.Disks))
+			}
+			sample = &models.Metrics{AgentID: agentID, Timestamp: created.Time}
+			lastID = metricID
+		}
+		sample.Disks = append(sample.Disks, disk)
+	}
+	if sample != nil {
+		fn(diskView(sample, sample.Disks))
+	}
+	return rows.Err()
+}
+
+// interfaceColumns are the columns of metric_interfaces scanned by scanInterface
+const interfaceColumns = `name, rx_bytes, tx_bytes, rx_packets, tx_packets, rx_errors, tx_errors, rx_drops, tx_drops`
+
+func scanInterface(scan func(dest ...interface{}) error, extra ...interface{}) (models.InterfaceMetrics, error) {
+	var iface models.InterfaceMetrics
+	dest := []interface{}{&iface.Name, &iface.RxBytes, &iface.TxBytes, &iface.RxPackets, &iface.TxPackets,
+		&iface.RxErrors, &iface.TxErrors, &iface.RxDrops, &iface.TxDrops}
+	err := scan(append(dest, extra...)...)
+	return iface, err
+}
+
+// GetLatestInterfaces retrieves the traffic of each interface reported with
+// an agent's newest metrics
+func (d *Database) GetLatestInterfaces(agentID string) ([]models.InterfaceMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+interfaceColumns+`
+		FROM metric_interfaces
+		WHERE metric_id = (SELECT MAX(metric_id) FROM metric_interfaces WHERE agent_id = ?)
+		ORDER BY name
+	`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	interfaces := []models.InterfaceMetrics{}
+	for rows.Next() {
+		iface, err := scanInterface(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		interfaces = append(interfaces, iface)
+	}
+	return interfaces, rows.Err()
+}
+
+// EachInterfaceSample calls fn with the traffic of one of an agent's
+// interfaces between from and to, oldest first. Each sample is a view of the
+// metrics with the network fields taken from the interface.
+func (d *Database) EachInterfaceSample(agentID, name string, from, to time.Time, fn func(*models.Metrics)) error {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+interfaceColumns+`, created_at
+		FROM metric_interfaces
+		WHERE agent_id = ? AND name = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC
+	`), agentID, name, d.timeArg(from), d.timeArg(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var created dbTime
+		iface, err := scanInterface(rows.Scan, &created)
+		if err != nil {
+			return err
+		}
+		fn(interfaceView(&models.Metrics{AgentID: agentID, Timestamp: created.Time}, iface))
+	}
+	return rows.Err()
+}
+
+// watchColumns are the columns of metric_watches scanned by scanWatch
+const watchColumns = `name, up, processes, restarts, total_restarts, cpu_percent, rss, error`
+
+func scanWatch(scan func(dest ...interface{}) error, extra ...interface{}) (models.WatchMetrics, error) {
+	var w models.WatchMetrics
+	var up interface{}
+	dest := []interface{}{&w.Name, &up, &w.Processes, &w.Restarts, &w.TotalRestarts,
+		&w.CPUPercent, &w.RSS, &w.Error}
+	err := scan(append(dest, extra...)...)
+	w.Up = dbBool(up)
+	return w, err
+}
+
+// GetLatestWatches retrieves the state of each watched process and unit
+// reported with an agent's newest metrics
+func (d *Database) GetLatestWatches(agentID string) ([]models.WatchMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+watchColumns+`
+		FROM metric_watches
+		WHERE metric_id = (SELECT MAX(metric_id) FROM metric_watches WHERE agent_id = ?)
+		ORDER BY name
+	`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	watches := []models.WatchMetrics{}
+	for rows.Next() {
+		w, err := scanWatch(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		watches = append(watches, w)
+	}
+	return watches, rows.Err()
+}
+
+// EachWatchSample calls fn with the state of one of an agent's watches
+// between from and to, oldest first. Each sample is a view of the metrics
+// with the watch fields taken from the watch.
+func (d *Database) EachWatchSample(agentID, name string, from, to time.Time, fn func(*models.Metrics)) error {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+watchColumns+`, created_at
+		FROM metric_watches
+		WHERE agent_id = ? AND name = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC
+	`), agentID, name, d.timeArg(from), d.timeArg(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var created dbTime
+		w, err := scanWatch(rows.Scan, &created)
+		if err != nil {
+			return err
+		}
+		fn(watchView(&models.Metrics{AgentID: agentID, Timestamp: created.Time}, w))
+	}
+	return rows.Err()
+}
+
+// sensorColumns are the columns of metric_sensors scanned by scanSensor
+const sensorColumns = `name, kind, value, high, critical`
 
-	CREATE INDEX IF NOT EXISTS idx_alert_rules_enabled ON alert_rules(enabled);
+func scanSensor(scan func(dest ...interface{}) error, extra ...interface{}) (models.SensorMetrics, error) {
+	var s models.SensorMetrics
+	dest := []interface{}{&s.Name, &s.Kind, &s.Value, &s.High, &s.Critical}
+	err := scan(append(dest, extra...)...)
+	return s, err
+}
 
-	CREATE TABLE IF NOT EXISTS alerts (
-		id BIGSERIAL PRIMARY KEY,
-		rule_id BIGINT NOT NULL,
-		agent_id VARCHAR(255) NOT NULL,
-		message TEXT NOT NULL,
-		value DOUBLE PRECISION NOT NULL,
-		resolved BOOLEAN NOT NULL DEFAULT FALSE,
-		created_at TIMESTAMP(3) NOT NULL,
-		updated_at TIMESTAMP(3) NOT NULL,
-		FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
-		FOREIGN KEY (agent_id) REFERENCES agents(id) ON DELETE CASCADE
-	);
+// GetLatestSensors retrieves the reading of each hardware sensor reported
+// with an agent's newest metrics
+func (d *Database) GetLatestSensors(agentID string) ([]models.SensorMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+sensorColumns+`
+		FROM metric_sensors
+		WHERE metric_id = (SELECT MAX(metric_id) FROM metric_sensors WHERE agent_id = ?)
+		ORDER BY kind DESC, name
+	`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	CREATE INDEX IF NOT EXISTS idx_alerts_agent_created ON alerts(agent_id, created_at);
-	CREATE INDEX IF NOT EXISTS idx_alerts_resolved ON alerts(resolved);
-	`
+	sensors := []models.SensorMetrics{}
+	for rows.Next() {
+		s, err := scanSensor(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors, rows.Err()
 }
 
-// SaveMetrics saves metrics to database
-func (d *Database) SaveMetrics(m *models.Metrics) error {
-	_, err := d.db.Exec(`
-		INSERT INTO metrics (agent_id, cpu_percent, cpu_cores, memory_used, memory_total,
-			disk_used, disk_total, network_rx, network_tx, load_avg_1, load_avg_5, load_avg_15, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		m.AgentID, m.CPUPercent, m.CPUCores, m.MemoryUsed, m.MemoryTotal,
-		m.DiskUsed, m.DiskTotal, m.NetworkRx, m.NetworkTx,
-		m.LoadAvg1, m.LoadAvg5, m.LoadAvg15, m.Timestamp,
-	)
-	return err
+// EachSensorSample calls fn with the readings of one of an agent's sensors
+// between from and to, oldest first. Each sample is a view of the metrics
+// with the sensor fields taken from the sensor.
+func (d *Database) EachSensorSample(agentID, name string, from, to time.Time, fn func(*models.Metrics)) error {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+sensorColumns+`, created_at
+		FROM metric_sensors
+		WHERE agent_id = ? AND name = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC
+	`), agentID, name, d.timeArg(from), d.timeArg(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var created dbTime
+		s, err := scanSensor(rows.Scan, &created)
+		if err != nil {
+			return err
+		}
+		fn(sensorView(&models.Metrics{AgentID: agentID, Timestamp: created.Time}, s))
+	}
+	return rows.Err()
 }
 
-// UpdateAgent updates or inserts agent information
-func (d *Database) UpdateAgent(agent *models.Agent) error {
-	now := time.Now()
+// GetLatestCustomMetrics retrieves the value of each custom series reported
+// with an agent's newest metrics
+func (d *Database) GetLatestCustomMetrics(agentID string) ([]models.CustomMetric, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT name, value, unit
+		FROM metric_custom
+		WHERE metric_id = (SELECT MAX(metric_id) FROM metric_custom WHERE agent_id = ?)
+		ORDER BY name
+	`), agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	// Use UPSERT pattern based on driver
-	switch d.driver {
-	case "mysql":
-		_, err := d.db.Exec(`
-			INSERT INTO agents (id, name, host, last_seen_at, status, platform, version, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-			ON DUPLICATE KEY UPDATE
-				name=VALUES(name), host=VALUES(host), last_seen_at=VALUES(last_seen_at),
-				status=VALUES(status), platform=VALUES(platform), version=VALUES(version), updated_at=VALUES(updated_at)`,
-			agent.ID, agent.Name, agent.Host, agent.LastSeen, agent.Status, agent.Platform, agent.Version, now, now,
-		)
-		return err
-	case "postgres":
-		_, err := d.db.Exec(`
-			INSERT INTO agents (id, name, host, last_seen_at, status, platform, version, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-			ON CONFLICT (id) DO UPDATE SET
-				name=EXCLUDED.name, host=EXCLUDED.host, last_seen_at=EXCLUDED.last_seen_at,
-				status=EXCLUDED.status, platform=EXCLUDED.platform, version=EXCLUDED.version, updated_at=EXCLUDED.updated_at`,
-			agent.ID, agent.Name, agent.Host, agent.LastSeen, agent.Status, agent.Platform, agent.Version, now, now,
-		)
-		return err
-	default: // sqlite3
-		_, err := d.db.Exec(`
-			INSERT OR REPLACE INTO agents (id, name, host, last_seen_at, status, platform, version, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			agent.ID, agent.Name, agent.Host, agent.LastSeen, agent.Status, agent.Platform, agent.Version, now, now,
-		)
+	custom := []models.CustomMetric{}
+	for rows.Next() {
+		var c models.CustomMetric
+		if err := rows.Scan(&c.Name, &c.Value, &c.Unit); err != nil {
+			return nil, err
+		}
+		custom = append(custom, c)
+	}
+	return custom, rows.Err()
+}
+
+// EachCustomSample calls fn with the values of one of an agent's custom
+// series between from and to, oldest first. Each sample is a view of the
+// metrics holding only that series.
+func (d *Database) EachCustomSample(agentID, name string, from, to time.Time, fn func(*models.Metrics)) error {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT name, value, unit, created_at
+		FROM metric_custom
+		WHERE agent_id = ? AND name = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC
+	`), agentID, name, d.timeArg(from), d.timeArg(to))
+	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.CustomMetric
+		var created dbTime
+		if err := rows.Scan(&c.Name, &c.Value, &c.Unit, &created); err != nil {
+			return err
+		}
+		fn(customView(&models.Metrics{AgentID: agentID, Timestamp: created.Time}, c))
+	}
+	return rows.Err()
 }
 
-// GetAgents retrieves all agents
-func (d *Database) GetAgents() ([]*models.Agent, error) {
-	rows, err := d.db.Query(`
-		SELECT id, name, host, last_seen_at, status, platform, version FROM agents
+// GetLatestContainers retrieves the usage of each container reported with
+// an agent's newest metrics
+func (d *Database) GetLatestContainers(agentID string) ([]models.ContainerMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT container_id, name, image, labels, cpu_percent, memory_used, memory_limit,
+			network_rx, network_tx, disk_read_bps, disk_write_bps
+		FROM metric_containers
+		WHERE metric_id = (SELECT MAX(id) FROM metrics WHERE agent_id = ?)
 		ORDER BY name
-	`)
+	`), agentID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var agents []*models.Agent
+	containers := []models.ContainerMetrics{}
 	for rows.Next() {
-		agent := &models.Agent{}
-		err := rows.Scan(&agent.ID, &agent.Name, &agent.Host, &agent.LastSeen,
-			&agent.Status, &agent.Platform, &agent.Version)
+		var c models.ContainerMetrics
+		var labels string
+		err := rows.Scan(&c.ID, &c.Name, &c.Image, &labels, &c.CPUPercent, &c.MemoryUsed, &c.MemoryLimit,
+			&c.NetworkRx, &c.NetworkTx, &c.DiskReadBps, &c.DiskWriteBps)
 		if err != nil {
 			return nil, err
 		}
-		agents = append(agents, agent)
+		parseJSONList(labels, &c.Labels)
+		containers = append(containers, c)
 	}
+	return containers, rows.Err()
+}
 
-	return agents, nil
+// GetContainerEvents retrieves an agent's most recent container starts and
+// stops, newest first
+func (d *Database) GetContainerEvents(agentID string, limit int) ([]models.ContainerEvent, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT container_id, name, event_type, created_at
+		FROM container_events
+		WHERE agent_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`), agentID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.ContainerEvent{}
+	for rows.Next() {
+		var e models.ContainerEvent
+		var created dbTime
+		if err := rows.Scan(&e.ContainerID, &e.Name, &e.Type, &created); err != nil {
+			return nil, err
+		}
+		e.Timestamp = created.Time
+		events = append(events, e)
+	}
+	return events, rows.Err()
 }
 
-// GetMetricsHistory retrieves metrics history for an agent
-func (d *Database) GetMetricsHistory(agentID string, since time.Time) ([]*models.Metrics, error) {
-	// Some drivers/store formats (e.g. sqlite's CURRENT_TIMESTAMP) store timestamps
-	// as 'YYYY-MM-DD HH:MM:SS'. To ensure comparisons work reliably, format the
-	// 'since' parameter appropriately for sqlite. For other drivers we pass the
-	// time.Time value directly.
-	var sinceParam interface{} = since
-	var query string
-	// Use local formatted string for sqlite; other drivers can accept time.Time
-	var layout = "2006-01-02 15:04:05"
-	if d.driver == "sqlite3" {
-		sinceParam = since.Format(layout)
-		query = `
-		SELECT agent_id, cpu_percent, cpu_cores, memory_used, memory_total,
-			disk_used, disk_total, network_rx, network_tx, load_avg_1, load_avg_5, load_avg_15, created_at
-		FROM metrics
-		WHERE agent_id = ? AND created_at >= ?
-		ORDER BY created_at DESC
-		LIMIT 1000
-	`
-	} else {
-		query = `
-		SELECT agent_id, cpu_percent, cpu_cores, memory_used, memory_total,
-			disk_used, disk_total, network_rx, network_tx, load_avg_1, load_avg_5, load_avg_15, created_at
-		FROM metrics
-		WHERE agent_id = ? AND created_at >= ?
-		ORDER BY created_at DESC
-		LIMIT 1000
-	`
+// processRetention is how long process samples are kept. They are only
+// needed to explain recent alerts and take far more rows than the metrics.
+const processRetention = 24 * time.Hour
+
+// saveProcesses stores the processes reported with a sample and drops the
+// agent's process samples that have outlived processRetention
+func (d *Database) saveProcesses(tx *sql.Tx, metricID int, m *models.Metrics) error {
+	for _, p := range m.Processes {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO metric_processes (metric_id, agent_id, pid, name, username, cmdline,
+				cpu_percent, rss, fds, threads, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			metricID, m.AgentID, p.PID, p.Name, p.User, p.Cmdline,
+			p.CPUPercent, p.RSS, p.FDs, p.Threads, m.Timestamp,
+		)
+		if err != nil {
+			return err
+		}
 	}
 
-	rows, err := d.db.Query(query, agentID, sinceParam)
+	_, err := tx.Exec(d.rebind(`DELETE FROM metric_processes WHERE agent_id = ? AND created_at < ?`),
+		m.AgentID, d.timeArg(m.Timestamp.Add(-processRetention)))
+	return err
+}
+
+// GetProcesses retrieves the busiest processes of the newest process sample
+// an agent reported at or before a time, busiest first. It returns an empty
+// list if there is none within processRetention.
+func (d *Database) GetProcesses(agentID string, at time.Time) ([]models.ProcessMetrics, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT pid, name, username, cmdline, cpu_percent, rss, fds, threads
+		FROM metric_processes
+		WHERE metric_id = (
+			SELECT MAX(metric_id) FROM metric_processes
+			WHERE agent_id = ? AND created_at <= ? AND created_at >= ?
+		)
+		ORDER BY cpu_percent DESC, rss DESC
+	`), agentID, d.timeArg(at), d.timeArg(at.Add(-processRetention)))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var metrics []*models.Metrics
+	processes := []models.ProcessMetrics{}
 	for rows.Next() {
-		m := &models.Metrics{}
-		err := rows.Scan(&m.AgentID, &m.CPUPercent, &m.CPUCores, &m.MemoryUsed, &m.MemoryTotal,
-			&m.DiskUsed, &m.DiskTotal, &m.NetworkRx, &m.NetworkTx,
-			&m.LoadAvg1, &m.LoadAvg5, &m.LoadAvg15, &m.Timestamp)
+		var p models.ProcessMetrics
+		err := rows.Scan(&p.PID, &p.Name, &p.User, &p.Cmdline, &p.CPUPercent, &p.RSS, &p.FDs, &p.Threads)
 		if err != nil {
 			return nil, err
 		}
-		metrics = append(metrics, m)
+		processes = append(processes, p)
 	}
-
-	return metrics, nil
+	return processes, rows.Err()
 }
 
-// SaveAlertRule saves an alert rule
+// SaveAlertRule validates and saves an alert rule
 func (d *Database) SaveAlertRule(rule *models.AlertRule) error {
 	now := time.Now()
 
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+	if rule.Expression != "" && rule.MetricType == "" {
+		rule.MetricType = "expression"
+	}
+	normalizeSeverities(rule)
+	normalizeRuleType(rule)
+	levels := jsonList(rule.Levels)
+
+	// Global rules are stored with a NULL agent so the foreign key holds
+	var agentID interface{}
+	if rule.AgentID != "" {
+		agentID = rule.AgentID
+	}
+
 	if rule.ID == 0 {
 		var query string
 		switch d.driver {
 		case "postgres":
-			query = `INSERT INTO alert_rules (agent_id, metric_type, threshold, operator, duration, enabled, description, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+			query = `INSERT INTO alert_rules (agent_id, metric_type, threshold, operator, duration, enabled, description, expression,
+				window_seconds, aggregate, breach_ratio, escalation_policy_id, severity, levels, rule_type, direction, seasonality, target,
+				created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20) RETURNING id`
 		default:
-			query = `INSERT INTO alert_rules (agent_id, metric_type, threshold, operator, duration, enabled, description, created_at, updated_at)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			query = `INSERT INTO alert_rules (agent_id, metric_type, threshold, operator, duration, enabled, description, expression,
+				window_seconds, aggregate, breach_ratio, escalation_policy_id, severity, levels, rule_type, direction, seasonality, target,
+				created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 		}
 
 		if d.driver == "postgres" {
 			err := d.db.QueryRow(query,
-				rule.AgentID, rule.MetricType, rule.Threshold, rule.Operator, rule.Duration,
-				rule.Enabled, rule.Description, now, now,
+				agentID, rule.MetricType, rule.Threshold, rule.Operator, rule.Duration,
+				rule.Enabled, rule.Description, rule.Expression,
+				rule.Window, rule.Aggregate, rule.BreachRatio, rule.EscalationPolicyID, rule.Severity, levels,
+				rule.Type, rule.Direction, rule.Seasonality, rule.Target, now, now,
 			).Scan(&rule.ID)
 			return err
 		} else {
 			result, err := d.db.Exec(query,
-				rule.AgentID, rule.MetricType, rule.Threshold, rule.Operator, rule.Duration,
-				rule.Enabled, rule.Description, now, now,
+				agentID, rule.MetricType, rule.Threshold, rule.Operator, rule.Duration,
+				rule.Enabled, rule.Description, rule.Expression,
+				rule.Window, rule.Aggregate, rule.BreachRatio, rule.EscalationPolicyID, rule.Severity, levels,
+				rule.Type, rule.Direction, rule.Seasonality, rule.Target, now, now,
 			)
 			if err != nil {
 				return err
@@ -463,25 +2272,50 @@ func (d *Database) SaveAlertRule(rule *models.AlertRule) error {
 			rule.ID = int(id)
 		}
 	} else {
-		_, err := d.db.Exec(`
+		_, err := d.db.Exec(d.rebind(`
 			UPDATE alert_rules SET agent_id=?, metric_type=?, threshold=?, operator=?,
-				duration=?, enabled=?, description=?, updated_at=?
-			WHERE id=?`,
-			rule.AgentID, rule.MetricType, rule.Threshold, rule.Operator, rule.Duration,
-			rule.Enabled, rule.Description, now, rule.ID,
+				duration=?, enabled=?, description=?, expression=?,
+				window_seconds=?, aggregate=?, breach_ratio=?, escalation_policy_id=?, severity=?, levels=?,
+				rule_type=?, direction=?, seasonality=?, target=?, updated_at=?
+			WHERE id=?`),
+			agentID, rule.MetricType, rule.Threshold, rule.Operator, rule.Duration,
+			rule.Enabled, rule.Description, rule.Expression,
+			rule.Window, rule.Aggregate, rule.BreachRatio, rule.EscalationPolicyID, rule.Severity, levels,
+			rule.Type, rule.Direction, rule.Seasonality, rule.Target, now, rule.ID,
 		)
 		return err
 	}
 	return nil
 }
 
+// alertRuleColumns are the columns scanned by scanAlertRule
+const alertRuleColumns = `id, agent_id, metric_type, threshold, operator, duration, enabled, description, expression,
+	window_seconds, aggregate, breach_ratio, escalation_policy_id, severity, levels, rule_type, direction, seasonality, target`
+
+// scanAlertRule scans a row selected with alertRuleColumns
+func scanAlertRule(scan func(dest ...interface{}) error) (*models.AlertRule, error) {
+	rule := &models.AlertRule{}
+	var agentID sql.NullString
+	var enabled interface{}
+	var levels string
+	err := scan(&rule.ID, &agentID, &rule.MetricType, &rule.Threshold,
+		&rule.Operator, &rule.Duration, &enabled, &rule.Description, &rule.Expression,
+		&rule.Window, &rule.Aggregate, &rule.BreachRatio, &rule.EscalationPolicyID, &rule.Severity, &levels,
+		&rule.Type, &rule.Direction, &rule.Seasonality, &rule.Target)
+	if err != nil {
+		return nil, err
+	}
+	// Global rules have no agent
+	rule.AgentID = agentID.String
+	// Handle different enabled types from different databases
+	rule.Enabled = dbBool(enabled)
+	parseJSONList(levels, &rule.Levels)
+	return rule, nil
+}
+
 // GetAlertRules retrieves all alert rules
 func (d *Database) GetAlertRules() ([]*models.AlertRule, error) {
-	rows, err := d.db.Query(`
-		SELECT id, agent_id, metric_type, threshold, operator, duration, enabled, description
-		FROM alert_rules
-		ORDER BY id
-	`)
+	rows, err := d.db.Query(`SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY id`)
 	if err != nil {
 		return nil, err
 	}
@@ -489,28 +2323,32 @@ func (d *Database) GetAlertRules() ([]*models.AlertRule, error) {
 
 	var rules []*models.AlertRule
 	for rows.Next() {
-		rule := &models.AlertRule{}
-		var enabled interface{}
-		err := rows.Scan(&rule.ID, &rule.AgentID, &rule.MetricType, &rule.Threshold,
-			&rule.Operator, &rule.Duration, &enabled, &rule.Description)
+		rule, err := scanAlertRule(rows.Scan)
 		if err != nil {
 			return nil, err
 		}
-		// Handle different enabled types from different databases
-		switch v := enabled.(type) {
-		case bool:
-			rule.Enabled = v
-		case int64:
-			rule.Enabled = v != 0
-		case int:
-			rule.Enabled = v != 0
-		}
 		rules = append(rules, rule)
 	}
 
 	return rules, nil
 }
 
+// GetAlertRule retrieves a single alert rule, returning nil if it does not exist
+func (d *Database) GetAlertRule(id int) (*models.AlertRule, error) {
+	row := d.db.QueryRow(d.rebind(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = ?`), id)
+	rule, err := scanAlertRule(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// DeleteAlertRule deletes an alert rule
+func (d *Database) DeleteAlertRule(id int) error {
+	_, err := d.db.Exec(d.rebind(`DELETE FROM alert_rules WHERE id = ?`), id)
+	return err
+}
+
 // SaveAlert saves a triggered alert
 func (d *Database) SaveAlert(alert *models.Alert) error {
 	now := time.Now()
@@ -518,21 +2356,21 @@ func (d *Database) SaveAlert(alert *models.Alert) error {
 	var query string
 	switch d.driver {
 	case "postgres":
-		query = `INSERT INTO alerts (rule_id, agent_id, message, value, resolved, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+		query = `INSERT INTO alerts (rule_id, agent_id, message, value, resolved, suppressed, severity, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
 	default:
-		query = `INSERT INTO alerts (rule_id, agent_id, message, value, resolved, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?)`
+		query = `INSERT INTO alerts (rule_id, agent_id, message, value, resolved, suppressed, severity, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	}
 
 	if d.driver == "postgres" {
 		err := d.db.QueryRow(query,
-			alert.RuleID, alert.AgentID, alert.Message, alert.Value, alert.Resolved, alert.Timestamp, now,
+			alert.RuleID, alert.AgentID, alert.Message, alert.Value, alert.Resolved, alert.Suppressed, alert.Severity, alert.Timestamp, now,
 		).Scan(&alert.ID)
 		return err
 	} else {
 		result, err := d.db.Exec(query,
-			alert.RuleID, alert.AgentID, alert.Message, alert.Value, alert.Resolved, alert.Timestamp, now,
+			alert.RuleID, alert.AgentID, alert.Message, alert.Value, alert.Resolved, alert.Suppressed, alert.Severity, alert.Timestamp, now,
 		)
 		if err != nil {
 			return err
@@ -543,51 +2381,235 @@ func (d *Database) SaveAlert(alert *models.Alert) error {
 	return nil
 }
 
-// GetAlerts retrieves recent alerts
-func (d *Database) GetAlerts(limit int) ([]*models.Alert, error) {
-	rows, err := d.db.Query(`
-		SELECT id, rule_id, agent_id, message, value, resolved, created_at
-		FROM alerts
-		ORDER BY created_at DESC
-		LIMIT ?
-	`, limit)
+// alertColumns are the columns scanned by scanAlert
+const alertColumns = `id, rule_id, agent_id, message, value, resolved, suppressed, severity,
+	acknowledged_at, acknowledged_by, escalation_step, resolved_at, created_at`
+
+// scanAlert scans a row selected with alertColumns
+func scanAlert(scan func(dest ...interface{}) error) (*models.Alert, error) {
+	alert := &models.Alert{}
+	var resolved, suppressed interface{}
+	var acknowledged, resolvedAt, created dbTime
+	err := scan(&alert.ID, &alert.RuleID, &alert.AgentID, &alert.Message, &alert.Value,
+		&resolved, &suppressed, &alert.Severity, &acknowledged, &alert.AcknowledgedBy, &alert.EscalationStep,
+		&resolvedAt, &created)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	// Handle different boolean types from different databases
+	alert.Resolved = dbBool(resolved)
+	alert.Suppressed = dbBool(suppressed)
+	alert.AcknowledgedAt = acknowledged.Time
+	alert.ResolvedAt = resolvedAt.Time
+	alert.Timestamp = created.Time
+	return alert, nil
+}
 
+// scanAlerts scans all rows selected with alertColumns
+func scanAlerts(rows *sql.Rows) ([]*models.Alert, error) {
 	var alerts []*models.Alert
 	for rows.Next() {
-		alert := &models.Alert{}
-		var resolved interface{}
-		err := rows.Scan(&alert.ID, &alert.RuleID, &alert.AgentID,
-			&alert.Message, &alert.Value, &resolved, &alert.Timestamp)
+		alert, err := scanAlert(rows.Scan)
 		if err != nil {
 			return nil, err
 		}
-		// Handle different resolved types from different databases
-		switch v := resolved.(type) {
-		case bool:
-			alert.Resolved = v
-		case int64:
-			alert.Resolved = v != 0
-		case int:
-			alert.Resolved = v != 0
-		}
 		alerts = append(alerts, alert)
 	}
+	return alerts, rows.Err()
+}
+
+// AlertQuery filters and pages the alerts returned by GetAlerts. Zero
+// fields do not filter.
+type AlertQuery struct {
+	AgentID    string
+	RuleID     int
+	Resolved   *bool
+	Severities []string
+	From, To   time.Time
+	Sort       string // created_at (default), value or severity
+	Ascending  bool
+	After      *AlertCursor // continue after this alert
+	Limit      int
+}
+
+// AlertCursor marks the last alert of a page: its sort key and id
+type AlertCursor struct {
+	Key float64 `json:"k"`
+	ID  int     `json:"id"`
+}
+
+// alertSortKey returns the SQL expression alerts are sorted by. Alerts are
+// stored in the order they are raised, so creation order follows the id.
+func alertSortKey(sort string) string {
+	switch sort {
+	case "value":
+		return "value"
+	case "severity":
+		key := "CASE severity"
+		for _, severity := range []string{"info", "warning", "critical"} {
+			key += fmt.Sprintf(" WHEN '%s' THEN %d", severity, severityRanks[severity])
+		}
+		return key + " ELSE 0 END"
+	}
+	return "id"
+}
+
+// GetAlerts retrieves the alerts matching q, newest first unless q asks
+// otherwise, along with the cursor of the next page if there is one
+func (d *Database) GetAlerts(q AlertQuery) ([]*models.Alert, *AlertCursor, error) {
+	var where []string
+	var args []interface{}
+	if q.AgentID != "" {
+		where = append(where, "agent_id = ?")
+		args = append(args, q.AgentID)
+	}
+	if q.RuleID != 0 {
+		where = append(where, "rule_id = ?")
+		args = append(args, q.RuleID)
+	}
+	if q.Resolved != nil {
+		where = append(where, "resolved = ?")
+		args = append(args, *q.Resolved)
+	}
+	if len(q.Severities) > 0 {
+		where = append(where, "severity IN (?"+strings.Repeat(", ?", len(q.Severities)-1)+")")
+		for _, severity := range q.Severities {
+			args = append(args, severity)
+		}
+	}
+	if !q.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, d.timeArg(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, d.timeArg(q.To))
+	}
+
+	key := alertSortKey(q.Sort)
+	cmp, order := "<", "DESC"
+	if q.Ascending {
+		cmp, order = ">", "ASC"
+	}
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", key, cmp, key, cmp))
+		args = append(args, q.After.Key, q.After.Key, q.After.ID)
+	}
+
+	query := `SELECT ` + alertColumns + ` FROM alerts`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	// Fetch one extra row to learn whether there is another page
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", key, order, order)
+	args = append(args, q.Limit+1)
+
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	alerts, err := scanAlerts(rows)
+	if err != nil || len(alerts) <= q.Limit {
+		return alerts, nil, err
+	}
+	alerts = alerts[:q.Limit]
+
+	last := alerts[len(alerts)-1]
+	next := &AlertCursor{ID: last.ID}
+	switch q.Sort {
+	case "value":
+		next.Key = last.Value
+	case "severity":
+		next.Key = float64(severityRanks[last.Severity])
+	default:
+		next.Key = float64(last.ID)
+	}
+	return alerts, next, nil
+}
+
+// GetAlert retrieves a single alert, returning nil if it does not exist
+func (d *Database) GetAlert(id int) (*models.Alert, error) {
+	row := d.db.QueryRow(d.rebind(`SELECT `+alertColumns+` FROM alerts WHERE id = ?`), id)
+	alert, err := scanAlert(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return alert, err
+}
+
+// GetUnresolvedAlerts retrieves alerts that have not been resolved yet
+func (d *Database) GetUnresolvedAlerts() ([]*models.Alert, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+alertColumns+`
+		FROM alerts
+		WHERE resolved = ?
+		ORDER BY id`), false)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAlerts(rows)
+}
+
+// GetEscalatingAlerts retrieves open, unacknowledged alerts of rules that
+// notify through an escalation policy
+func (d *Database) GetEscalatingAlerts() ([]*models.Alert, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+alertColumns+`
+		FROM alerts
+		WHERE resolved = ? AND suppressed = ? AND acknowledged_at IS NULL
+			AND rule_id IN (SELECT id FROM alert_rules WHERE escalation_policy_id > 0)
+		ORDER BY id`), false, false)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAlerts(rows)
+}
+
+// AcknowledgeAlert records who acknowledged an alert and when
+func (d *Database) AcknowledgeAlert(id int, by string, at time.Time) error {
+	_, err := d.db.Exec(d.rebind(`UPDATE alerts SET acknowledged_at=?, acknowledged_by=?, updated_at=? WHERE id=?`),
+		at, by, time.Now(), id)
+	return err
+}
+
+// SetAlertEscalationStep records how many escalation steps of an alert were notified
+func (d *Database) SetAlertEscalationStep(id, step int) error {
+	_, err := d.db.Exec(d.rebind(`UPDATE alerts SET escalation_step=?, updated_at=? WHERE id=?`), step, time.Now(), id)
+	return err
+}
 
-	return alerts, nil
+// RaiseAlertSeverity moves an open alert to a more severe level, along with
+// the value and message that reached it
+func (d *Database) RaiseAlertSeverity(id int, severity, message string, value float64) error {
+	_, err := d.db.Exec(d.rebind(`UPDATE alerts SET severity=?, message=?, value=?, updated_at=? WHERE id=?`),
+		severity, message, value, time.Now(), id)
+	return err
+}
+
+// ResolveAlert marks an alert as resolved
+func (d *Database) ResolveAlert(id int) error {
+	now := time.Now()
+	_, err := d.db.Exec(d.rebind(`UPDATE alerts SET resolved=?, resolved_at=?, updated_at=? WHERE id=?`), true, now, now, id)
+	return err
 }
 
 // DeleteOldMetrics deletes metrics older than the specified duration
 func (d *Database) DeleteOldMetrics(olderThan time.Time) error {
-	if d.driver == "sqlite3" {
-		_, err := d.db.Exec(`DELETE FROM metrics WHERE created_at < ?`, olderThan.Format("2006-01-02 15:04:05"))
-		return err
+	// sqlite does not enforce the cascade, so child rows go first
+	for _, table := range []string{"metric_filesystems", "metric_disks", "metric_interfaces", "metric_watches",
+		"metric_sensors", "metric_custom", "metric_containers", "metric_processes", "container_events", "metrics"} {
+		_, err := d.db.Exec(d.rebind(`DELETE FROM `+table+` WHERE created_at < ?`), d.timeArg(olderThan))
+		if err != nil {
+			return err
+		}
 	}
-	_, err := d.db.Exec(`DELETE FROM metrics WHERE created_at < ?`, olderThan)
-	return err
+	return nil
 }
 
 // CheckInstalled checks if the database schema is installed
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// scanEscalationPolicy scans an escalation policy row
+func scanEscalationPolicy(scan func(dest ...interface{}) error) (*models.EscalationPolicy, error) {
+	p := &models.EscalationPolicy{}
+	var steps string
+	var created dbTime
+	if err := scan(&p.ID, &p.Name, &p.Description, &steps, &created); err != nil {
+		return nil, err
+	}
+	parseJSONList(steps, &p.Steps)
+	p.CreatedAt = created.Time
+	return p, nil
+}
+
+// GetEscalationPolicies retrieves all escalation policies
+func (d *Database) GetEscalationPolicies() ([]*models.EscalationPolicy, error) {
+	rows, err := d.db.Query(`SELECT id, name, description, steps, created_at FROM escalation_policies ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*models.EscalationPolicy
+	for rows.Next() {
+		p, err := scanEscalationPolicy(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// GetEscalationPolicy retrieves a single escalation policy, returning nil if it does not exist
+func (d *Database) GetEscalationPolicy(id int) (*models.EscalationPolicy, error) {
+	row := d.db.QueryRow(d.rebind(`SELECT id, name, description, steps, created_at FROM escalation_policies WHERE id = ?`), id)
+	p, err := scanEscalationPolicy(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// SaveEscalationPolicy inserts or updates an escalation policy
+func (d *Database) SaveEscalationPolicy(p *models.EscalationPolicy) error {
+	now := time.Now()
+
+	if p.ID == 0 {
+		id, err := d.insertReturningID(`
+			INSERT INTO escalation_policies (name, description, steps, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			p.Name, p.Description, jsonList(p.Steps), now, now,
+		)
+		if err != nil {
+			return err
+		}
+		p.ID = id
+		p.CreatedAt = now
+		return nil
+	}
+
+	_, err := d.db.Exec(d.rebind(`
+		UPDATE escalation_policies SET name=?, description=?, steps=?, updated_at=?
+		WHERE id=?`),
+		p.Name, p.Description, jsonList(p.Steps), now, p.ID,
+	)
+	return err
+}
+
+// DeleteEscalationPolicy deletes an escalation policy
+func (d *Database) DeleteEscalationPolicy(id int) error {
+	_, err := d.db.Exec(d.rebind(`DELETE FROM escalation_policies WHERE id = ?`), id)
+	return err
+}
+
+// scanOnCallSchedule scans an on-call schedule row
+func scanOnCallSchedule(scan func(dest ...interface{}) error) (*models.OnCallSchedule, error) {
+	sch := &models.OnCallSchedule{}
+	var rotations, overrides string
+	var created dbTime
+	if err := scan(&sch.ID, &sch.Name, &sch.Channel, &rotations, &overrides, &created); err != nil {
+		return nil, err
+	}
+	parseJSONList(rotations, &sch.Rotations)
+	parseJSONList(overrides, &sch.Overrides)
+	sch.CreatedAt = created.Time
+	return sch, nil
+}
+
+// GetOnCallSchedules retrieves all on-call schedules
+func (d *Database) GetOnCallSchedules() ([]*models.OnCallSchedule, error) {
+	rows, err := d.db.Query(`SELECT id, name, channel, rotations, overrides, created_at FROM oncall_schedules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*models.OnCallSchedule
+	for rows.Next() {
+		sch, err := scanOnCallSchedule(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sch)
+	}
+
+	return schedules, nil
+}
+
+// GetOnCallSchedule retrieves a single on-call schedule, returning nil if it does not exist
+func (d *Database) GetOnCallSchedule(id int) (*models.OnCallSchedule, error) {
+	row := d.db.QueryRow(d.rebind(`SELECT id, name, channel, rotations, overrides, created_at FROM oncall_schedules WHERE id = ?`), id)
+	sch, err := scanOnCallSchedule(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sch, err
+}
+
+// SaveOnCallSchedule inserts or updates an on-call schedule
+func (d *Database) SaveOnCallSchedule(sch *models.OnCallSchedule) error {
+	now := time.Now()
+
+	if sch.ID == 0 {
+		id, err := d.insertReturningID(`
+			INSERT INTO oncall_schedules (name, channel, rotations, overrides, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			sch.Name, sch.Channel, jsonList(sch.Rotations), jsonList(sch.Overrides), now, now,
+		)
+		if err != nil {
+			return err
+		}
+		sch.ID = id
+		sch.CreatedAt = now
+		return nil
+	}
+
+	_, err := d.db.Exec(d.rebind(`
+		UPDATE oncall_schedules SET name=?, channel=?, rotations=?, overrides=?, updated_at=?
+		WHERE id=?`),
+		sch.Name, sch.Channel, jsonList(sch.Rotations), jsonList(sch.Overrides), now, sch.ID,
+	)
+	return err
+}
+
+// DeleteOnCallSchedule deletes an on-call schedule
+func (d *Database) DeleteOnCallSchedule(id int) error {
+	_, err := d.db.Exec(d.rebind(`DELETE FROM oncall_schedules WHERE id = ?`), id)
+	return err
+}
+
+// GetNotifiedTargets returns the distinct channel and recipient pairs an
+// alert has been notified on
+func (d *Database) GetNotifiedTargets(alertID int) ([]notifyTarget, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT DISTINCT channel, recipient FROM notification_outbox WHERE alert_id = ?`), alertID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []notifyTarget
+	for rows.Next() {
+		var t notifyTarget
+		if err := rows.Scan(&t.channel, &t.recipient); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// SaveAgentInventory stores the host inventory an agent reported, replacing
+// the one it reported before
+func (d *Database) SaveAgentInventory(agentID string, inv *models.HostInventory) error {
+	now := time.Now()
+	args := []interface{}{agentID, inv.Hostname, inv.OS, inv.Platform, inv.PlatformVersion, inv.KernelVersion,
+		inv.Arch, inv.CPUModel, inv.CPUCount, inv.MemoryTotal, jsonList(inv.IPAddresses), inv.Virtualization,
+		nullTime(inv.BootTime), inv.Uptime, inv.AgentVersion, now}
+
+	switch d.driver {
+	case "mysql":
+		_, err := d.db.Exec(`
+			INSERT INTO agent_inventory (agent_id, hostname, os, platform, platform_version, kernel_version,
+				arch, cpu_model, cpu_count, memory_total, ip_addresses, virtualization,
+				boot_time, uptime, agent_version, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE
+				hostname=VALUES(hostname), os=VALUES(os), platform=VALUES(platform),
+				platform_version=VALUES(platform_version), kernel_version=VALUES(kernel_version),
+				arch=VALUES(arch), cpu_model=VALUES(cpu_model), cpu_count=VALUES(cpu_count),
+				memory_total=VALUES(memory_total), ip_addresses=VALUES(ip_addresses),
+				virtualization=VALUES(virtualization), boot_time=VALUES(boot_time), uptime=VALUES(uptime),
+				agent_version=VALUES(agent_version), updated_at=VALUES(updated_at)`,
+			args...,
+		)
+		return err
+	case "postgres":
+		_, err := d.db.Exec(`
+			INSERT INTO agent_inventory (agent_id, hostname, os, platform, platform_version, kernel_version,
+				arch, cpu_model, cpu_count, memory_total, ip_addresses, virtualization,
+				boot_time, uptime, agent_version, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			ON CONFLICT (agent_id) DO UPDATE SET
+				hostname=EXCLUDED.hostname, os=EXCLUDED.os, platform=EXCLUDED.platform,
+				platform_version=EXCLUDED.platform_version, kernel_version=EXCLUDED.kernel_version,
+				arch=EXCLUDED.arch, cpu_model=EXCLUDED.cpu_model, cpu_count=EXCLUDED.cpu_count,
+				memory_total=EXCLUDED.memory_total, ip_addresses=EXCLUDED.ip_addresses,
+				virtualization=EXCLUDED.virtualization, boot_time=EXCLUDED.boot_time, uptime=EXCLUDED.uptime,
+				agent_version=EXCLUDED.agent_version, updated_at=EXCLUDED.updated_at`,
+			args...,
+		)
+		return err
+	default: // sqlite3
+		_, err := d.db.Exec(`
+			INSERT OR REPLACE INTO agent_inventory (agent_id, hostname, os, platform, platform_version, kernel_version,
+				arch, cpu_model, cpu_count, memory_total, ip_addresses, virtualization,
+				boot_time, uptime, agent_version, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			args...,
+		)
+		return err
+	}
+}
+
+// GetAgentInventory retrieves the host inventory an agent last reported,
+// returning nil if it has not reported one. The uptime is brought forward to
+// now from the boot time.
+func (d *Database) GetAgentInventory(agentID string) (*models.HostInventory, error) {
+	inv := &models.HostInventory{}
+	var ips string
+	var bootTime, updated dbTime
+	err := d.db.QueryRow(d.rebind(`
+		SELECT hostname, os, platform, platform_version, kernel_version, arch, cpu_model, cpu_count,
+			memory_total, ip_addresses, virtualization, boot_time, uptime, agent_version, updated_at
+		FROM agent_inventory
+		WHERE agent_id = ?`), agentID).Scan(&inv.Hostname, &inv.OS, &inv.Platform, &inv.PlatformVersion,
+		&inv.KernelVersion, &inv.Arch, &inv.CPUModel, &inv.CPUCount, &inv.MemoryTotal, &ips,
+		&inv.Virtualization, &bootTime, &inv.Uptime, &inv.AgentVersion, &updated)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	parseJSONList(ips, &inv.IPAddresses)
+	inv.BootTime = bootTime.Time
+	inv.UpdatedAt = updated.Time
+	if !inv.BootTime.IsZero() {
+		inv.Uptime = uint64(time.Since(inv.BootTime).Seconds())
+	}
+	return inv, nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const maintenanceWindowColumns = `id, name, description, starts_at, ends_at, schedule, duration, timezone,
+	agent_ids, tags, rule_ids, enabled, created_at`
+
+// nullTime stores a zero time as NULL
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// scanMaintenanceWindow scans a row selected with maintenanceWindowColumns
+func scanMaintenanceWindow(scan func(dest ...interface{}) error) (*models.MaintenanceWindow, error) {
+	w := &models.MaintenanceWindow{}
+	var startsAt, endsAt, created dbTime
+	var agentIDs, tags, ruleIDs string
+	var enabled interface{}
+	err := scan(&w.ID, &w.Name, &w.Description, &startsAt, &endsAt, &w.Schedule, &w.Duration, &w.Timezone,
+		&agentIDs, &tags, &ruleIDs, &enabled, &created)
+	if err != nil {
+		return nil, err
+	}
+	w.StartsAt = startsAt.Time
+	w.EndsAt = endsAt.Time
+	w.CreatedAt = created.Time
+	w.Enabled = dbBool(enabled)
+	parseJSONList(agentIDs, &w.AgentIDs)
+	parseJSONList(tags, &w.Tags)
+	parseJSONList(ruleIDs, &w.RuleIDs)
+	return w, nil
+}
+
+// GetMaintenanceWindows retrieves all maintenance windows
+func (d *Database) GetMaintenanceWindows() ([]*models.MaintenanceWindow, error) {
+	rows, err := d.db.Query(`SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []*models.MaintenanceWindow
+	for rows.Next() {
+		w, err := scanMaintenanceWindow(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, nil
+}
+
+// GetMaintenanceWindow retrieves a single maintenance window, returning nil if it does not exist
+func (d *Database) GetMaintenanceWindow(id int) (*models.MaintenanceWindow, error) {
+	row := d.db.QueryRow(d.rebind(`SELECT `+maintenanceWindowColumns+` FROM maintenance_windows WHERE id = ?`), id)
+	w, err := scanMaintenanceWindow(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return w, err
+}
+
+// SaveMaintenanceWindow inserts or updates a maintenance window
+func (d *Database) SaveMaintenanceWindow(w *models.MaintenanceWindow) error {
+	now := time.Now()
+
+	if w.ID == 0 {
+		id, err := d.insertReturningID(`
+			INSERT INTO maintenance_windows (name, description, starts_at, ends_at, schedule, duration, timezone,
+				agent_ids, tags, rule_ids, enabled, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			w.Name, w.Description, nullTime(w.StartsAt), nullTime(w.EndsAt), w.Schedule, w.Duration, w.Timezone,
+			jsonList(w.AgentIDs), jsonList(w.Tags), jsonList(w.RuleIDs), w.Enabled, now, now,
+		)
+		if err != nil {
+			return err
+		}
+		w.ID = id
+		w.CreatedAt = now
+		return nil
+	}
+
+	_, err := d.db.Exec(d.rebind(`
+		UPDATE maintenance_windows SET name=?, description=?, starts_at=?, ends_at=?, schedule=?, duration=?,
+			timezone=?, agent_ids=?, tags=?, rule_ids=?, enabled=?, updated_at=?
+		WHERE id=?`),
+		w.Name, w.Description, nullTime(w.StartsAt), nullTime(w.EndsAt), w.Schedule, w.Duration,
+		w.Timezone, jsonList(w.AgentIDs), jsonList(w.Tags), jsonList(w.RuleIDs), w.Enabled, now, w.ID,
+	)
+	return err
+}
+
+// DeleteMaintenanceWindow deletes a maintenance window
+func (d *Database) DeleteMaintenanceWindow(id int) error {
+	_, err := d.db.Exec(d.rebind(`DELETE FROM maintenance_windows WHERE id = ?`), id)
+	return err
+}
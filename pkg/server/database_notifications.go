@@ -0,0 +1,311 @@
+package server
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// EnqueueNotification adds a notification to the outbox
+func (d *Database) EnqueueNotification(n *models.Notification) error {
+	now := time.Now()
+	if n.Status == "" {
+		n.Status = "pending"
+	}
+	if n.NextAttemptAt.IsZero() {
+		n.NextAttemptAt = now
+	}
+	n.CreatedAt = now
+
+	id, err := d.insertReturningID(`
+		INSERT INTO notification_outbox (alert_id, channel, recipient, subject, body, status,
+			attempts, next_attempt_at, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.AlertID, n.Channel, n.Recipient, n.Subject, n.Body, n.Status,
+		n.Attempts, n.NextAttemptAt, n.LastError, now, now,
+	)
+	if err != nil {
+		return err
+	}
+	n.ID = id
+	return nil
+}
+
+// GetDueNotifications retrieves pending notifications whose next attempt is due
+func (d *Database) GetDueNotifications(now time.Time, limit int) ([]*models.Notification, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT id, alert_id, channel, recipient, subject, body, status, attempts,
+			next_attempt_at, last_error, created_at
+		FROM notification_outbox
+		WHERE status = 'pending' AND next_attempt_at <= ?
+		ORDER BY next_attempt_at
+		LIMIT ?`), d.timeArg(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// GetNotifications retrieves outbox entries, optionally filtered by alert and status
+func (d *Database) GetNotifications(alertID int, status string, limit int) ([]*models.Notification, error) {
+	query := `
+		SELECT id, alert_id, channel, recipient, subject, body, status, attempts,
+			next_attempt_at, last_error, created_at
+		FROM notification_outbox
+		WHERE 1=1`
+	var args []interface{}
+	if alertID > 0 {
+		query += " AND alert_id = ?"
+		args = append(args, alertID)
+	}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanNotifications(rows)
+}
+
+// scanNotifications scans outbox rows selected in the column order used above
+func scanNotifications(rows *sql.Rows) ([]*models.Notification, error) {
+	var notifications []*models.Notification
+	for rows.Next() {
+		n := &models.Notification{}
+		var next, created dbTime
+		err := rows.Scan(&n.ID, &n.AlertID, &n.Channel, &n.Recipient, &n.Subject, &n.Body,
+			&n.Status, &n.Attempts, &next, &n.LastError, &created)
+		if err != nil {
+			return nil, err
+		}
+		n.NextAttemptAt = next.Time
+		n.CreatedAt = created.Time
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// UpdateNotification stores the delivery state of an outbox entry
+func (d *Database) UpdateNotification(n *models.Notification) error {
+	_, err := d.db.Exec(d.rebind(`
+		UPDATE notification_outbox SET status=?, attempts=?, next_attempt_at=?, last_error=?, updated_at=?
+		WHERE id=?`),
+		n.Status, n.Attempts, n.NextAttemptAt, n.LastError, time.Now(), n.ID,
+	)
+	return err
+}
+
+// SaveNotificationDelivery records a delivery attempt
+func (d *Database) SaveNotificationDelivery(delivery *models.NotificationDelivery) error {
+	id, err := d.insertReturningID(`
+		INSERT INTO notification_deliveries (notification_id, alert_id, channel, attempt, status, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		delivery.NotificationID, delivery.AlertID, delivery.Channel, delivery.Attempt,
+		delivery.Status, delivery.Error, delivery.Timestamp,
+	)
+	if err != nil {
+		return err
+	}
+	delivery.ID = id
+	return nil
+}
+
+// GetNotificationDeliveries retrieves the delivery log, optionally for a single alert
+func (d *Database) GetNotificationDeliveries(alertID int, limit int) ([]*models.NotificationDelivery, error) {
+	query := `
+		SELECT id, notification_id, alert_id, channel, attempt, status, error, created_at
+		FROM notification_deliveries`
+	var args []interface{}
+	if alertID > 0 {
+		query += " WHERE alert_id = ?"
+		args = append(args, alertID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*models.NotificationDelivery
+	for rows.Next() {
+		delivery := &models.NotificationDelivery{}
+		var created dbTime
+		err := rows.Scan(&delivery.ID, &delivery.NotificationID, &delivery.AlertID, &delivery.Channel,
+			&delivery.Attempt, &delivery.Status, &delivery.Error, &created)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Timestamp = created.Time
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// GetNotificationTemplates retrieves all notification templates
+func (d *Database) GetNotificationTemplates() ([]*models.NotificationTemplate, error) {
+	rows, err := d.db.Query(`
+		SELECT id, channel, rule_id, subject, body
+		FROM notification_templates
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.NotificationTemplate
+	for rows.Next() {
+		tpl := &models.NotificationTemplate{}
+		if err := rows.Scan(&tpl.ID, &tpl.Channel, &tpl.RuleID, &tpl.Subject, &tpl.Body); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tpl)
+	}
+
+	return templates, nil
+}
+
+// GetNotificationTemplate retrieves a single template, returning nil if it does not exist
+func (d *Database) GetNotificationTemplate(id int) (*models.NotificationTemplate, error) {
+	tpl := &models.NotificationTemplate{}
+	err := d.db.QueryRow(d.rebind(`
+		SELECT id, channel, rule_id, subject, body
+		FROM notification_templates
+		WHERE id = ?`), id).Scan(&tpl.ID, &tpl.Channel, &tpl.RuleID, &tpl.Subject, &tpl.Body)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// SaveNotificationTemplate inserts or updates a notification template
+func (d *Database) SaveNotificationTemplate(tpl *models.NotificationTemplate) error {
+	now := time.Now()
+
+	if tpl.ID == 0 {
+		id, err := d.insertReturningID(`
+			INSERT INTO notification_templates (channel, rule_id, subject, body, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			tpl.Channel, tpl.RuleID, tpl.Subject, tpl.Body, now, now,
+		)
+		if err != nil {
+			return err
+		}
+		tpl.ID = id
+		return nil
+	}
+
+	_, err := d.db.Exec(d.rebind(`
+		UPDATE notification_templates SET channel=?, rule_id=?, subject=?, body=?, updated_at=?
+		WHERE id=?`),
+		tpl.Channel, tpl.RuleID, tpl.Subject, tpl.Body, now, tpl.ID,
+	)
+	return err
+}
+
+// DeleteNotificationTemplate deletes a notification template
+func (d *Database) DeleteNotificationTemplate(id int) error {
+	_, err := d.db.Exec(d.rebind(`DELETE FROM notification_templates WHERE id = ?`), id)
+	return err
+}
+
+const notificationRouteColumns = `id, name, position, severities, tags, metric_types, channels, continue_matching`
+
+// scanNotificationRoute scans a row selected with notificationRouteColumns
+func scanNotificationRoute(scan func(dest ...interface{}) error) (*models.NotificationRoute, error) {
+	route := &models.NotificationRoute{}
+	var severities, tags, metricTypes, channels string
+	var cont interface{}
+	err := scan(&route.ID, &route.Name, &route.Position, &severities, &tags, &metricTypes, &channels, &cont)
+	if err != nil {
+		return nil, err
+	}
+	parseJSONList(severities, &route.Severities)
+	parseJSONList(tags, &route.Tags)
+	parseJSONList(metricTypes, &route.MetricTypes)
+	parseJSONList(channels, &route.Channels)
+	route.Continue = dbBool(cont)
+	return route, nil
+}
+
+// GetNotificationRoutes retrieves all notification routes in matching order
+func (d *Database) GetNotificationRoutes() ([]*models.NotificationRoute, error) {
+	rows, err := d.db.Query(`SELECT ` + notificationRouteColumns + ` FROM notification_routes ORDER BY position, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []*models.NotificationRoute
+	for rows.Next() {
+		route, err := scanNotificationRoute(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// GetNotificationRoute retrieves a single route, returning nil if it does not exist
+func (d *Database) GetNotificationRoute(id int) (*models.NotificationRoute, error) {
+	row := d.db.QueryRow(d.rebind(`SELECT `+notificationRouteColumns+` FROM notification_routes WHERE id = ?`), id)
+	route, err := scanNotificationRoute(row.Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return route, err
+}
+
+// SaveNotificationRoute inserts or updates a notification route
+func (d *Database) SaveNotificationRoute(route *models.NotificationRoute) error {
+	now := time.Now()
+
+	if route.ID == 0 {
+		id, err := d.insertReturningID(`
+			INSERT INTO notification_routes (name, position, severities, tags, metric_types, channels,
+				continue_matching, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			route.Name, route.Position, jsonList(route.Severities), jsonList(route.Tags),
+			jsonList(route.MetricTypes), jsonList(route.Channels), route.Continue, now, now,
+		)
+		if err != nil {
+			return err
+		}
+		route.ID = id
+		return nil
+	}
+
+	_, err := d.db.Exec(d.rebind(`
+		UPDATE notification_routes SET name=?, position=?, severities=?, tags=?, metric_types=?, channels=?,
+			continue_matching=?, updated_at=?
+		WHERE id=?`),
+		route.Name, route.Position, jsonList(route.Severities), jsonList(route.Tags),
+		jsonList(route.MetricTypes), jsonList(route.Channels), route.Continue, now, route.ID,
+	)
+	return err
+}
+
+// DeleteNotificationRoute deletes a notification route
+func (d *Database) DeleteNotificationRoute(id int) error {
+	_, err := d.db.Exec(d.rebind(`DELETE FROM notification_routes WHERE id = ?`), id)
+	return err
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"math"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// findDisk returns the I/O of a device in a sample
+func findDisk(m *models.Metrics, device string) (models.DiskIOMetrics, bool) {
+	for _, disk := range m.Disks {
+		if disk.Device == device {
+			return disk, true
+		}
+	}
+	return models.DiskIOMetrics{}, false
+}
+
+// diskView returns a copy of a sample whose disk I/O fields are totalled
+// over the given devices the same way the agent totals all of them
+func diskView(m *models.Metrics, disks []models.DiskIOMetrics) *models.Metrics {
+	view := *m
+	view.Disks = disks
+	view.DiskReadBps, view.DiskWriteBps = 0, 0
+	view.DiskReadIOPS, view.DiskWriteIOPS = 0, 0
+	view.DiskUtilPercent, view.DiskAwaitMs = 0, 0
+
+	var ops, waited float64
+	for _, disk := range disks {
+		view.DiskReadBps += disk.ReadBps
+		view.DiskWriteBps += disk.WriteBps
+		view.DiskReadIOPS += disk.ReadIOPS
+		view.DiskWriteIOPS += disk.WriteIOPS
+		view.DiskUtilPercent = math.Max(view.DiskUtilPercent, disk.UtilPercent)
+		ops += disk.ReadIOPS + disk.WriteIOPS
+		waited += disk.AwaitMs * (disk.ReadIOPS + disk.WriteIOPS)
+	}
+	if ops > 0 {
+		view.DiskAwaitMs = waited / ops
+	}
+	return &view
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const escalationCheckInterval = 30 * time.Second
+
+// onCallAt returns who is on call on a schedule at t, or "" if nobody is
+func onCallAt(sch *models.OnCallSchedule, t time.Time) string {
+	// Later overrides win over earlier ones
+	for i := len(sch.Overrides) - 1; i >= 0; i-- {
+		o := sch.Overrides[i]
+		if !t.Before(o.Start) && t.Before(o.End) {
+			return o.Participant
+		}
+	}
+
+	var current *models.OnCallRotation
+	for i := range sch.Rotations {
+		r := &sch.Rotations[i]
+		if t.Before(r.Start) || (!r.End.IsZero() && !t.Before(r.End)) {
+			continue
+		}
+		if current == nil || r.Start.After(current.Start) {
+			current = r
+		}
+	}
+	if current == nil || len(current.Participants) == 0 || current.ShiftLength <= 0 {
+		return ""
+	}
+
+	shift := int64(t.Sub(current.Start) / (time.Duration(current.ShiftLength) * time.Second))
+	return current.Participants[shift%int64(len(current.Participants))]
+}
+
+// RunEscalations notifies the next escalation steps of unacknowledged
+// alerts until stop is closed
+func (n *Notifier) RunEscalations(stop <-chan struct{}) {
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if !n.config.Installed {
+				continue
+			}
+			if err := n.CheckEscalations(now); err != nil {
+				log.Printf("Failed to check escalations: %v", err)
+			}
+		}
+	}
+}
+
+// CheckEscalations notifies every escalation step that has become due
+func (n *Notifier) CheckEscalations(now time.Time) error {
+	alerts, err := n.db.GetEscalatingAlerts()
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	rules, err := n.db.GetAlertRules()
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]*models.AlertRule, len(rules))
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+
+	for _, alert := range alerts {
+		rule := byID[alert.RuleID]
+		if rule == nil || rule.EscalationPolicyID == 0 {
+			continue
+		}
+		if err := n.escalate(alert, rule, now); err != nil {
+			log.Printf("Failed to escalate alert %d: %v", alert.ID, err)
+		}
+	}
+	return nil
+}
+
+// escalate notifies the steps of the rule's escalation policy that are due
+// for an alert and have not been notified yet
+func (n *Notifier) escalate(alert *models.Alert, rule *models.AlertRule, now time.Time) error {
+	policy, err := n.db.GetEscalationPolicy(rule.EscalationPolicyID)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		// The policy was deleted; fall back to notifying every channel once
+		if alert.EscalationStep > 0 {
+			return nil
+		}
+		if err := n.Enqueue(alert, rule); err != nil {
+			return err
+		}
+		alert.EscalationStep = 1
+		return n.db.SetAlertEscalationStep(alert.ID, alert.EscalationStep)
+	}
+
+	step := alert.EscalationStep
+	for step < len(policy.Steps) {
+		s := policy.Steps[step]
+		if alert.Timestamp.Add(time.Duration(s.Delay) * time.Second).After(now) {
+			break
+		}
+		if err := n.enqueueTargets(alert, rule, n.stepTargets(&s, now)); err != nil {
+			return err
+		}
+		step++
+	}
+
+	if step == alert.EscalationStep {
+		return nil
+	}
+	alert.EscalationStep = step
+	return n.db.SetAlertEscalationStep(alert.ID, step)
+}
+
+// stepTargets resolves the channels and on-call people of an escalation step
+func (n *Notifier) stepTargets(step *models.EscalationStep, now time.Time) []notifyTarget {
+	var targets []notifyTarget
+	for _, channel := range step.Channels {
+		targets = append(targets, notifyTarget{channel: channel})
+	}
+	for _, id := range step.ScheduleIDs {
+		sch, err := n.db.GetOnCallSchedule(id)
+		if err != nil {
+			log.Printf("Failed to load on-call schedule %d: %v", id, err)
+			continue
+		}
+		if sch == nil {
+			continue
+		}
+		person := onCallAt(sch, now)
+		if person == "" {
+			log.Printf("Nobody is on call on schedule %q", sch.Name)
+			continue
+		}
+		targets = append(targets, notifyTarget{channel: sch.Channel, recipient: person})
+	}
+	return targets
+}
+
+// validateEscalationPolicy checks an escalation policy before it is stored
+func validateEscalationPolicy(p *models.EscalationPolicy, channels []string) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return &ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	if len(p.Steps) == 0 {
+		return &ValidationError{Field: "steps", Message: "must contain at least one step"}
+	}
+
+	previous := -1
+	for i, step := range p.Steps {
+		field := fmt.Sprintf("steps[%d]", i)
+		if step.Delay < 0 || step.Delay < previous {
+			return &ValidationError{Field: field + ".delay", Message: "must not be negative or earlier than the previous step"}
+		}
+		previous = step.Delay
+		if len(step.Channels) == 0 && len(step.ScheduleIDs) == 0 {
+			return &ValidationError{Field: field, Message: "must notify at least one channel or schedule"}
+		}
+		for _, channel := range step.Channels {
+			if !containsString(channels, channel) {
+				return &ValidationError{Field: field + ".channels", Message: fmt.Sprintf("unknown channel %q", channel)}
+			}
+		}
+	}
+	return nil
+}
+
+// validateOnCallSchedule checks an on-call schedule before it is stored
+func validateOnCallSchedule(sch *models.OnCallSchedule, channels []string) error {
+	if strings.TrimSpace(sch.Name) == "" {
+		return &ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	if !containsString(channels, sch.Channel) {
+		return &ValidationError{Field: "channel", Message: fmt.Sprintf("unknown channel %q", sch.Channel)}
+	}
+
+	for i, r := range sch.Rotations {
+		field := fmt.Sprintf("rotations[%d]", i)
+		if r.Start.IsZero() {
+			return &ValidationError{Field: field + ".start", Message: "must be set"}
+		}
+		if !r.End.IsZero() && !r.End.After(r.Start) {
+			return &ValidationError{Field: field + ".end", Message: "must be after start"}
+		}
+		if r.ShiftLength <= 0 {
+			return &ValidationError{Field: field + ".shift_length", Message: "must be positive"}
+		}
+		if len(r.Participants) == 0 {
+			return &ValidationError{Field: field + ".participants", Message: "must not be empty"}
+		}
+	}
+	for i, o := range sch.Overrides {
+		field := fmt.Sprintf("overrides[%d]", i)
+		if o.Participant == "" {
+			return &ValidationError{Field: field + ".participant", Message: "must not be empty"}
+		}
+		if !o.End.After(o.Start) {
+			return &ValidationError{Field: field + ".end", Message: "must be after start"}
+		}
+	}
+	return nil
+}
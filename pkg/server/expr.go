@@ -0,0 +1,664 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// maxExprWindow bounds the window functions may look back over, since
+// history is kept in memory by the alerter
+const maxExprWindow = time.Hour
+
+// ExprError reports an invalid alert rule expression
+type ExprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("invalid expression at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// exprEnv is the data an expression is evaluated against
+type exprEnv struct {
+	current *models.Metrics
+	history []*models.Metrics // oldest first, including current
+
+	// observed is the left-hand side of the first comparison evaluated and
+	// is reported as the alert value
+	observed    float64
+	hasObserved bool
+}
+
+// window returns the samples within d of the current sample
+func (e *exprEnv) window(d time.Duration) []*models.Metrics {
+	since := e.current.Timestamp.Add(-d)
+	i := len(e.history)
+	for i > 0 && !e.history[i-1].Timestamp.Before(since) {
+		i--
+	}
+	return e.history[i:]
+}
+
+// Expression is a parsed alert rule expression
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ParseExpression parses and validates an alert rule expression such as
+// "avg(cpu, 5m) > 85 and mem_percent > 90"
+func ParseExpression(source string) (*Expression, error) {
+	p := &exprParser{src: source}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	if !root.boolean() {
+		return nil, &ExprError{Pos: 0, Msg: "expression must be a comparison, e.g. cpu > 90"}
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression, returning whether it holds and the value
+// that was compared
+func (e *Expression) Eval(env *exprEnv) (bool, float64) {
+	result := e.root.eval(env) != 0
+	return result, env.observed
+}
+
+// Token kinds
+const (
+	tokEOF = iota
+	tokNumber
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type exprToken struct {
+	kind  int
+	text  string
+	value float64
+	pos   int
+}
+
+type exprParser struct {
+	src    string
+	tokens []exprToken
+	pos    int
+}
+
+// Unit multipliers for numeric literals. Sizes are binary, rates share the
+// multiplier of their size and durations are expressed in seconds.
+var exprUnits = map[string]float64{
+	"%":  1,
+	"b":  1,
+	"kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30, "tb": 1 << 40,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+	"b/s":  1,
+	"kb/s": 1 << 10, "mb/s": 1 << 20, "gb/s": 1 << 30,
+	"ms": 0.001, "s": 1, "m": 60, "h": 3600, "d": 86400,
+}
+
+// durationUnits are the units accepted for window arguments
+var durationUnits = map[string]bool{"ms": true, "s": true, "m": true, "h": true, "d": true}
+
+// lex splits the source into tokens
+func (p *exprParser) lex() error {
+	src := p.src
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return &ExprError{Pos: start, Msg: fmt.Sprintf("invalid number %q", src[start:i])}
+			}
+
+			// Optional unit suffix, e.g. 5m, 90%, 100MB/s
+			unitStart := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || src[i] == '%') {
+				i++
+			}
+			if i > unitStart && strings.HasPrefix(src[i:], "/s") &&
+				(i+2 == len(src) || !unicode.IsLetter(rune(src[i+2])) && src[i+2] != '_') {
+				i += 2
+			}
+			text := src[start:i]
+			if unit := strings.ToLower(src[unitStart:i]); unit != "" {
+				mult, ok := exprUnits[unit]
+				if !ok {
+					return &ExprError{Pos: unitStart, Msg: fmt.Sprintf("unknown unit %q", src[unitStart:i])}
+				}
+				value *= mult
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokNumber, text: text, value: value, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_') {
+				i++
+			}
+			word := src[start:i]
+			switch strings.ToLower(word) {
+			case "and", "or", "not":
+				p.tokens = append(p.tokens, exprToken{kind: tokOp, text: strings.ToLower(word), pos: start})
+			default:
+				p.tokens = append(p.tokens, exprToken{kind: tokIdent, text: word, pos: start})
+			}
+
+		case c == '(':
+			p.tokens = append(p.tokens, exprToken{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, exprToken{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			p.tokens = append(p.tokens, exprToken{kind: tokComma, text: ",", pos: i})
+			i++
+
+		default:
+			op := ""
+			for _, candidate := range []string{">=", "<=", "==", "!=", "&&", "||", ">", "<", "+", "-", "*", "/", "!"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return &ExprError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			text := op
+			switch op {
+			case "&&":
+				text = "and"
+			case "||":
+				text = "or"
+			case "!":
+				text = "not"
+			}
+			p.tokens = append(p.tokens, exprToken{kind: tokOp, text: text, pos: i})
+			i += len(op)
+		}
+	}
+
+	p.tokens = append(p.tokens, exprToken{kind: tokEOF, text: "end of expression", pos: len(src)})
+	return nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) acceptOp(ops ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return tok, true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("or")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "or", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("and")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "and", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if tok, ok := p.acceptOp("not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, operand); err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.acceptOp(">", "<", ">=", "<=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if left.boolean() || right.boolean() {
+		return nil, &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("%q compares numbers, not conditions", tok.text)}
+	}
+	return &compareNode{op: tok.text, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireNumber(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.acceptOp("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireNumber(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok, ok := p.acceptOp("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireNumber(tok, operand); err != nil {
+			return nil, err
+		}
+		return &arithNode{op: "-", left: &numberNode{value: 0}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &numberNode{value: tok.value}, nil
+
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &ExprError{Pos: closing.pos, Msg: fmt.Sprintf("expected ) but found %q", closing.text)}
+		}
+		return inner, nil
+
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		name := strings.ToLower(tok.text)
+		if _, ok := metricAccessors[name]; !ok {
+			return nil, &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("unknown metric %q", tok.text)}
+		}
+		if metricAccessors[name].target == targetCustom {
+			return nil, &ExprError{Pos: tok.pos, Msg: "custom series cannot be used in expressions"}
+		}
+		return &metricNode{name: name}, nil
+	}
+
+	return nil, &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+// parseCall parses a window function call such as avg(cpu, 5m)
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	fn := strings.ToLower(name.text)
+	if _, ok := windowFuncs[fn]; !ok && fn != "rate" {
+		return nil, &ExprError{Pos: name.pos, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	p.next() // (
+
+	arg := p.next()
+	if arg.kind != tokIdent {
+		return nil, &ExprError{Pos: arg.pos, Msg: fmt.Sprintf("%s() expects a metric name", fn)}
+	}
+	metric := strings.ToLower(arg.text)
+	if _, ok := metricAccessors[metric]; !ok {
+		return nil, &ExprError{Pos: arg.pos, Msg: fmt.Sprintf("unknown metric %q", arg.text)}
+	}
+	if metricAccessors[metric].target == targetCustom {
+		return nil, &ExprError{Pos: arg.pos, Msg: "custom series cannot be used in expressions"}
+	}
+
+	call := &callNode{fn: fn, metric: metric}
+	if p.peek().kind == tokComma {
+		p.next()
+		window := p.next()
+		if window.kind != tokNumber || !durationUnits[strings.ToLower(strings.TrimLeft(window.text, "0123456789."))] {
+			return nil, &ExprError{Pos: window.pos, Msg: "window must be a duration such as 30s, 5m or 1h"}
+		}
+		call.window = time.Duration(window.value * float64(time.Second))
+		if call.window <= 0 || call.window > maxExprWindow {
+			return nil, &ExprError{Pos: window.pos, Msg: fmt.Sprintf("window must be between 1s and %s", maxExprWindow)}
+		}
+	} else if fn != "rate" {
+		return nil, &ExprError{Pos: p.peek().pos, Msg: fmt.Sprintf("%s() requires a window, e.g. %s(%s, 5m)", fn, fn, metric)}
+	}
+
+	if closing := p.next(); closing.kind != tokRParen {
+		return nil, &ExprError{Pos: closing.pos, Msg: fmt.Sprintf("expected ) but found %q", closing.text)}
+	}
+	return call, nil
+}
+
+func requireBool(tok exprToken, nodes ...exprNode) error {
+	for _, n := range nodes {
+		if !n.boolean() {
+			return &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("%q expects conditions on both sides", tok.text)}
+		}
+	}
+	return nil
+}
+
+func requireNumber(tok exprToken, nodes ...exprNode) error {
+	for _, n := range nodes {
+		if n.boolean() {
+			return &ExprError{Pos: tok.pos, Msg: fmt.Sprintf("%q expects numbers, not conditions", tok.text)}
+		}
+	}
+	return nil
+}
+
+// exprNode is a node of the expression tree. Boolean nodes evaluate to 1 or 0.
+type exprNode interface {
+	eval(env *exprEnv) float64
+	boolean() bool
+}
+
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) eval(*exprEnv) float64 { return n.value }
+func (n *numberNode) boolean() bool         { return false }
+
+type metricNode struct {
+	name string
+}
+
+func (n *metricNode) eval(env *exprEnv) float64 {
+	return metricAccessors[n.name].value(env.current)
+}
+func (n *metricNode) boolean() bool { return false }
+
+type callNode struct {
+	fn     string
+	metric string
+	window time.Duration
+}
+
+func (n *callNode) eval(env *exprEnv) float64 {
+	accessor := metricAccessors[n.metric]
+	if n.fn == "rate" {
+		return rate(env, accessor, n.window)
+	}
+
+	samples := env.window(n.window)
+	values := make([]float64, len(samples))
+	for i, m := range samples {
+		values[i] = accessor.value(m)
+	}
+	return windowFuncs[n.fn](values)
+}
+func (n *callNode) boolean() bool { return false }
+
+type arithNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *arithNode) eval(env *exprEnv) float64 {
+	l, r := n.left.eval(env), n.right.eval(env)
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	}
+	return 0
+}
+func (n *arithNode) boolean() bool { return false }
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(env *exprEnv) float64 {
+	l, r := n.left.eval(env), n.right.eval(env)
+	if !env.hasObserved {
+		env.observed = l
+		env.hasObserved = true
+	}
+
+	var result bool
+	switch n.op {
+	case ">":
+		result = l > r
+	case "<":
+		result = l < r
+	case ">=":
+		result = l >= r
+	case "<=":
+		result = l <= r
+	case "==":
+		result = l == r
+	case "!=":
+		result = l != r
+	}
+	return boolValue(result)
+}
+func (n *compareNode) boolean() bool { return true }
+
+type logicalNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *logicalNode) eval(env *exprEnv) float64 {
+	l := n.left.eval(env) != 0
+	if n.op == "and" && !l {
+		return 0
+	}
+	if n.op == "or" && l {
+		return 1
+	}
+	return boolValue(n.right.eval(env) != 0)
+}
+func (n *logicalNode) boolean() bool { return true }
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n *notNode) eval(env *exprEnv) float64 { return boolValue(n.operand.eval(env) == 0) }
+func (n *notNode) boolean() bool             { return true }
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// windowFuncs aggregate the values of a metric over a window
+var windowFuncs = map[string]func(values []float64) float64{
+	"avg": func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	},
+	"min": func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		result := math.Inf(1)
+		for _, v := range values {
+			result = math.Min(result, v)
+		}
+		return result
+	},
+	"max": func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		result := math.Inf(-1)
+		for _, v := range values {
+			result = math.Max(result, v)
+		}
+		return result
+	},
+	"p95": func(values []float64) float64 {
+		return percentile(values, 95)
+	},
+}
+
+// rate returns the per-second rate of a metric. Metrics that are already
+// rates are averaged over the window, gauges are differenced between the
+// first and last sample. Without a window the last two samples are used.
+func rate(env *exprEnv, accessor metricAccessor, window time.Duration) float64 {
+	var samples []*models.Metrics
+	if window > 0 {
+		samples = env.window(window)
+	} else if n := len(env.history); n >= 2 {
+		samples = env.history[n-2:]
+	}
+	if len(samples) < 2 {
+		return 0
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+	elapsed := last.Timestamp.Sub(first.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+
+	if accessor.rate {
+		// Each rate covers the time since the sample before it, so the
+		// first sample's covers time before the window
+		sum := 0.0
+		for i, m := range samples[1:] {
+			sum += accessor.value(m) * m.Timestamp.Sub(samples[i].Timestamp).Seconds()
+		}
+		return sum / elapsed
+	}
+	return (accessor.value(last) - accessor.value(first)) / elapsed
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		pos    int
+		msg    string
+	}{
+		{"", 0, `unexpected "end of expression"`},
+		{"cpu", 0, "must be a comparison"},
+		{"cpu + 1", 0, "must be a comparison"},
+		{"cpu >", 5, `unexpected "end of expression"`},
+		{"cpu > 90 90", 9, `unexpected "90"`},
+		{"cpu > 90)", 8, `unexpected ")"`},
+		{"cpu > 1 > 2", 8, `unexpected ">"`},
+		{"(cpu > 90", 9, `expected ) but found "end of expression"`},
+		{"cpu > 90 $", 9, "unexpected character '$'"},
+		{"cpu > 1.2.3", 6, `invalid number "1.2.3"`},
+		{"cpu > 9x", 7, `unknown unit "x"`},
+		{"cpux > 90", 0, `unknown metric "cpux"`},
+		{"avg(cpux, 5m) > 90", 4, `unknown metric "cpux"`},
+		{"custom > 1", 0, "custom series cannot be used"},
+		{"median(cpu, 5m) > 1", 0, `unknown function "median"`},
+		{"avg() > 1", 4, "avg() expects a metric name"},
+		{"avg(cpu) > 1", 7, "avg() requires a window"},
+		{"avg(cpu, 5m, 1m) > 1", 11, `expected ) but found ","`},
+		{"avg(cpu, 5) > 1", 9, "window must be a duration"},
+		{"avg(cpu, 5MB) > 1", 9, "window must be a duration"},
+		{"avg(cpu, 2h) > 1", 9, "window must be between"},
+		{"cpu > 90 and 5", 9, `"and" expects conditions`},
+		{"not cpu", 0, `"not" expects conditions`},
+		{"(cpu > 1) + 1 > 2", 10, `"+" expects numbers`},
+		{"-(cpu > 1) < 0", 0, `"-" expects numbers`},
+		{"(cpu > 1) == (cpu > 2)", 10, `"==" compares numbers`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseExpression(tt.source)
+		var exprErr *ExprError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("ParseExpression(%q) = %v, want an ExprError", tt.source, err)
+			continue
+		}
+		if exprErr.Pos != tt.pos || !strings.Contains(exprErr.Msg, tt.msg) {
+			t.Errorf("ParseExpression(%q) = %q at %d, want %q at %d", tt.source, exprErr.Msg, exprErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestExpressionEval(t *testing.T) {
+	now := time.Now()
+	sample := func(ago time.Duration, cpu float64, rx uint64) *models.Metrics {
+		return &models.Metrics{Timestamp: now.Add(-ago), CPUPercent: cpu, LoadAvg1: 2, NetworkRx: rx, MemoryUsed: 2 << 30}
+	}
+	history := []*models.Metrics{
+		sample(3*time.Minute, 10, 100),
+		sample(2*time.Minute, 20, 200),
+		sample(time.Minute, 30, 300),
+		sample(0, 50, 400),
+	}
+
+	tests := []struct {
+		source   string
+		want     bool
+		observed float64
+	}{
+		// Precedence and associativity
+		{"cpu + 10 * 2 == 70", true, 70},
+		{"(cpu + 10) * 2 == 120", true, 120},
+		{"cpu - 10 - 20 == 20", true, 20},
+		{"cpu / 5 / 2 == 5", true, 5},
+		{"cpu > 40 or cpu > 90 and cpu > 95", true, 50},
+		{"(cpu > 40 or cpu > 90) and cpu > 95", false, 50},
+		{"not cpu > 90 and cpu > 40", true, 50},
+		{"cpu > 40 && !(cpu > 90)", true, 50},
+
+		// Unary minus
+		{"-cpu < 0", true, -50},
+		{"- -cpu == 50", true, 50},
+		{"2 * -load1 == -4", true, -4},
+		{"-load1 * -load1 == 4", true, 4},
+
+		// Division by zero yields zero rather than an infinity
+		{"cpu / 0 == 0", true, 0},
+		{"0 / 0 == 0", true, 0},
+		{"cpu / (load1 - 2) > 1", false, 0},
+
+		// Units and window functions
+		{"mem_used_bytes >= 2GB", true, 2 << 30},
+		{"cpu < 50%", false, 50},
+		{"avg(cpu, 90s) == 40", true, 40},
+		{"min(cpu, 5m) == 10", true, 10},
+		{"max(CPU, 1h) == 50", true, 50},
+		{"rate(network_rx) == 400", true, 400},
+
+		// The first comparison evaluated is the one reported
+		{"load1 > 5 or cpu > 40", true, 2},
+		{"cpu > 90 and load1 > 1", false, 50},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpression(tt.source)
+		if err != nil {
+			t.Errorf("ParseExpression(%q): %v", tt.source, err)
+			continue
+		}
+		got, observed := expr.Eval(&exprEnv{current: history[len(history)-1], history: history})
+		if got != tt.want || observed != tt.observed {
+			t.Errorf("%q = %v (%v), want %v (%v)", tt.source, got, observed, tt.want, tt.observed)
+		}
+	}
+}
+
+func TestExpressionEvalNaN(t *testing.T) {
+	current := &models.Metrics{Timestamp: time.Now(), CPUPercent: math.NaN()}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		// NaN compares false against everything but !=
+		{"cpu > 90", false},
+		{"cpu <= 90", false},
+		{"cpu == cpu", false},
+		{"cpu != 90", true},
+		{"not cpu > 90", true},
+		{"cpu * 0 == 0", false},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpression(tt.source)
+		if err != nil {
+			t.Errorf("ParseExpression(%q): %v", tt.source, err)
+			continue
+		}
+		got, observed := expr.Eval(&exprEnv{current: current, history: []*models.Metrics{current}})
+		if got != tt.want || !math.IsNaN(observed) {
+			t.Errorf("%q = %v (%v), want %v (NaN)", tt.source, got, observed, tt.want)
+		}
+	}
+}
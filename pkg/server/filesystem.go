@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// filesystemView returns a copy of a sample whose disk fields hold the usage
+// of a single filesystem, so disk rules can be evaluated against it unchanged
+func filesystemView(m *models.Metrics, fs models.FilesystemMetrics) *models.Metrics {
+	view := *m
+	view.DiskUsed = fs.Used
+	view.DiskTotal = fs.Total
+	view.Filesystems = []models.FilesystemMetrics{fs}
+	return &view
+}
+
+// findFilesystem returns the filesystem mounted at mountpoint in a sample
+func findFilesystem(m *models.Metrics, mountpoint string) (models.FilesystemMetrics, bool) {
+	for _, fs := range m.Filesystems {
+		if fs.Mountpoint == mountpoint {
+			return fs, true
+		}
+	}
+	return models.FilesystemMetrics{}, false
+}
+
+// primaryFilesystem returns the root filesystem of a sample, or its only
+// filesystem for a view of a single mount
+func primaryFilesystem(m *models.Metrics) (models.FilesystemMetrics, bool) {
+	if len(m.Filesystems) == 1 {
+		return m.Filesystems[0], true
+	}
+	return findFilesystem(m, "/")
+}
+
+func inodesPercent(m *models.Metrics) float64 {
+	fs, ok := primaryFilesystem(m)
+	if !ok || fs.InodesTotal == 0 {
+		return 0
+	}
+	return float64(fs.InodesUsed) / float64(fs.InodesTotal) * 100
+}
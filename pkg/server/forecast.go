@@ -0,0 +1,313 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	// forecastFitPeriod is how much recent history a trend is fitted to
+	forecastFitPeriod = 24 * time.Hour
+
+	// forecastRefreshInterval is how often a trend is fitted again
+	forecastRefreshInterval = 5 * time.Minute
+
+	// minForecastSamples is how many samples a trend needs to be trusted
+	minForecastSamples = 10
+
+	// maxForecastHours bounds the thresholds of forecast rules
+	maxForecastHours = 24 * 365
+)
+
+// forecastResource reads the usage and capacity of a resource that can fill up
+type forecastResource struct {
+	used       func(m *models.Metrics) uint64
+	total      func(m *models.Metrics) uint64
+	filesystem bool // the resource can be forecast per mountpoint
+}
+
+// forecastResources maps the metric types of forecast rules to their resources
+var forecastResources = map[string]forecastResource{
+	"disk": {
+		used:       func(m *models.Metrics) uint64 { return m.DiskUsed },
+		total:      func(m *models.Metrics) uint64 { return m.DiskTotal },
+		filesystem: true,
+	},
+	"memory": {
+		used:  func(m *models.Metrics) uint64 { return m.MemoryUsed },
+		total: func(m *models.Metrics) uint64 { return m.MemoryTotal },
+	},
+}
+
+// trend is a straight line fitted to the usage of a resource
+type trend struct {
+	slope    float64 // bytes per second
+	samples  int
+	last     *models.Metrics // newest sample the trend was fitted to
+	fittedAt time.Time
+}
+
+// fitSlope returns the least squares slope of values over times, in units
+// per second
+func fitSlope(times []time.Time, values []float64) float64 {
+	if len(times) < 2 {
+		return 0
+	}
+	origin := times[0]
+	var sumX, sumY, sumXY, sumXX float64
+	for i, t := range times {
+		x := t.Sub(origin).Seconds()
+		sumX += x
+		sumY += values[i]
+		sumXY += x * values[i]
+		sumXX += x * x
+	}
+	n := float64(len(times))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// fitTrend fits a trend to the usage of a resource, or of the filesystem
+// mounted at target, over the period before now
+func (a *Alerter) fitTrend(agentID, resourceName, target string, now time.Time) (*trend, error) {
+	resource := forecastResources[resourceName]
+	t := &trend{fittedAt: now}
+	var times []time.Time
+	var values []float64
+	err := a.db.eachSample(agentID, resourceName, target, now.Add(-forecastFitPeriod), now, func(m *models.Metrics) {
+		if resource.total(m) == 0 {
+			return
+		}
+		times = append(times, m.Timestamp)
+		values = append(values, float64(resource.used(m)))
+		t.last = m
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.samples = len(times)
+	t.slope = fitSlope(times, values)
+	return t, nil
+}
+
+// trendKey identifies the trend of a resource of an agent
+type trendKey struct {
+	agentID, resource, target string
+}
+
+// trend returns the cached trend of a resource of an agent. Once it is older
+// than forecastRefreshInterval it is fitted again in the background, and
+// until the first fit is done nil is returned.
+func (a *Alerter) trend(agentID, resourceName, target string, now time.Time) (*trend, error) {
+	key := trendKey{agentID, resourceName, target}
+
+	a.mu.Lock()
+	t := a.trends[key]
+	if t != nil && now.Sub(t.fittedAt) < forecastRefreshInterval {
+		a.mu.Unlock()
+		return t, nil
+	}
+
+	if a.learnInline {
+		a.mu.Unlock()
+		t, err := a.fitTrend(agentID, resourceName, target, now)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		a.trends[key] = t
+		a.mu.Unlock()
+		return t, nil
+	}
+
+	if !a.fitting[key] {
+		a.fitting[key] = true
+		go a.refreshTrend(key, now)
+	}
+	a.mu.Unlock()
+	return t, nil
+}
+
+// refreshTrend fits a trend in the background and caches it
+func (a *Alerter) refreshTrend(key trendKey, now time.Time) {
+	a.learners <- struct{}{}
+	t, err := a.fitTrend(key.agentID, key.resource, key.target, now)
+	<-a.learners
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.fitting, key)
+	if err != nil {
+		log.Printf("Failed to fit the %s trend of agent %s: %v", key.resource, key.agentID, err)
+		return
+	}
+	a.trends[key] = t
+}
+
+// pruneTrends drops the trends no enabled forecast rule uses, such as those
+// of deleted rules, removed agents or unmounted filesystems. Trends fitted
+// for the forecasts page are kept until they are due to be fitted again.
+func (a *Alerter) pruneTrends(rules []*models.AlertRule) {
+	var forecastRules []*models.AlertRule
+	for _, rule := range rules {
+		if rule.Enabled && rule.Type == "forecast" {
+			forecastRules = append(forecastRules, rule)
+		}
+	}
+	wanted := func(key trendKey) bool {
+		for _, rule := range forecastRules {
+			if rule.MetricType == key.resource && rule.Target == key.target &&
+				(rule.AgentID == "" || rule.AgentID == key.agentID) {
+				return true
+			}
+		}
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for key, t := range a.trends {
+		if !wanted(key) && time.Since(t.fittedAt) >= forecastRefreshInterval {
+			delete(a.trends, key)
+		}
+	}
+}
+
+// hoursUntilFull returns how long until used reaches total at the trend's
+// rate, or false if usage is not growing or the trend is not trusted yet
+func hoursUntilFull(t *trend, used, total uint64) (float64, bool) {
+	if t.samples < minForecastSamples || t.slope <= 0 {
+		return 0, false
+	}
+	if used >= total {
+		return 0, true
+	}
+	return float64(total-used) / t.slope / 3600, true
+}
+
+// evaluateForecast checks how many hours remain until a resource is full.
+// Rules do not fire while usage is flat or shrinking, and are not judged
+// until a trend has been fitted.
+func (a *Alerter) evaluateForecast(rule *models.AlertRule, metrics *models.Metrics) (verdict, float64, error) {
+	resource := forecastResources[rule.MetricType]
+	t, err := a.trend(metrics.AgentID, rule.MetricType, rule.Target, metrics.Timestamp)
+	if err != nil {
+		return verdictUnknown, 0, err
+	}
+	if t == nil {
+		return verdictUnknown, 0, nil
+	}
+	hours, ok := hoursUntilFull(t, resource.used(metrics), resource.total(metrics))
+	if !ok {
+		return verdictClear, 0, nil
+	}
+	return judge(checkThreshold(hours, rule.Threshold, rule.Operator)), hours, nil
+}
+
+// Forecasts predicts when each resource of an agent will be full. Disks are
+// forecast per mountpoint, falling back to the root filesystem for agents
+// that do not report their filesystems.
+func (a *Alerter) Forecasts(agentID string, now time.Time) ([]*models.Forecast, error) {
+	filesystems, err := a.db.GetLatestFilesystems(agentID)
+	if err != nil {
+		return nil, err
+	}
+	mountpoints := []string{""}
+	if len(filesystems) > 0 {
+		mountpoints = mountpoints[:0]
+		for _, fs := range filesystems {
+			mountpoints = append(mountpoints, fs.Mountpoint)
+		}
+	}
+
+	var forecasts []*models.Forecast
+	for _, name := range []string{"disk", "memory"} {
+		resource := forecastResources[name]
+		targets := []string{""}
+		if resource.filesystem {
+			targets = mountpoints
+		}
+		for _, target := range targets {
+			f, err := a.forecast(agentID, name, target, now)
+			if err != nil {
+				return nil, err
+			}
+			if f != nil {
+				forecasts = append(forecasts, f)
+			}
+		}
+	}
+	return forecasts, nil
+}
+
+// forecast predicts when a resource, or the filesystem mounted at target,
+// will be full. It returns nil if nothing has been stored for it yet.
+func (a *Alerter) forecast(agentID, name, target string, now time.Time) (*models.Forecast, error) {
+	resource := forecastResources[name]
+	t, err := a.trend(agentID, name, target, now)
+	if err == nil && t == nil {
+		// Nothing fitted yet, so fit it now rather than show nothing
+		t, err = a.fitTrend(agentID, name, target, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.last == nil {
+		return nil, nil
+	}
+
+	f := &models.Forecast{
+		AgentID:       agentID,
+		Resource:      name,
+		Filesystem:    target,
+		Used:          resource.used(t.last),
+		Total:         resource.total(t.last),
+		GrowthPerHour: t.slope * 3600,
+		Samples:       t.samples,
+	}
+	if resource.filesystem && target == "" {
+		f.Filesystem = "/"
+	}
+	if hours, ok := hoursUntilFull(t, f.Used, f.Total); ok {
+		fullAt := t.last.Timestamp.Add(time.Duration(hours * float64(time.Hour)))
+		f.HoursUntilFull = &hours
+		f.FullAt = &fullAt
+	}
+	return f, nil
+}
+
+// validateForecastRule checks the settings of a forecast rule, whose
+// thresholds are hours until the resource is full
+func validateForecastRule(rule *models.AlertRule) error {
+	if _, ok := forecastResources[rule.MetricType]; !ok {
+		return &ValidationError{Field: "metric_type", Message: "must be disk or memory for forecast rules"}
+	}
+	switch rule.Operator {
+	case "lt", "lte":
+	default:
+		return &ValidationError{Field: "operator", Message: "must be lt or lte for forecast rules"}
+	}
+	if rule.Window != 0 {
+		return &ValidationError{Field: "window", Message: "cannot be used with forecast rules"}
+	}
+
+	thresholds := []float64{rule.Threshold}
+	for _, level := range rule.Levels {
+		thresholds = append(thresholds, level.Threshold)
+	}
+	for _, threshold := range thresholds {
+		if math.IsNaN(threshold) || threshold <= 0 || threshold > maxForecastHours {
+			return &ValidationError{Field: "threshold", Message: fmt.Sprintf("must be between 0 and %d hours", maxForecastHours)}
+		}
+	}
+	return nil
+}
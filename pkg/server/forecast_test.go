@@ -0,0 +1,64 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// waitForTrends waits until no trend is being fitted in the background
+func waitForTrends(t *testing.T, a *Alerter) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		a.mu.RLock()
+		fitting := len(a.fitting)
+		a.mu.RUnlock()
+		if fitting == 0 {
+			return
+		}
+	}
+	t.Fatal("trend still being fitted")
+}
+
+func TestTrendIsFittedInBackground(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now()
+	for i := 20; i > 0; i-- {
+		m := &models.Metrics{AgentID: "web-1", Timestamp: now.Add(-time.Duration(i) * time.Minute),
+			MemoryUsed: uint64(100-i) << 20, MemoryTotal: 1 << 30}
+		if err := s.db.SaveMetrics(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := s.alerter
+	if trend, err := a.trend("web-1", "memory", "", now); err != nil || trend != nil {
+		t.Fatalf("trend before the first fit = %v, %v, want nil", trend, err)
+	}
+	waitForTrends(t, a)
+	trend, err := a.trend("web-1", "memory", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trend == nil || trend.samples != 20 || trend.slope <= 0 {
+		t.Fatalf("fitted trend = %+v, want 20 samples of growing usage", trend)
+	}
+
+	// A trend no rule uses is kept only until it is due to be fitted again
+	a.pruneTrends(nil)
+	if len(a.trends) != 1 {
+		t.Fatal("fresh trend was pruned")
+	}
+	trend.fittedAt = now.Add(-time.Hour)
+	rule := &models.AlertRule{ID: 1, Type: "forecast", MetricType: "memory", Operator: "lt", Threshold: 24, Enabled: true}
+	a.pruneTrends([]*models.AlertRule{rule})
+	if len(a.trends) != 1 {
+		t.Fatal("trend of a forecast rule was pruned")
+	}
+	rule.AgentID = "db-1"
+	a.pruneTrends([]*models.AlertRule{rule})
+	if len(a.trends) != 0 {
+		t.Error("stale trend of another agent's rule was kept")
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
-	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,9 +22,11 @@ var jwtSecret = []byte("change-this-secret-in-production")
 
 // Server represents the monitoring server
 type Server struct {
-	db      *Database
-	config  *models.Config
-	alerter *Alerter
+	db       *Database
+	config   *models.Config
+	alerter  *Alerter
+	notifier *Notifier
+	stop     chan struct{}
 }
 
 // NewServer creates a new server instance
@@ -52,12 +55,22 @@ func NewServer(config *models.Config) (*Server, error) {
 	}
 	config.Installed = installed
 
-	alerter := NewAlerter(db, config)
+	// Bring existing installations up to date with new tables
+	if installed {
+		if err := db.Migrate(); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	notifier := NewNotifier(db, config)
+	alerter := NewAlerter(db, config, notifier)
 
 	return &Server{
-		db:      db,
-		config:  config,
-		alerter: alerter,
+		db:       db,
+		config:   config,
+		alerter:  alerter,
+		notifier: notifier,
+		stop:     make(chan struct{}),
 	}, nil
 }
 
@@ -72,12 +85,33 @@ func (s *Server) Start() error {
 	// API endpoints
 	mux.HandleFunc("/api/login", s.handleLogin)
 	mux.HandleFunc("/api/agents", s.withAuth(s.handleAgents))
+	mux.HandleFunc("/api/agents/", s.withAuth(s.handleAgent))
 	mux.HandleFunc("/api/metrics/", s.withAuth(s.handleMetrics))
 	mux.HandleFunc("/api/metrics/report", s.handleMetricsReport)
 	mux.HandleFunc("/api/alerts", s.withAuth(s.handleAlerts))
+	mux.HandleFunc("/api/alerts/", s.withAuth(s.handleAlert))
 	mux.HandleFunc("/api/alert-rules", s.withAuth(s.handleAlertRules))
+	mux.HandleFunc("/api/alert-rules/", s.withAuth(s.handleAlertRule))
+	mux.HandleFunc("/api/alert-rules/backtest", s.withAuth(s.handleAlertRuleBacktest))
+	mux.HandleFunc("/api/notifications", s.withAuth(s.handleNotifications))
+	mux.HandleFunc("/api/notifications/deliveries", s.withAuth(s.handleNotificationDeliveries))
+	mux.HandleFunc("/api/notification-templates", s.withAuth(s.handleNotificationTemplates))
+	mux.HandleFunc("/api/notification-templates/", s.withAuth(s.handleNotificationTemplate))
+	mux.HandleFunc("/api/notification-routes", s.withAuth(s.handleNotificationRoutes))
+	mux.HandleFunc("/api/notification-routes/", s.withAuth(s.handleNotificationRoute))
+	mux.HandleFunc("/api/maintenance-windows", s.withAuth(s.handleMaintenanceWindows))
+	mux.HandleFunc("/api/maintenance-windows/", s.withAuth(s.handleMaintenanceWindow))
+	mux.HandleFunc("/api/escalation-policies", s.withAuth(s.handleEscalationPolicies))
+	mux.HandleFunc("/api/escalation-policies/", s.withAuth(s.handleEscalationPolicy))
+	mux.HandleFunc("/api/oncall-schedules", s.withAuth(s.handleOnCallSchedules))
+	mux.HandleFunc("/api/oncall-schedules/", s.withAuth(s.handleOnCallSchedule))
 	mux.HandleFunc("/api/config", s.withAuth(s.handleConfig))
 
+	// Background workers
+	go s.notifier.Run(s.stop)
+	go s.alerter.RunHeartbeats(s.stop)
+	go s.notifier.RunEscalations(s.stop)
+
 	// Static files
 	mux.HandleFunc("/", s.handleStatic)
 
@@ -172,15 +206,12 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 
 	// Update agent status based on reporting history
 	for _, agent := range agents {
-		// Default fallback interval if we don't have enough history
-		fallbackInterval := 2 * time.Minute
-
 		// Get metrics from the last hour to estimate reporting interval
 		since := time.Now().Add(-1 * time.Hour)
 		metrics, err := s.db.GetMetricsHistory(agent.ID, since)
 		if err != nil || len(metrics) < 2 {
 			// Not enough data to estimate - fallback to previous simple rule
-			if time.Since(time.Time(agent.LastSeen)) > fallbackInterval {
+			if time.Since(agent.LastSeen) > fallbackReportInterval {
 				agent.Status = "offline"
 			} else {
 				agent.Status = "online"
@@ -188,34 +219,11 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		// metrics are ordered DESC (newest first)
-		var totalInterval time.Duration
-		var count int64
-		for i := 0; i < len(metrics)-1; i++ {
-			// interval between consecutive reports
-			delta := time.Time(metrics[i].Timestamp).Sub(time.Time(metrics[i+1].Timestamp))
-			if delta > 0 {
-				totalInterval += delta
-				count++
-			}
-		}
+		avgInterval := estimateReportInterval(metrics, fallbackReportInterval)
 
-		var avgInterval time.Duration
-		if count > 0 {
-			avgInterval = time.Duration(int64(totalInterval) / count)
-			// if avgInterval is zero or unreasonable, fallback
-			if avgInterval <= 0 {
-				avgInterval = fallbackInterval
-			}
-		} else {
-			avgInterval = fallbackInterval
-		}
-
-		// Use the latest known report time as the base
-		latest := metrics[0].Timestamp
-
-		// Predict next three expected report times (only third needed)
-		next3 := time.Time(latest).Add(3 * avgInterval)
+		// metrics are ordered DESC (newest first); predict the third
+		// expected report after the latest one
+		next3 := metrics[0].Timestamp.Add(defaultMissedReports * avgInterval)
 
 		// If now has passed the third expected report time, mark offline
 		if time.Now().After(next3) {
@@ -229,6 +237,178 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(agents)
 }
 
+// containerEventLimit is how many container events an agent's page lists
+const containerEventLimit = 50
+
+// customPoint is one stored value of a custom series
+type customPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// handleAgent handles requests about a single agent:
+// /api/agents/{id} returns the agent with the host inventory it last reported
+// /api/agents/{id}/forecast predicts when its disks and memory will be full
+// /api/agents/{id}/filesystems lists the filesystems it last reported
+// /api/agents/{id}/disks lists the I/O of each device it last reported
+// /api/agents/{id}/interfaces lists the traffic of each interface it last reported
+// /api/agents/{id}/processes lists the busiest processes it last reported
+// /api/agents/{id}/watches lists the state of each process and unit it watches
+// /api/agents/{id}/sensors lists the temperature and fan sensors it last reported
+// /api/agents/{id}/custom lists the value of each custom series it last reported
+// /api/agents/{id}/custom-history?name=&since= lists the values of one custom series
+// /api/agents/{id}/containers lists the usage of each container it last reported
+// /api/agents/{id}/container-events lists its recent container starts and stops
+func (s *Server) handleAgent(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/agents/"), "/")
+	if len(parts) == 1 {
+		parts = append(parts, "")
+	}
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	agent, err := s.db.GetAgent(parts[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if agent == nil {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	var result interface{}
+	switch parts[1] {
+	case "":
+		inventory, err := s.db.GetAgentInventory(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		agent.Inventory = inventory
+		result = agent
+	case "forecast":
+		forecasts, err := s.alerter.Forecasts(agent.ID, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if forecasts == nil {
+			forecasts = []*models.Forecast{}
+		}
+		result = forecasts
+	case "filesystems":
+		filesystems, err := s.db.GetLatestFilesystems(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = filesystems
+	case "disks":
+		disks, err := s.db.GetLatestDisks(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = disks
+	case "interfaces":
+		interfaces, err := s.db.GetLatestInterfaces(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = interfaces
+	case "processes":
+		processes, err := s.db.GetProcesses(agent.ID, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = processes
+	case "watches":
+		watches, err := s.db.GetLatestWatches(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = watches
+	case "sensors":
+		sensors, err := s.db.GetLatestSensors(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = sensors
+	case "custom":
+		custom, err := s.db.GetLatestCustomMetrics(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = custom
+	case "custom-history":
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		now := time.Now()
+		since, err := parseSince(r.URL.Query().Get("since"), now.Add(-5*time.Minute))
+		if err != nil {
+			http.Error(w, "Invalid since parameter", http.StatusBadRequest)
+			return
+		}
+		points := []customPoint{}
+		err = s.db.EachCustomSample(agent.ID, name, since, now, func(m *models.Metrics) {
+			points = append(points, customPoint{Timestamp: m.Timestamp, Value: customValue(m)})
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = points
+	case "containers":
+		containers, err := s.db.GetLatestContainers(agent.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = containers
+	case "container-events":
+		events, err := s.db.GetContainerEvents(agent.ID, containerEventLimit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = events
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseSince parses the since query parameter, in "2006-01-02 15:04:05" with
+// optional milliseconds, returning def if it is empty
+func parseSince(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	// Try to parse with millisecond precision first, then without milliseconds.
+	if t, err := time.Parse("2006-01-02 15:04:05.000", value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02 15:04:05", value)
+}
+
 // handleMetrics handles metrics retrieval
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -246,20 +426,10 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 
 	// Get time range from query params. Default to last 5 minutes if not provided.
 	now := time.Now()
-	var since time.Time
-	if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
-		// Try to parse with millisecond precision first, then without milliseconds.
-		// All times expected in local "2006-01-02 15:04:05" (optional .000)
-		if t, err := time.Parse("2006-01-02 15:04:05.000", sinceParam); err == nil {
-			since = t
-		} else if t, err := time.Parse("2006-01-02 15:04:05", sinceParam); err == nil {
-			since = t
-		} else {
-			http.Error(w, "Invalid since parameter", http.StatusBadRequest)
-			return
-		}
-	} else {
-		since = now.Add(-5 * time.Minute)
+	since, err := parseSince(r.URL.Query().Get("since"), now.Add(-5*time.Minute))
+	if err != nil {
+		http.Error(w, "Invalid since parameter", http.StatusBadRequest)
+		return
 	}
 
 	// Fetch raw metrics from DB (limited by DB implementation)
@@ -427,15 +597,26 @@ func (s *Server) handleMetricsReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update agent info
+	// Update agent info, keeping the platform and version from the agent's
+	// last inventory when this report carries none
 	agent := &models.Agent{
 		ID:       metrics.AgentID,
 		Name:     metrics.AgentID,
 		Host:     r.RemoteAddr,
 		LastSeen: time.Now(),
 		Status:   "online",
-		Platform: runtime.GOOS,
-		Version:  "1.0.0",
+		Tags:     metrics.Tags,
+	}
+	if metrics.Inventory != nil {
+		agent.Platform = metrics.Inventory.OS
+		agent.Version = metrics.Inventory.AgentVersion
+		if err := s.db.SaveAgentInventory(metrics.AgentID, metrics.Inventory); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if prev, err := s.db.GetAgent(metrics.AgentID); err == nil && prev != nil {
+		agent.Platform = prev.Platform
+		agent.Version = prev.Version
 	}
 	s.db.UpdateAgent(agent)
 
@@ -452,17 +633,86 @@ func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alerts, err := s.db.GetAlerts(100)
+	q, err := parseAlertQuery(r.URL.Query())
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	alerts, next, err := s.db.GetAlerts(q)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if alerts == nil {
+		alerts = []*models.Alert{}
+	}
 
+	// The body stays a plain list; the next page is linked from a header
+	if next != nil {
+		w.Header().Set("X-Next-Cursor", encodeAlertCursor(next))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(alerts)
 }
 
-// handleAlertRules handles alert rules CRUD
+// handleAlert returns an alert with its timeline and handles actions on it
+func (s *Server) handleAlert(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/alerts/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid alert id", http.StatusBadRequest)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "ack" {
+		s.handleAlertAck(w, r, id)
+		return
+	}
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	alert, err := s.db.GetAlert(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if alert == nil {
+		http.Error(w, "Alert not found", http.StatusNotFound)
+		return
+	}
+
+	rule, err := s.db.GetAlertRule(alert.RuleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	notifications, err := s.db.GetNotifications(alert.ID, "", maxTimelineEntries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deliveries, err := s.db.GetNotificationDeliveries(alert.ID, maxTimelineEntries)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AlertDetail{
+		Alert:    alert,
+		Rule:     rule,
+		Timeline: alertTimeline(alert, notifications, deliveries),
+	})
+}
+
+// handleAlertRules handles alert rule listing and creation
 func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -471,6 +721,9 @@ func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if rules == nil {
+			rules = []*models.AlertRule{}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(rules)
 
@@ -481,19 +734,204 @@ func (s *Server) handleAlertRules(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.db.SaveAlertRule(&rule); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Updates go through PUT or PATCH on the rule itself
+		if rule.ID != 0 {
+			writeValidationError(w, &ValidationError{Field: "id", Message: "must not be set when creating a rule; use PUT /api/alert-rules/{id} to update"})
+			return
+		}
+
+		if !s.saveAlertRule(w, &rule) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAlertRule handles a single alert rule
+func (s *Server) handleAlertRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/alert-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid alert rule id", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.db.GetAlertRule(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut, http.MethodPatch:
+		// PUT replaces the rule, PATCH only changes the fields that are sent
+		rule := models.AlertRule{}
+		if r.Method == http.MethodPatch {
+			rule = *existing
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
+		rule.ID = id
 
+		if !s.saveAlertRule(w, &rule) {
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(rule)
 
+	case http.MethodDelete:
+		if err := s.db.DeleteAlertRule(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// saveAlertRule checks the references of a rule and stores it, writing an
+// error response and returning false if it cannot be saved
+func (s *Server) saveAlertRule(w http.ResponseWriter, rule *models.AlertRule) bool {
+	if rule.AgentID != "" {
+		agent, err := s.db.GetAgent(rule.AgentID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return false
+		}
+		if agent == nil {
+			writeValidationError(w, &ValidationError{Field: "agent_id", Message: fmt.Sprintf("unknown agent %q; leave empty for a rule that applies to every agent", rule.AgentID)})
+			return false
+		}
+	}
+	if rule.EscalationPolicyID != 0 {
+		policy, err := s.db.GetEscalationPolicy(rule.EscalationPolicyID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return false
+		}
+		if policy == nil {
+			writeValidationError(w, &ValidationError{Field: "escalation_policy_id", Message: fmt.Sprintf("unknown escalation policy %d", rule.EscalationPolicyID)})
+			return false
+		}
+	}
+
+	if err := s.db.SaveAlertRule(rule); err != nil {
+		writeValidationError(w, err)
+		return false
+	}
+	return true
+}
+
+// writeValidationError responds with 400 and a JSON description of the
+// invalid field for validation errors, and 500 for anything else
+func writeValidationError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	var exprErr *ExprError
+	switch {
+	case errors.As(err, &validationErr):
+	case errors.As(err, &exprErr):
+		validationErr = &ValidationError{Field: "expression", Message: err.Error()}
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   validationErr.Error(),
+		"field":   validationErr.Field,
+		"message": validationErr.Message,
+	})
+}
+
+// handleNotifications handles notification outbox listing
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	alertID, err := queryInt(r, "alert_id", 0)
+	if err != nil {
+		http.Error(w, "Invalid alert_id parameter", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", 100)
+	if err != nil || limit <= 0 {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+
+	notifications, err := s.db.GetNotifications(alertID, r.URL.Query().Get("status"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if notifications == nil {
+		notifications = []*models.Notification{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// handleNotificationDeliveries handles the notification delivery log
+func (s *Server) handleNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	alertID, err := queryInt(r, "alert_id", 0)
+	if err != nil {
+		http.Error(w, "Invalid alert_id parameter", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", 100)
+	if err != nil || limit <= 0 {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := s.db.GetNotificationDeliveries(alertID, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.NotificationDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// queryInt parses an integer query parameter, returning def when it is absent
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
 // handleConfig handles configuration retrieval
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -508,6 +946,8 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 		"smtp_port":   s.config.SMTPPort,
 		"email_from":  s.config.EmailFrom,
 		"alert_email": s.config.AlertEmail,
+
+		"notification_channels": s.notifier.Channels(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -608,5 +1048,10 @@ func (s *Server) handleInstallSetup(w http.ResponseWriter, r *http.Request) {
 
 // Close closes the server resources
 func (s *Server) Close() error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
 	return s.db.Close()
 }
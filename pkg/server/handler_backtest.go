@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// handleAlertRuleBacktest replays stored metrics through an unsaved rule to
+// show how often it would have fired. The range defaults to the last 24 hours.
+func (s *Server) handleAlertRuleBacktest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Rule models.AlertRule `json:"rule"`
+		From time.Time        `json:"from"`
+		To   time.Time        `json:"to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if req.From.IsZero() {
+		req.From = req.To.Add(-defaultBacktestRange)
+	}
+	if err := validateBacktestRange(req.From, req.To); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	rule := &req.Rule
+	if err := validateAlertRule(rule); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if rule.Expression != "" && rule.MetricType == "" {
+		rule.MetricType = "expression"
+	}
+	normalizeSeverities(rule)
+	normalizeRuleType(rule)
+
+	if rule.AgentID != "" {
+		agent, err := s.db.GetAgent(rule.AgentID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if agent == nil {
+			writeValidationError(w, &ValidationError{Field: "agent_id", Message: fmt.Sprintf("unknown agent %q", rule.AgentID)})
+			return
+		}
+	}
+
+	result, err := s.alerter.Backtest(rule, req.From, req.To)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
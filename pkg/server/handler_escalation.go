@@ -0,0 +1,273 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// handleAlertAck acknowledges an alert, which stops its escalation
+func (s *Server) handleAlertAck(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		By string `json:"by"`
+	}
+	// The body is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.By == "" {
+		req.By = "admin"
+	}
+
+	alert, err := s.db.GetAlert(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if alert == nil {
+		http.Error(w, "Alert not found", http.StatusNotFound)
+		return
+	}
+
+	if alert.AcknowledgedAt.IsZero() {
+		alert.AcknowledgedAt = time.Now()
+		alert.AcknowledgedBy = req.By
+		if err := s.db.AcknowledgeAlert(alert.ID, alert.AcknowledgedBy, alert.AcknowledgedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alert)
+}
+
+// handleEscalationPolicies handles escalation policy listing and creation
+func (s *Server) handleEscalationPolicies(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		policies, err := s.db.GetEscalationPolicies()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if policies == nil {
+			policies = []*models.EscalationPolicy{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policies)
+
+	case http.MethodPost:
+		var policy models.EscalationPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		policy.ID = 0
+		s.saveEscalationPolicy(w, &policy)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleEscalationPolicy handles a single escalation policy
+func (s *Server) handleEscalationPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/escalation-policies/"))
+	if err != nil {
+		http.Error(w, "Invalid escalation policy id", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.db.GetEscalationPolicy(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Escalation policy not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var policy models.EscalationPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		policy.ID = id
+		s.saveEscalationPolicy(w, &policy)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteEscalationPolicy(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveEscalationPolicy validates and stores an escalation policy
+func (s *Server) saveEscalationPolicy(w http.ResponseWriter, policy *models.EscalationPolicy) {
+	if err := validateEscalationPolicy(policy, s.notifier.Channels()); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	for i, step := range policy.Steps {
+		for _, id := range step.ScheduleIDs {
+			sch, err := s.db.GetOnCallSchedule(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if sch == nil {
+				writeValidationError(w, &ValidationError{
+					Field:   "steps[" + strconv.Itoa(i) + "].schedule_ids",
+					Message: "unknown schedule " + strconv.Itoa(id),
+				})
+				return
+			}
+		}
+	}
+
+	if err := s.db.SaveEscalationPolicy(policy); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// handleOnCallSchedules handles on-call schedule listing and creation
+func (s *Server) handleOnCallSchedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := s.db.GetOnCallSchedules()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if schedules == nil {
+			schedules = []*models.OnCallSchedule{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedules)
+
+	case http.MethodPost:
+		var sch models.OnCallSchedule
+		if err := json.NewDecoder(r.Body).Decode(&sch); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		sch.ID = 0
+		s.saveOnCallSchedule(w, &sch)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOnCallSchedule handles a single on-call schedule and reports who is
+// on call at /api/oncall-schedules/{id}/oncall?at=RFC3339
+func (s *Server) handleOnCallSchedule(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/oncall-schedules/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "oncall") {
+		http.Error(w, "Invalid on-call schedule path", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.db.GetOnCallSchedule(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "On-call schedule not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		at := time.Now()
+		if v := r.URL.Query().Get("at"); v != "" {
+			if at, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "Invalid at time, expected RFC3339", http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"schedule_id": existing.ID,
+			"at":          at,
+			"channel":     existing.Channel,
+			"participant": onCallAt(existing, at),
+		})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var sch models.OnCallSchedule
+		if err := json.NewDecoder(r.Body).Decode(&sch); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		sch.ID = id
+		s.saveOnCallSchedule(w, &sch)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteOnCallSchedule(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveOnCallSchedule validates and stores an on-call schedule
+func (s *Server) saveOnCallSchedule(w http.ResponseWriter, sch *models.OnCallSchedule) {
+	if err := validateOnCallSchedule(sch, s.notifier.Channels()); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := s.db.SaveOnCallSchedule(sch); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sch)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// handleMaintenanceWindows handles maintenance window listing and creation
+func (s *Server) handleMaintenanceWindows(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		windows, err := s.db.GetMaintenanceWindows()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if windows == nil {
+			windows = []*models.MaintenanceWindow{}
+		}
+		now := time.Now()
+		for _, window := range windows {
+			window.Active = maintenanceActive(window, now)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(windows)
+
+	case http.MethodPost:
+		var window models.MaintenanceWindow
+		if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		window.ID = 0
+		s.saveMaintenanceWindow(w, &window)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMaintenanceWindow handles a single maintenance window
+func (s *Server) handleMaintenanceWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/maintenance-windows/"))
+	if err != nil {
+		http.Error(w, "Invalid maintenance window id", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.db.GetMaintenanceWindow(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Maintenance window not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		existing.Active = maintenanceActive(existing, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var window models.MaintenanceWindow
+		if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		window.ID = id
+		s.saveMaintenanceWindow(w, &window)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteMaintenanceWindow(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveMaintenanceWindow validates and stores a maintenance window
+func (s *Server) saveMaintenanceWindow(w http.ResponseWriter, window *models.MaintenanceWindow) {
+	if err := validateMaintenanceWindow(window); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := s.db.SaveMaintenanceWindow(window); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	window.Active = maintenanceActive(window, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(window)
+}
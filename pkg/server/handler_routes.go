@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// handleNotificationRoutes handles routing table listing and creation
+func (s *Server) handleNotificationRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		routes, err := s.db.GetNotificationRoutes()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if routes == nil {
+			routes = []*models.NotificationRoute{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routes)
+
+	case http.MethodPost:
+		var route models.NotificationRoute
+		if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		route.ID = 0
+		s.saveNotificationRoute(w, &route)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleNotificationRoute handles a single notification route
+func (s *Server) handleNotificationRoute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/notification-routes/"))
+	if err != nil {
+		http.Error(w, "Invalid route id", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.db.GetNotificationRoute(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Route not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var route models.NotificationRoute
+		if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		route.ID = id
+		s.saveNotificationRoute(w, &route)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteNotificationRoute(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveNotificationRoute validates and stores a notification route
+func (s *Server) saveNotificationRoute(w http.ResponseWriter, route *models.NotificationRoute) {
+	if err := validateNotificationRoute(route, s.notifier.Channels()); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := s.db.SaveNotificationRoute(route); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// handleNotificationTemplates handles template listing and creation
+func (s *Server) handleNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		templates, err := s.db.GetNotificationTemplates()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if templates == nil {
+			templates = []*models.NotificationTemplate{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(templates)
+
+	case http.MethodPost:
+		var tpl models.NotificationTemplate
+		if err := json.NewDecoder(r.Body).Decode(&tpl); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		tpl.ID = 0
+		s.saveNotificationTemplate(w, &tpl)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleNotificationTemplate handles a single template and template previews
+func (s *Server) handleNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/notification-templates/")
+	if rest == "preview" {
+		s.handleNotificationTemplatePreview(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(rest)
+	if err != nil {
+		http.Error(w, "Invalid template id", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.db.GetNotificationTemplate(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(existing)
+
+	case http.MethodPut:
+		var tpl models.NotificationTemplate
+		if err := json.NewDecoder(r.Body).Decode(&tpl); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		tpl.ID = id
+		s.saveNotificationTemplate(w, &tpl)
+
+	case http.MethodDelete:
+		if err := s.db.DeleteNotificationTemplate(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveNotificationTemplate validates and stores a template
+func (s *Server) saveNotificationTemplate(w http.ResponseWriter, tpl *models.NotificationTemplate) {
+	if err := validateTemplate(tpl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.db.SaveNotificationTemplate(tpl); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tpl)
+}
+
+// handleNotificationTemplatePreview renders a template without saving it. When
+// rule_id and agent_id are given the preview uses that rule and the agent's
+// latest metrics, otherwise it uses sample data.
+func (s *Server) handleNotificationTemplatePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		models.NotificationTemplate
+		AgentID string `json:"agent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	data, err := s.previewTemplateData(req.RuleID, req.AgentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if req.Channel != "" {
+		data.Channel = req.Channel
+	}
+
+	subject, body, err := renderNotification(&req.NotificationTemplate, data)
+	if err != nil {
+		var tplErr *TemplateError
+		if errors.As(err, &tplErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"subject": subject,
+		"body":    body,
+	})
+}
+
+// previewTemplateData builds template data for a preview
+func (s *Server) previewTemplateData(ruleID int, agentID string) (*TemplateData, error) {
+	if ruleID == 0 || agentID == "" {
+		return sampleTemplateData(), nil
+	}
+
+	rules, err := s.db.GetAlertRules()
+	if err != nil {
+		return nil, err
+	}
+	var rule *models.AlertRule
+	for _, candidate := range rules {
+		if candidate.ID == ruleID {
+			rule = candidate
+			break
+		}
+	}
+	if rule == nil {
+		return sampleTemplateData(), nil
+	}
+
+	alert := &models.Alert{
+		RuleID:    rule.ID,
+		AgentID:   agentID,
+		Timestamp: time.Now(),
+	}
+	history, err := s.db.GetMetricsHistory(agentID, alert.Timestamp.Add(-templateHistoryWindow))
+	if err == nil && len(history) > 0 {
+		alert.Value = metricValue(history[0], rule.MetricType)
+	}
+	alert.Message = formatAlertMessage(rule, alert.Value)
+
+	return buildTemplateData(s.db, s.config, alert, rule), nil
+}
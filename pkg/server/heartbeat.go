@@ -0,0 +1,337 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	heartbeatCheckInterval = 15 * time.Second
+
+	// fallbackReportInterval is assumed when an agent has too little history
+	// to estimate how often it reports
+	fallbackReportInterval = 2 * time.Minute
+
+	// defaultMissedReports is how many expected reports may be missed before
+	// an agent is considered offline
+	defaultMissedReports = 3
+)
+
+// estimateReportInterval returns the average interval between consecutive
+// samples, ignoring their order, or fallback if it cannot be estimated
+func estimateReportInterval(samples []*models.Metrics, fallback time.Duration) time.Duration {
+	var total time.Duration
+	var count int64
+	for i := 0; i < len(samples)-1; i++ {
+		delta := samples[i].Timestamp.Sub(samples[i+1].Timestamp)
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta > 0 {
+			total += delta
+			count++
+		}
+	}
+	if count == 0 {
+		return fallback
+	}
+	return time.Duration(int64(total) / count)
+}
+
+// offlineDeadline returns how long an agent reporting every interval may stay
+// silent before an offline rule fires
+func offlineDeadline(rule *models.AlertRule, interval time.Duration) time.Duration {
+	missed := rule.Threshold
+	if missed <= 0 {
+		missed = defaultMissedReports
+	}
+	return time.Duration(missed*float64(interval)) + time.Duration(rule.Duration)*time.Second
+}
+
+// RunHeartbeats checks for agents that stopped reporting until stop is closed
+func (a *Alerter) RunHeartbeats(stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if !a.config.Installed {
+				continue
+			}
+			if err := a.CheckHeartbeats(now); err != nil {
+				log.Printf("Failed to check agent heartbeats: %v", err)
+			}
+		}
+	}
+}
+
+// CheckHeartbeats fires "offline" rules for agents that have missed their
+// expected reports. Offline alerts are resolved by CheckMetrics once the
+// agent reports again.
+func (a *Alerter) CheckHeartbeats(now time.Time) error {
+	rules, err := a.db.GetAlertRules()
+	if err != nil {
+		return err
+	}
+
+	var offlineRules []*models.AlertRule
+	for _, rule := range rules {
+		if rule.Enabled && rule.MetricType == "offline" {
+			offlineRules = append(offlineRules, rule)
+		}
+	}
+	if len(offlineRules) == 0 {
+		return nil
+	}
+
+	if err := a.loadActiveAlerts(offlineRules); err != nil {
+		return err
+	}
+
+	agents, err := a.db.GetAgents()
+	if err != nil {
+		return err
+	}
+
+	for _, agent := range agents {
+		lastSeen, interval := a.heartbeat(agent)
+		silence := now.Sub(lastSeen)
+
+		offline := false
+		for _, rule := range offlineRules {
+			if rule.AgentID != "" && rule.AgentID != agent.ID {
+				continue
+			}
+
+			if silence <= offlineDeadline(rule, interval) {
+				continue
+			}
+
+			offline = true
+			alert := &models.Alert{
+				RuleID:    rule.ID,
+				AgentID:   agent.ID,
+				Timestamp: now,
+				Message:   fmt.Sprintf("agent offline: no report for %s (expected every %s)", silence.Round(time.Second), interval.Round(time.Second)),
+				Value:     silence.Seconds(),
+				Severity:  ruleSeverity(rule, silence.Seconds()),
+			}
+			a.fireOnce(alert, rule)
+		}
+
+		if offline && agent.Status != "offline" {
+			if err := a.db.UpdateAgentStatus(agent.ID, "offline"); err != nil {
+				log.Printf("Failed to mark agent %s offline: %v", agent.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// heartbeat returns when an agent last reported and how often it reports,
+// preferring the in-memory history over the database
+func (a *Alerter) heartbeat(agent *models.Agent) (time.Time, time.Duration) {
+	a.mu.RLock()
+	ring := a.history[agent.ID]
+	var samples []*models.Metrics
+	if ring != nil {
+		samples = ring.since(time.Now().Add(-time.Hour))
+	}
+	a.mu.RUnlock()
+
+	if len(samples) >= 2 {
+		latest := samples[len(samples)-1].Timestamp
+		if agent.LastSeen.After(latest) {
+			latest = agent.LastSeen
+		}
+		return latest, estimateReportInterval(samples, fallbackReportInterval)
+	}
+
+	history, err := a.db.GetMetricsHistory(agent.ID, time.Now().Add(-time.Hour))
+	if err != nil || len(history) < 2 {
+		return agent.LastSeen, fallbackReportInterval
+	}
+	return agent.LastSeen, estimateReportInterval(history, fallbackReportInterval)
+}
+
+// resolveOffline resolves open offline alerts for an agent that reported again
+func (a *Alerter) resolveOffline(rules []*models.AlertRule, agentID string) {
+	var offlineRules []*models.AlertRule
+	for _, rule := range rules {
+		if rule.Enabled && rule.MetricType == "offline" {
+			offlineRules = append(offlineRules, rule)
+		}
+	}
+	if len(offlineRules) == 0 {
+		return
+	}
+
+	if err := a.loadActiveAlerts(offlineRules); err != nil {
+		log.Printf("Failed to load active alerts: %v", err)
+		return
+	}
+
+	resolved := make([][]*models.Alert, len(offlineRules))
+	a.transitions.Lock()
+	for i, rule := range offlineRules {
+		resolved[i] = a.resolveOpen(rule, agentID)
+	}
+	a.transitions.Unlock()
+
+	for i, rule := range offlineRules {
+		for _, alert := range resolved[i] {
+			a.notifyResolved(alert, rule)
+		}
+	}
+}
+
+// fireOnce fires an alert unless its rule already has an open alert for the
+// agent, and records it as open. The check and the record are made under
+// transitions, so a report resolving the alert in between cannot leave a
+// resolved alert recorded as open or an open one forgotten.
+func (a *Alerter) fireOnce(alert *models.Alert, rule *models.AlertRule) {
+	// Checked again under transitions, this spares the maintenance window
+	// query while the agent stays offline
+	if a.activeAlert(rule.ID, alert.AgentID) != nil {
+		return
+	}
+	alert.Suppressed = a.inMaintenance(alert, rule, alert.Timestamp)
+	if fired := a.recordOnce(alert, rule); fired != nil {
+		a.notifyFired(fired, rule)
+	}
+}
+
+// recordOnce records an alert as fireOnce does, returning a copy of it to
+// notify about once transitions is released
+func (a *Alerter) recordOnce(alert *models.Alert, rule *models.AlertRule) *models.Alert {
+	a.transitions.Lock()
+	defer a.transitions.Unlock()
+
+	if a.activeAlert(rule.ID, alert.AgentID) != nil || !a.record(alert, rule) {
+		return nil
+	}
+	fired := *alert
+	return &fired
+}
+
+// loadActiveAlerts restores the unresolved alerts of rules it has not seen
+// before from the database, so alerts raised before a restart, or before a
+// rule was first checked, are still resolved
+func (a *Alerter) loadActiveAlerts(rules []*models.AlertRule) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ruleIDs := make(map[int]bool)
+	for _, rule := range rules {
+		if !a.loadedRules[rule.ID] {
+			ruleIDs[rule.ID] = true
+		}
+	}
+	if len(ruleIDs) == 0 {
+		return nil
+	}
+
+	alerts, err := a.db.GetUnresolvedAlerts()
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		if ruleIDs[alert.RuleID] {
+			a.setActiveAlertLocked(alert)
+		}
+	}
+
+	for id := range ruleIDs {
+		a.loadedRules[id] = true
+	}
+	return nil
+}
+
+// activeAlert returns the latest open alert of a rule for an agent, if any
+func (a *Alerter) activeAlert(ruleID int, agentID string) *models.Alert {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	open := a.activeAlerts[ruleID][agentID]
+	if len(open) == 0 {
+		return nil
+	}
+	return open[len(open)-1]
+}
+
+// setActiveAlert records an alert as open until it is resolved. A rule keeps
+// one open alert per agent, but databases from before that can hold several,
+// and all of them are restored.
+func (a *Alerter) setActiveAlert(alert *models.Alert) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.setActiveAlertLocked(alert)
+}
+
+func (a *Alerter) setActiveAlertLocked(alert *models.Alert) {
+	if a.activeAlerts[alert.RuleID] == nil {
+		a.activeAlerts[alert.RuleID] = make(map[string][]*models.Alert)
+	}
+	a.activeAlerts[alert.RuleID][alert.AgentID] = append(a.activeAlerts[alert.RuleID][alert.AgentID], alert)
+}
+
+// resolveOpen resolves the open alerts of a rule for an agent. Callers hold
+// transitions, and notify about the returned copies of the resolved alerts
+// once they release it. Alerts that fail to resolve stay open, so they are
+// tried again the next time the rule clears.
+func (a *Alerter) resolveOpen(rule *models.AlertRule, agentID string) []*models.Alert {
+	a.mu.RLock()
+	open := a.activeAlerts[rule.ID][agentID]
+	a.mu.RUnlock()
+	if len(open) == 0 {
+		return nil
+	}
+
+	var resolved, failed []*models.Alert
+	for _, alert := range open {
+		if !a.resolve(alert) {
+			failed = append(failed, alert)
+			continue
+		}
+		snapshot := *alert
+		resolved = append(resolved, &snapshot)
+	}
+
+	a.mu.Lock()
+	if len(failed) > 0 {
+		a.activeAlerts[rule.ID][agentID] = failed
+	} else {
+		delete(a.activeAlerts[rule.ID], agentID)
+	}
+	a.mu.Unlock()
+	return resolved
+}
+
+// resolve marks an open alert as resolved
+func (a *Alerter) resolve(alert *models.Alert) bool {
+	if err := a.db.ResolveAlert(alert.ID); err != nil {
+		log.Printf("Failed to resolve alert %d: %v", alert.ID, err)
+		return false
+	}
+	alert.Resolved = true
+	return true
+}
+
+// notifyResolved queues the notifications of a resolved alert
+func (a *Alerter) notifyResolved(alert *models.Alert, rule *models.AlertRule) {
+	// Nobody was told about a suppressed alert, so there is nothing to resolve
+	if alert.Suppressed || a.inMaintenance(alert, rule, time.Now()) {
+		return
+	}
+	if err := a.notifier.NotifyResolved(alert, rule); err != nil {
+		log.Printf("Failed to queue notifications for resolved alert %d: %v", alert.ID, err)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// maxMaintenanceDuration bounds how long a recurring window stays open, which
+// is also how far back its schedule is searched
+const maxMaintenanceDuration = 7 * 24 * time.Hour
+
+// maintenanceLocation returns the time zone a window's schedule uses
+func maintenanceLocation(w *models.MaintenanceWindow) (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// maintenanceActive reports whether a window is open at now
+func maintenanceActive(w *models.MaintenanceWindow, now time.Time) bool {
+	if !w.Enabled {
+		return false
+	}
+	if !w.StartsAt.IsZero() && now.Before(w.StartsAt) {
+		return false
+	}
+	if !w.EndsAt.IsZero() && !now.Before(w.EndsAt) {
+		return false
+	}
+	if w.Schedule == "" {
+		// One-off windows are open for exactly their bounds
+		return !w.StartsAt.IsZero() && !w.EndsAt.IsZero()
+	}
+
+	schedule, err := ParseCronSchedule(w.Schedule)
+	if err != nil {
+		return false
+	}
+	loc, err := maintenanceLocation(w)
+	if err != nil {
+		return false
+	}
+	duration := time.Duration(w.Duration) * time.Second
+	opened, ok := schedule.Previous(now.In(loc), duration)
+	return ok && now.Before(opened.Add(duration))
+}
+
+// maintenanceCovers reports whether a window applies to an alert of a rule on
+// an agent. Each scope that is set must match; a window without scopes
+// covers everything.
+func maintenanceCovers(w *models.MaintenanceWindow, agent *models.Agent, ruleID int) bool {
+	if len(w.AgentIDs) > 0 && !containsString(w.AgentIDs, agent.ID) {
+		return false
+	}
+	if len(w.Tags) > 0 {
+		matched := false
+		for _, tag := range agent.Tags {
+			if containsString(w.Tags, tag) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(w.RuleIDs) > 0 {
+		matched := false
+		for _, id := range w.RuleIDs {
+			if id == ruleID {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMaintenanceWindow checks a maintenance window before it is stored
+func validateMaintenanceWindow(w *models.MaintenanceWindow) error {
+	if strings.TrimSpace(w.Name) == "" {
+		return &ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	if _, err := maintenanceLocation(w); err != nil {
+		return &ValidationError{Field: "timezone", Message: fmt.Sprintf("unknown time zone %q", w.Timezone)}
+	}
+	if !w.StartsAt.IsZero() && !w.EndsAt.IsZero() && !w.EndsAt.After(w.StartsAt) {
+		return &ValidationError{Field: "ends_at", Message: "must be after starts_at"}
+	}
+
+	if w.Schedule == "" {
+		if w.StartsAt.IsZero() || w.EndsAt.IsZero() {
+			return &ValidationError{Field: "schedule", Message: "one-off windows need starts_at and ends_at, recurring windows need a schedule"}
+		}
+		return nil
+	}
+
+	if _, err := ParseCronSchedule(w.Schedule); err != nil {
+		return &ValidationError{Field: "schedule", Message: err.Error()}
+	}
+	if w.Duration <= 0 || time.Duration(w.Duration)*time.Second > maxMaintenanceDuration {
+		return &ValidationError{Field: "duration", Message: fmt.Sprintf("must be between 1 and %d seconds", int(maxMaintenanceDuration.Seconds()))}
+	}
+	return nil
+}
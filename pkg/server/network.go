@@ -0,0 +1,31 @@
+package server
+
+import (
+	"math"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// findInterface returns the traffic of a network interface in a sample
+func findInterface(m *models.Metrics, name string) (models.InterfaceMetrics, bool) {
+	for _, iface := range m.Interfaces {
+		if iface.Name == name {
+			return iface, true
+		}
+	}
+	return models.InterfaceMetrics{}, false
+}
+
+// interfaceView returns a copy of a sample whose network fields hold the
+// traffic of a single interface
+func interfaceView(m *models.Metrics, iface models.InterfaceMetrics) *models.Metrics {
+	view := *m
+	view.NetworkRx = uint64(math.Round(iface.RxBytes))
+	view.NetworkTx = uint64(math.Round(iface.TxBytes))
+	view.NetworkRxPackets = iface.RxPackets
+	view.NetworkTxPackets = iface.TxPackets
+	view.NetworkErrors = iface.RxErrors + iface.TxErrors
+	view.NetworkDrops = iface.RxDrops + iface.TxDrops
+	view.Interfaces = []models.InterfaceMetrics{iface}
+	return &view
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	notifierPollInterval = 5 * time.Second
+	notifierBatchSize    = 50
+	notifierMaxAttempts  = 8
+	notifierBaseBackoff  = 30 * time.Second
+	notifierMaxBackoff   = time.Hour
+	defaultEmailChannel  = "email"
+)
+
+// Channel delivers a notification to an external system
+type Channel interface {
+	Send(n *models.Notification) error
+}
+
+// Notifier drains the notification outbox in the background, retrying
+// failed deliveries with exponential backoff and logging every attempt
+type Notifier struct {
+	db       *Database
+	config   *models.Config
+	channels map[string]Channel
+	order    []string
+}
+
+// NewNotifier creates a notifier with the channels configured on the server.
+// When no channels are configured the legacy SMTP settings are exposed as
+// the "email" channel.
+func NewNotifier(db *Database, config *models.Config) *Notifier {
+	n := &Notifier{
+		db:       db,
+		config:   config,
+		channels: make(map[string]Channel),
+	}
+
+	channels := config.NotificationChannels
+	if len(channels) == 0 && config.SMTPHost != "" && config.AlertEmail != "" {
+		channels = []models.NotificationChannelConfig{{
+			Name: defaultEmailChannel,
+			Type: "email",
+			To:   []string{config.AlertEmail},
+		}}
+	}
+
+	for _, c := range channels {
+		switch c.Type {
+		case "email":
+			n.channels[c.Name] = &emailChannel{config: config, to: c.To}
+		case "webhook":
+			n.channels[c.Name] = &webhookChannel{url: c.URL, client: &http.Client{Timeout: 10 * time.Second}}
+		default:
+			log.Printf("Ignoring notification channel %q with unknown type %q", c.Name, c.Type)
+			continue
+		}
+		n.order = append(n.order, c.Name)
+	}
+
+	return n
+}
+
+// Channels returns the names of the configured channels
+func (n *Notifier) Channels() []string {
+	return n.order
+}
+
+// notifyTarget is a channel and, optionally, a recipient overriding the
+// channel's configured recipients
+type notifyTarget struct {
+	channel   string
+	recipient string
+}
+
+// Notify queues the notifications for a newly fired alert. Alerts of rules
+// with an escalation policy go to the policy's first steps instead of every
+// channel.
+func (n *Notifier) Notify(alert *models.Alert, rule *models.AlertRule) error {
+	if rule.EscalationPolicyID != 0 {
+		return n.escalate(alert, rule, time.Now())
+	}
+	return n.Enqueue(alert, rule)
+}
+
+// NotifyResolved queues the notifications for a resolved alert. Escalated
+// alerts are only resolved towards the people who were told about them.
+func (n *Notifier) NotifyResolved(alert *models.Alert, rule *models.AlertRule) error {
+	if rule.EscalationPolicyID == 0 {
+		return n.Enqueue(alert, rule)
+	}
+	targets, err := n.db.GetNotifiedTargets(alert.ID)
+	if err != nil {
+		return err
+	}
+	return n.enqueueTargets(alert, rule, targets)
+}
+
+// Enqueue queues a notification for an alert on the channels it is routed
+// to, rendered with the most specific template for the channel and rule
+func (n *Notifier) Enqueue(alert *models.Alert, rule *models.AlertRule) error {
+	var targets []notifyTarget
+	for _, channel := range n.routeChannels(alert, rule) {
+		targets = append(targets, notifyTarget{channel: channel})
+	}
+	return n.enqueueTargets(alert, rule, targets)
+}
+
+// routeChannels returns the channels the routing table sends an alert to.
+// Without routes, or when no route matches, every channel is notified so
+// that no alert goes unnoticed.
+func (n *Notifier) routeChannels(alert *models.Alert, rule *models.AlertRule) []string {
+	routes, err := n.db.GetNotificationRoutes()
+	if err != nil {
+		log.Printf("Failed to load notification routes, notifying every channel: %v", err)
+		return n.order
+	}
+	if len(routes) == 0 {
+		return n.order
+	}
+
+	agent, err := n.db.GetAgent(alert.AgentID)
+	if err != nil || agent == nil {
+		agent = &models.Agent{ID: alert.AgentID}
+	}
+
+	var channels []string
+	for _, route := range routes {
+		if !routeMatches(route, alert, rule, agent) {
+			continue
+		}
+		for _, channel := range route.Channels {
+			if _, ok := n.channels[channel]; ok && !containsString(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+		if !route.Continue {
+			break
+		}
+	}
+
+	if len(channels) == 0 {
+		return n.order
+	}
+	return channels
+}
+
+// enqueueTargets queues a notification for an alert on each target
+func (n *Notifier) enqueueTargets(alert *models.Alert, rule *models.AlertRule, targets []notifyTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	templates, err := n.db.GetNotificationTemplates()
+	if err != nil {
+		log.Printf("Failed to load notification templates, using defaults: %v", err)
+	}
+	data := buildTemplateData(n.db, n.config, alert, rule)
+
+	for _, target := range targets {
+		channel := target.channel
+		data.Channel = channel
+		tpl := selectTemplate(templates, channel, rule.ID)
+		subject, body, err := renderNotification(tpl, data)
+		if err != nil {
+			log.Printf("Failed to render notification template %d for rule %d on channel %s (alert %d), using the default template: %v",
+				tpl.ID, rule.ID, channel, alert.ID, err)
+			if subject, body, err = renderNotification(defaultTemplate, data); err != nil {
+				return err
+			}
+		}
+
+		notification := &models.Notification{
+			AlertID:   alert.ID,
+			Channel:   channel,
+			Recipient: target.recipient,
+			Subject:   subject,
+			Body:      body,
+		}
+		if err := n.db.EnqueueNotification(notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run drains the outbox until stop is closed
+func (n *Notifier) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(notifierPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !n.config.Installed {
+				continue
+			}
+			if err := n.drain(); err != nil {
+				log.Printf("Failed to process notification outbox: %v", err)
+			}
+		}
+	}
+}
+
+// drain attempts delivery of every due notification
+func (n *Notifier) drain() error {
+	due, err := n.db.GetDueNotifications(time.Now(), notifierBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, notification := range due {
+		n.deliver(notification)
+	}
+	return nil
+}
+
+// deliver sends a single notification and records the outcome
+func (n *Notifier) deliver(notification *models.Notification) {
+	notification.Attempts++
+
+	var sendErr error
+	channel, ok := n.channels[notification.Channel]
+	if !ok {
+		sendErr = fmt.Errorf("channel %q is not configured", notification.Channel)
+	} else {
+		sendErr = channel.Send(notification)
+	}
+
+	delivery := &models.NotificationDelivery{
+		NotificationID: notification.ID,
+		AlertID:        notification.AlertID,
+		Channel:        notification.Channel,
+		Attempt:        notification.Attempts,
+		Status:         "sent",
+		Timestamp:      time.Now(),
+	}
+
+	if sendErr == nil {
+		notification.Status = "sent"
+		notification.LastError = ""
+	} else {
+		delivery.Status = "failed"
+		delivery.Error = sendErr.Error()
+		notification.LastError = sendErr.Error()
+		if notification.Attempts >= notifierMaxAttempts || !ok {
+			notification.Status = "failed"
+		} else {
+			notification.NextAttemptAt = time.Now().Add(backoff(notification.Attempts))
+		}
+	}
+
+	if err := n.db.SaveNotificationDelivery(delivery); err != nil {
+		log.Printf("Failed to record delivery of notification %d: %v", notification.ID, err)
+	}
+	if err := n.db.UpdateNotification(notification); err != nil {
+		log.Printf("Failed to update notification %d: %v", notification.ID, err)
+	}
+}
+
+// backoff returns the delay before the next attempt after the given number of attempts
+func backoff(attempts int) time.Duration {
+	delay := notifierBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= notifierMaxBackoff {
+			return notifierMaxBackoff
+		}
+	}
+	return delay
+}
+
+// emailChannel delivers notifications over SMTP
+type emailChannel struct {
+	config *models.Config
+	to     []string
+}
+
+// Send implements Channel
+func (c *emailChannel) Send(n *models.Notification) error {
+	if c.config.SMTPHost == "" {
+		return fmt.Errorf("SMTP is not configured")
+	}
+
+	to := c.to
+	if n.Recipient != "" {
+		to = []string{n.Recipient}
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients configured")
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
+		c.config.EmailFrom, strings.Join(to, ", "), n.Subject, n.Body)
+
+	auth := smtp.PlainAuth("", c.config.SMTPUser, c.config.SMTPPassword, c.config.SMTPHost)
+	addr := fmt.Sprintf("%s:%d", c.config.SMTPHost, c.config.SMTPPort)
+
+	return smtp.SendMail(addr, auth, c.config.EmailFrom, to, []byte(msg))
+}
+
+// webhookChannel delivers notifications as JSON POST requests
+type webhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// Send implements Channel
+func (c *webhookChannel) Send(n *models.Notification) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"alert_id":  n.AlertID,
+		"recipient": n.Recipient,
+		"subject":   n.Subject,
+		"text":      n.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
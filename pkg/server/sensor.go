@@ -0,0 +1,36 @@
+package server
+
+import "github.com/jyxjjj/Monitor/pkg/models"
+
+// findSensor returns the reading of a hardware sensor in a sample
+func findSensor(m *models.Metrics, name string) (models.SensorMetrics, bool) {
+	for _, s := range m.Sensors {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return models.SensorMetrics{}, false
+}
+
+// sensorView returns a copy of a sample whose sensor fields hold the reading
+// of a single sensor
+func sensorView(m *models.Metrics, s models.SensorMetrics) *models.Metrics {
+	view := *m
+	view.TemperatureMax = 0
+	if s.Kind == "temperature" {
+		view.TemperatureMax = s.Value
+	}
+	view.Sensors = []models.SensorMetrics{s}
+	return &view
+}
+
+// fanSpeed returns the speed of the first fan in a sample, which for a view
+// of one sensor is that sensor's
+func fanSpeed(m *models.Metrics) float64 {
+	for _, s := range m.Sensors {
+		if s.Kind == "fan" {
+			return s.Value
+		}
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const defaultSeverity = "warning"
+
+// severityRanks orders severities from least to most severe
+var severityRanks = map[string]int{
+	"info":     1,
+	"warning":  2,
+	"critical": 3,
+}
+
+// normalizeSeverities sorts a rule's levels from least to most severe and
+// keeps Threshold at the lowest level, so single-threshold evaluation still
+// decides whether the rule is breached at all
+func normalizeSeverities(rule *models.AlertRule) {
+	if rule.Severity == "" {
+		rule.Severity = defaultSeverity
+	}
+	if len(rule.Levels) == 0 {
+		return
+	}
+
+	sort.SliceStable(rule.Levels, func(i, j int) bool {
+		return severityRanks[rule.Levels[i].Severity] < severityRanks[rule.Levels[j].Severity]
+	})
+	rule.Threshold = rule.Levels[0].Threshold
+}
+
+// validateSeverities checks a rule's severity and threshold levels
+func validateSeverities(rule *models.AlertRule) error {
+	if rule.Severity != "" && severityRanks[rule.Severity] == 0 {
+		return &ValidationError{Field: "severity", Message: "must be one of info, warning, critical"}
+	}
+	if len(rule.Levels) == 0 {
+		return nil
+	}
+	if rule.Expression != "" || rule.MetricType == "offline" {
+		return &ValidationError{Field: "levels", Message: "cannot be used with expression or offline rules"}
+	}
+
+	seen := make(map[string]bool)
+	for i, level := range rule.Levels {
+		if severityRanks[level.Severity] == 0 {
+			return &ValidationError{Field: fmt.Sprintf("levels[%d].severity", i), Message: "must be one of info, warning, critical"}
+		}
+		if seen[level.Severity] {
+			return &ValidationError{Field: fmt.Sprintf("levels[%d].severity", i), Message: "is used by more than one level"}
+		}
+		seen[level.Severity] = true
+	}
+
+	// A more severe level must be at least as hard to reach as a milder one
+	levels := append([]models.ThresholdLevel(nil), rule.Levels...)
+	sort.SliceStable(levels, func(i, j int) bool {
+		return severityRanks[levels[i].Severity] < severityRanks[levels[j].Severity]
+	})
+	for i := 1; i < len(levels); i++ {
+		if checkThreshold(levels[i-1].Threshold, levels[i].Threshold, rule.Operator) {
+			return &ValidationError{Field: "levels", Message: fmt.Sprintf("%s threshold must not be reached before %s", levels[i].Severity, levels[i-1].Severity)}
+		}
+	}
+	return nil
+}
+
+// ruleSeverity returns the severity a breaching value reaches on a rule
+func ruleSeverity(rule *models.AlertRule, value float64) string {
+	severity := rule.Severity
+	if severity == "" {
+		severity = defaultSeverity
+	}
+	for _, level := range rule.Levels {
+		if checkThreshold(value, level.Threshold, rule.Operator) {
+			severity = level.Severity
+		}
+	}
+	return severity
+}
+
+// routeMatches reports whether a notification route applies to an alert
+func routeMatches(route *models.NotificationRoute, alert *models.Alert, rule *models.AlertRule, agent *models.Agent) bool {
+	if len(route.Severities) > 0 && !containsString(route.Severities, alert.Severity) {
+		return false
+	}
+	if len(route.MetricTypes) > 0 && !containsString(route.MetricTypes, rule.MetricType) {
+		return false
+	}
+	if len(route.Tags) > 0 {
+		for _, tag := range agent.Tags {
+			if containsString(route.Tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// validateNotificationRoute checks a notification route before it is stored
+func validateNotificationRoute(route *models.NotificationRoute, channels []string) error {
+	if route.Name == "" {
+		return &ValidationError{Field: "name", Message: "must not be empty"}
+	}
+	for _, severity := range route.Severities {
+		if severityRanks[severity] == 0 {
+			return &ValidationError{Field: "severities", Message: fmt.Sprintf("unknown severity %q", severity)}
+		}
+	}
+	if len(route.Channels) == 0 {
+		return &ValidationError{Field: "channels", Message: "must not be empty"}
+	}
+	for _, channel := range route.Channels {
+		if !containsString(channels, channel) {
+			return &ValidationError{Field: "channels", Message: fmt.Sprintf("unknown channel %q", channel)}
+		}
+	}
+	return nil
+}
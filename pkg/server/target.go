@@ -0,0 +1,138 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// maxTargetLength bounds the mountpoint, device, interface, watch, sensor or
+// custom series a rule may target
+const maxTargetLength = 255
+
+// Kinds of targets a metric can be limited to, see metricAccessor.target
+const (
+	targetFilesystem = "filesystem" // a mountpoint such as /var
+	targetDevice     = "device"     // a block device such as sda
+	targetInterface  = "interface"  // a network interface such as eth0
+	targetWatch      = "watch"      // a watched process or unit such as nginx
+	targetSensor     = "sensor"     // a hardware sensor such as coretemp/Package id 0
+	targetCustom     = "custom"     // a series reported by a plugin such as queue.depth
+)
+
+// targetView returns the view of a sample a rule limited to target is
+// evaluated against, or false if the sample did not report that target
+func targetView(m *models.Metrics, metricType, target string) (*models.Metrics, bool) {
+	if m == nil {
+		return nil, false
+	}
+	switch metricAccessors[metricType].target {
+	case targetFilesystem:
+		if fs, ok := findFilesystem(m, target); ok {
+			return filesystemView(m, fs), true
+		}
+	case targetDevice:
+		if disk, ok := findDisk(m, target); ok {
+			return diskView(m, []models.DiskIOMetrics{disk}), true
+		}
+	case targetInterface:
+		if iface, ok := findInterface(m, target); ok {
+			return interfaceView(m, iface), true
+		}
+	case targetWatch:
+		if w, ok := findWatch(m, target); ok {
+			return watchView(m, w), true
+		}
+	case targetSensor:
+		if s, ok := findSensor(m, target); ok {
+			return sensorView(m, s), true
+		}
+	case targetCustom:
+		if c, ok := findCustom(m, target); ok {
+			return customView(m, c), true
+		}
+	}
+	return nil, false
+}
+
+// targetHistory maps recent samples to views of one target, dropping the
+// samples that did not report it
+func targetHistory(history []*models.Metrics, oldest *models.Metrics, metricType, target string) ([]*models.Metrics, *models.Metrics) {
+	views := make([]*models.Metrics, 0, len(history))
+	for _, m := range history {
+		if view, ok := targetView(m, metricType, target); ok {
+			views = append(views, view)
+		}
+	}
+	view, _ := targetView(oldest, metricType, target)
+	return views, view
+}
+
+// eachSample calls fn with an agent's stored samples between from and to,
+// oldest first, viewed through the target a rule is limited to if any
+func (d *Database) eachSample(agentID, metricType, target string, from, to time.Time, fn func(*models.Metrics)) error {
+	switch metricAccessors[metricType].target {
+	case targetFilesystem:
+		if target != "" {
+			return d.EachFilesystemSample(agentID, target, from, to, fn)
+		}
+	case targetDevice:
+		if target != "" {
+			return d.EachDiskSample(agentID, target, from, to, fn)
+		}
+	case targetInterface:
+		if target != "" {
+			return d.EachInterfaceSample(agentID, target, from, to, fn)
+		}
+	case targetWatch:
+		if target != "" {
+			return d.EachWatchSample(agentID, target, from, to, fn)
+		}
+	case targetSensor:
+		if target != "" {
+			return d.EachSensorSample(agentID, target, from, to, fn)
+		}
+	case targetCustom:
+		return d.EachCustomSample(agentID, target, from, to, fn)
+	}
+	return d.EachMetric(agentID, from, to, fn)
+}
+
+// validateRuleTarget checks the mountpoint, device, interface, watch, sensor
+// or custom series a rule is limited to
+func validateRuleTarget(rule *models.AlertRule) error {
+	if rule.Expression != "" {
+		return &ValidationError{Field: "target", Message: "cannot be used with an expression"}
+	}
+	switch metricAccessors[rule.MetricType].target {
+	case targetFilesystem:
+		if strings.TrimSpace(rule.Target) != rule.Target || len(rule.Target) > maxTargetLength {
+			return &ValidationError{Field: "target", Message: "must be a mountpoint such as /var"}
+		}
+	case targetInterface:
+		// Windows names interfaces such as "Ethernet 2"
+		if strings.TrimSpace(rule.Target) != rule.Target || len(rule.Target) > maxTargetLength {
+			return &ValidationError{Field: "target", Message: "must be an interface name such as eth0"}
+		}
+	case targetDevice:
+		if strings.ContainsAny(rule.Target, " /") || len(rule.Target) > maxTargetLength {
+			return &ValidationError{Field: "target", Message: "must be a device name such as sda"}
+		}
+	case targetWatch:
+		if strings.TrimSpace(rule.Target) != rule.Target || len(rule.Target) > maxTargetLength {
+			return &ValidationError{Field: "target", Message: "must be the name of a watch such as nginx"}
+		}
+	case targetSensor:
+		if strings.TrimSpace(rule.Target) != rule.Target || len(rule.Target) > maxTargetLength {
+			return &ValidationError{Field: "target", Message: "must be a sensor name such as coretemp/Package id 0"}
+		}
+	case targetCustom:
+		if strings.TrimSpace(rule.Target) != rule.Target || len(rule.Target) > maxTargetLength {
+			return &ValidationError{Field: "target", Message: "must be the name of a custom series such as queue.depth"}
+		}
+	default:
+		return &ValidationError{Field: "target", Message: "only applies to filesystem, disk I/O, network, watch, sensor and custom metrics"}
+	}
+	return nil
+}
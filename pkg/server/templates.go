@@ -0,0 +1,279 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+const (
+	templateHistoryWindow = 15 * time.Minute
+	templateHistoryLimit  = 20
+)
+
+// defaultTemplate is used when no stored template matches a notification
+var defaultTemplate = &models.NotificationTemplate{
+	Subject: `{{if .Alert.Severity}}[{{upper .Alert.Severity}}] {{end}}{{if .Alert.Resolved}}Resolved{{else}}Alert{{end}}: {{.Rule.Description}}`,
+	Body: `{{if .Alert.Resolved}}Alert resolved at {{formatTime now}}{{else}}Alert triggered at {{formatTime .Alert.Timestamp}}{{end}}
+
+Agent: {{.Agent.Name}} ({{.Agent.Host}})
+Rule: {{.Rule.Description}}
+Message: {{.Alert.Message}}
+{{- if .DashboardURL}}
+Dashboard: {{.DashboardURL}}
+{{- end}}
+{{- if .History}}
+
+Recent values:
+{{- range .History}}
+  {{formatTime .Timestamp}}  {{printf "%.2f" (metric . $.Rule.MetricType)}}{{$.Unit}}
+{{- end}}
+{{- end}}
+{{- if and .Processes (not .Alert.Resolved)}}
+
+Top processes:
+{{- range .Processes}}
+  {{.PID}}  {{.Name}} ({{.User}})  CPU {{printf "%.1f" .CPUPercent}}%  RSS {{formatBytes .RSS}}
+{{- end}}
+{{- end}}
+`,
+}
+
+// TemplateData is the data available to notification templates
+type TemplateData struct {
+	Agent        *models.Agent
+	Rule         *models.AlertRule
+	Alert        *models.Alert
+	Channel      string
+	Value        float64
+	Threshold    float64
+	Duration     time.Duration
+	Unit         string
+	DashboardURL string
+	History      []*models.Metrics       // oldest first
+	Processes    []models.ProcessMetrics // busiest processes when the alert was raised, busiest first
+}
+
+// TemplateError reports a template that failed to parse or render
+type TemplateError struct {
+	Field string
+	Err   error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("invalid %s template: %v", e.Field, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// templateFuncs are the helper functions available to notification templates
+var templateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04:05")
+	},
+	"formatBytes": formatBytes,
+	"now":         time.Now,
+	"metric":      metricValue,
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(value interface{}) string {
+	var b float64
+	switch v := value.(type) {
+	case uint64:
+		b = float64(v)
+	case int64:
+		b = float64(v)
+	case int:
+		b = float64(v)
+	case float64:
+		b = v
+	}
+
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", b, units[i])
+}
+
+// renderNotification renders the subject and body of a template
+func renderNotification(tpl *models.NotificationTemplate, data *TemplateData) (string, string, error) {
+	subject, err := renderTemplate("subject", tpl.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := renderTemplate("body", tpl.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	// Subjects end up in mail headers, so keep them on a single line
+	subject = strings.Join(strings.Fields(subject), " ")
+	return subject, body, nil
+}
+
+// renderTemplate parses and executes a single template
+func renderTemplate(field, text string, data *TemplateData) (string, error) {
+	t, err := template.New(field).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", &TemplateError{Field: field, Err: err}
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", &TemplateError{Field: field, Err: err}
+	}
+	return buf.String(), nil
+}
+
+// validateTemplate checks that a template parses and renders against sample data
+func validateTemplate(tpl *models.NotificationTemplate) error {
+	if strings.TrimSpace(tpl.Subject) == "" {
+		return &TemplateError{Field: "subject", Err: fmt.Errorf("must not be empty")}
+	}
+	if strings.TrimSpace(tpl.Body) == "" {
+		return &TemplateError{Field: "body", Err: fmt.Errorf("must not be empty")}
+	}
+	_, _, err := renderNotification(tpl, sampleTemplateData())
+	return err
+}
+
+// selectTemplate picks the most specific template for a channel and rule
+func selectTemplate(templates []*models.NotificationTemplate, channel string, ruleID int) *models.NotificationTemplate {
+	best := defaultTemplate
+	bestScore := -1
+	for _, tpl := range templates {
+		if tpl.Channel != "" && tpl.Channel != channel {
+			continue
+		}
+		if tpl.RuleID != 0 && tpl.RuleID != ruleID {
+			continue
+		}
+
+		// A rule match is more specific than a channel match
+		score := 0
+		if tpl.RuleID != 0 {
+			score += 2
+		}
+		if tpl.Channel != "" {
+			score++
+		}
+		if score > bestScore {
+			best = tpl
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// buildTemplateData collects everything a template can reference for an alert
+func buildTemplateData(db *Database, config *models.Config, alert *models.Alert, rule *models.AlertRule) *TemplateData {
+	data := &TemplateData{
+		Rule:      rule,
+		Alert:     alert,
+		Value:     alert.Value,
+		Threshold: rule.Threshold,
+		Duration:  time.Duration(rule.Duration) * time.Second,
+		Unit:      metricUnit(rule.MetricType),
+	}
+
+	agent, err := db.GetAgent(alert.AgentID)
+	if err != nil || agent == nil {
+		agent = &models.Agent{ID: alert.AgentID, Name: alert.AgentID}
+	}
+	data.Agent = agent
+
+	if config.DashboardURL != "" {
+		data.DashboardURL = strings.TrimSuffix(config.DashboardURL, "/") + "/agents/" + alert.AgentID
+	}
+
+	// An expression combines several metrics, so it has no single value
+	// to list. Other rules list the values of the target they judge.
+	if rule.Expression == "" {
+		var history []*models.Metrics
+		from := alert.Timestamp.Add(-templateHistoryWindow)
+		err := db.eachSample(alert.AgentID, rule.MetricType, rule.Target, from, time.Now().Add(time.Second), func(m *models.Metrics) {
+			// Samples come oldest first; keep the most recent ones
+			history = append(history, m)
+			if len(history) > templateHistoryLimit {
+				history = history[1:]
+			}
+		})
+		if err == nil {
+			data.History = history
+		}
+	}
+
+	if processes, err := db.GetProcesses(alert.AgentID, alert.Timestamp); err == nil {
+		data.Processes = processes
+	}
+
+	return data
+}
+
+// sampleTemplateData returns representative data for validating and previewing templates
+func sampleTemplateData() *TemplateData {
+	now := time.Now()
+	rule := &models.AlertRule{
+		ID:          1,
+		AgentID:     "web-01",
+		MetricType:  "cpu",
+		Threshold:   90,
+		Operator:    "gt",
+		Duration:    60,
+		Enabled:     true,
+		Description: "High CPU usage",
+		Severity:    "critical",
+	}
+	alert := &models.Alert{
+		ID:        1,
+		RuleID:    rule.ID,
+		AgentID:   rule.AgentID,
+		Timestamp: now,
+		Message:   "cpu: 93.20% gt 90.00%",
+		Value:     93.2,
+		Severity:  "critical",
+	}
+
+	var history []*models.Metrics
+	for i := 4; i >= 0; i-- {
+		history = append(history, &models.Metrics{
+			AgentID:     rule.AgentID,
+			Timestamp:   now.Add(-time.Duration(i) * time.Minute),
+			CPUPercent:  89 + float64(4-i),
+			CPUCores:    4,
+			MemoryUsed:  6 << 30,
+			MemoryTotal: 16 << 30,
+			DiskUsed:    120 << 30,
+			DiskTotal:   500 << 30,
+			LoadAvg1:    3.5,
+		})
+	}
+
+	return &TemplateData{
+		Agent:        &models.Agent{ID: rule.AgentID, Name: "Web 01", Host: "10.0.0.10", LastSeen: now, Status: "online"},
+		Rule:         rule,
+		Alert:        alert,
+		Channel:      defaultEmailChannel,
+		Value:        alert.Value,
+		Threshold:    rule.Threshold,
+		Duration:     time.Duration(rule.Duration) * time.Second,
+		Unit:         metricUnit(rule.MetricType),
+		DashboardURL: "https://monitor.example.com/agents/" + rule.AgentID,
+		History:      history,
+		Processes: []models.ProcessMetrics{
+			{PID: 2301, Name: "java", User: "app", Cmdline: "java -jar /opt/app/app.jar", CPUPercent: 312.5, RSS: 3 << 30, FDs: 412, Threads: 96},
+			{PID: 1187, Name: "postgres", User: "postgres", Cmdline: "postgres: checkpointer", CPUPercent: 14.2, RSS: 512 << 20, FDs: 38, Threads: 1},
+		},
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// ValidationError reports an invalid field in a request
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// nonNegativeUnits are the units of metrics that cannot go below zero.
+// Temperatures and custom series can, so any threshold is accepted for them.
+var nonNegativeUnits = map[string]bool{"%": true, "B": true, "B/s": true, "/s": true, "ms": true, "RPM": true}
+
+// validateAlertRule checks an alert rule before it is stored
+func validateAlertRule(rule *models.AlertRule) error {
+	switch rule.Type {
+	case "", "threshold":
+		if rule.Direction != "" || rule.Seasonality != "" {
+			return &ValidationError{Field: "type", Message: "direction and seasonality only apply to anomaly rules"}
+		}
+	case "anomaly", "forecast":
+		if rule.Expression != "" {
+			return &ValidationError{Field: "type", Message: rule.Type + " rules cannot use an expression"}
+		}
+		if rule.Type == "forecast" && (rule.Direction != "" || rule.Seasonality != "") {
+			return &ValidationError{Field: "type", Message: "direction and seasonality only apply to anomaly rules"}
+		}
+	default:
+		return &ValidationError{Field: "type", Message: "must be one of threshold, anomaly, forecast"}
+	}
+
+	if rule.Expression != "" {
+		if _, err := ParseExpression(rule.Expression); err != nil {
+			return err
+		}
+	} else if err := validateRuleCondition(rule); err != nil {
+		return err
+	}
+	if rule.Target != "" {
+		if err := validateRuleTarget(rule); err != nil {
+			return err
+		}
+	}
+
+	if rule.Duration < 0 || rule.Duration > maxRuleDuration {
+		return &ValidationError{Field: "duration", Message: fmt.Sprintf("must be between 0 and %d seconds", maxRuleDuration)}
+	}
+	if rule.Window < 0 || rule.Window > int(maxExprWindow.Seconds()) {
+		return &ValidationError{Field: "window", Message: fmt.Sprintf("must be between 0 and %d seconds", int(maxExprWindow.Seconds()))}
+	}
+	if rule.Aggregate != "" {
+		if _, ok := windowFuncs[rule.Aggregate]; !ok {
+			return &ValidationError{Field: "aggregate", Message: "must be one of avg, min, max, p95"}
+		}
+	}
+	if rule.BreachRatio < 0 || rule.BreachRatio > 1 {
+		return &ValidationError{Field: "breach_ratio", Message: "must be between 0 and 1"}
+	}
+	if rule.EscalationPolicyID < 0 {
+		return &ValidationError{Field: "escalation_policy_id", Message: "must not be negative"}
+	}
+	if err := validateSeverities(rule); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// maxRuleDuration is the longest a breach may be required to last, in seconds
+const maxRuleDuration = 86400
+
+// validateRuleCondition checks the metric, operator and thresholds of a rule
+// without an expression
+func validateRuleCondition(rule *models.AlertRule) error {
+	if rule.MetricType == "" {
+		return &ValidationError{Field: "metric_type", Message: "is required unless an expression is given"}
+	}
+
+	if rule.MetricType == "offline" {
+		if rule.Type == "anomaly" || rule.Type == "forecast" {
+			return &ValidationError{Field: "metric_type", Message: "offline cannot be used with " + rule.Type + " rules"}
+		}
+		// The threshold is the number of missed reports, 0 for the default
+		if rule.Threshold < 0 {
+			return &ValidationError{Field: "threshold", Message: "must not be negative"}
+		}
+		return nil
+	}
+
+	accessor, ok := metricAccessors[rule.MetricType]
+	if !ok {
+		return &ValidationError{Field: "metric_type", Message: fmt.Sprintf("unknown metric %q", rule.MetricType)}
+	}
+	// Custom series have nothing to add up to, so one must be named
+	if accessor.target == targetCustom && rule.Target == "" {
+		return &ValidationError{Field: "target", Message: "must name the custom series, such as queue.depth"}
+	}
+
+	switch rule.Type {
+	case "anomaly":
+		return validateAnomalyRule(rule)
+	case "forecast":
+		return validateForecastRule(rule)
+	}
+
+	switch rule.Operator {
+	case "gt", "lt", "gte", "lte":
+	default:
+		return &ValidationError{Field: "operator", Message: "must be one of gt, lt, gte, lte"}
+	}
+
+	thresholds := []float64{rule.Threshold}
+	for _, level := range rule.Levels {
+		thresholds = append(thresholds, level.Threshold)
+	}
+	for _, threshold := range thresholds {
+		if math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+			return &ValidationError{Field: "threshold", Message: "must be a finite number"}
+		}
+		if threshold < 0 && nonNegativeUnits[metricUnit(rule.MetricType)] {
+			return &ValidationError{Field: "threshold", Message: "must not be negative"}
+		}
+		if accessor.unit == "%" && threshold > 100 {
+			return &ValidationError{Field: "threshold", Message: "must be between 0 and 100 for a percentage"}
+		}
+	}
+	return nil
+}
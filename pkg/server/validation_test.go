@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+func TestNegativeThresholds(t *testing.T) {
+	tests := []struct {
+		metric string
+		target string
+		valid  bool
+	}{
+		{"temperature", "", true},
+		{"custom", "queue.delta", true},
+		{"cpu", "", false},
+		{"mem_used_bytes", "", false},
+		{"network_rx", "", false},
+		{"disk_await_ms", "", false},
+	}
+
+	for _, tt := range tests {
+		rule := &models.AlertRule{MetricType: tt.metric, Target: tt.target, Operator: "lt", Threshold: -5}
+		err := validateAlertRule(rule)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("threshold -5 for %s: got %v, want valid %v", tt.metric, err, tt.valid)
+		}
+	}
+}
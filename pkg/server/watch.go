@@ -0,0 +1,26 @@
+package server
+
+import "github.com/jyxjjj/Monitor/pkg/models"
+
+// findWatch returns the state of a watched process or unit in a sample
+func findWatch(m *models.Metrics, name string) (models.WatchMetrics, bool) {
+	for _, w := range m.Watches {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return models.WatchMetrics{}, false
+}
+
+// watchView returns a copy of a sample whose watch fields hold the state of
+// a single watch
+func watchView(m *models.Metrics, w models.WatchMetrics) *models.Metrics {
+	view := *m
+	view.WatchesDown = 0
+	if !w.Up {
+		view.WatchesDown = 1
+	}
+	view.WatchRestarts = w.Restarts
+	view.Watches = []models.WatchMetrics{w}
+	return &view
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jyxjjj/Monitor/pkg/models"
+)
+
+// sampleRingSize is the number of samples kept per agent. At the default
+// 5 second report interval this covers several hours.
+const sampleRingSize = 4096
+
+// sampleRing is a fixed-size ring buffer of an agent's recent samples
+type sampleRing struct {
+	samples []*models.Metrics
+	start   int
+	count   int
+}
+
+func newSampleRing(size int) *sampleRing {
+	return &sampleRing{samples: make([]*models.Metrics, size)}
+}
+
+// add appends a sample, overwriting the oldest one when the ring is full
+func (r *sampleRing) add(m *models.Metrics) {
+	size := len(r.samples)
+	if r.count < size {
+		r.samples[(r.start+r.count)%size] = m
+		r.count++
+		return
+	}
+	r.samples[r.start] = m
+	r.start = (r.start + 1) % size
+}
+
+// since returns the samples at or after t, oldest first
+func (r *sampleRing) since(t time.Time) []*models.Metrics {
+	size := len(r.samples)
+	var out []*models.Metrics
+	for i := 0; i < r.count; i++ {
+		m := r.samples[(r.start+i)%size]
+		if !m.Timestamp.Before(t) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// oldest returns the oldest sample in the ring, or nil if it is empty
+func (r *sampleRing) oldest() *models.Metrics {
+	if r.count == 0 {
+		return nil
+	}
+	return r.samples[r.start]
+}
+
+// percentile returns the p-th percentile (0-100) using linear interpolation
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// windowResult is the outcome of evaluating a rule over a window
+type windowResult struct {
+	value    float64 // aggregated value
+	share    float64 // share of samples breaching the threshold
+	complete bool    // whether the samples cover the whole window
+}
+
+// evaluateWindow aggregates a rule's metric over its window. The window is
+// only complete once the agent has reported for its full length.
+func evaluateWindow(rule *models.AlertRule, now time.Time, samples []*models.Metrics, oldest *models.Metrics) windowResult {
+	window := time.Duration(rule.Window) * time.Second
+	start := now.Add(-window)
+
+	var values []float64
+	breaching := 0
+	for _, m := range samples {
+		if m.Timestamp.Before(start) {
+			continue
+		}
+		value := metricValue(m, rule.MetricType)
+		values = append(values, value)
+		if checkThreshold(value, rule.Threshold, rule.Operator) {
+			breaching++
+		}
+	}
+	if len(values) == 0 {
+		return windowResult{}
+	}
+
+	aggregate := rule.Aggregate
+	if aggregate == "" {
+		aggregate = "avg"
+	}
+
+	return windowResult{
+		value:    windowFuncs[aggregate](values),
+		share:    float64(breaching) / float64(len(values)),
+		complete: oldest != nil && !oldest.Timestamp.After(start),
+	}
+}